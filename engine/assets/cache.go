@@ -0,0 +1,156 @@
+package assets
+
+import (
+	"fmt"
+)
+
+// Cache shares assets of one kind by key. Every Acquire hands out a
+// handle holding one reference; the asset is freed once the last handle
+// to it is released, and loaded again by the next Acquire.
+//
+// Caches are not safe for concurrent use. Assets owning GL objects must
+// be acquired and released on the thread owning the GL context anyway.
+type Cache[T any] struct {
+	kind    string
+	free    func(T)
+	entries map[string]*entry[T]
+}
+
+type entry[T any] struct {
+	key   string
+	value T
+	bytes int64
+	refs  int
+	freed bool
+}
+
+// NewCache creates an empty cache for assets of the named kind, such as
+// "textures". free releases an asset's resources; it may be nil.
+func NewCache[T any](kind string, free func(T)) *Cache[T] {
+	return &Cache[T]{kind: kind, free: free, entries: make(map[string]*entry[T])}
+}
+
+// Acquire returns a new handle to the asset under key, calling load to
+// create it when no handle to it is held. load returns the asset and
+// roughly how many bytes of memory it takes. Failed loads are not cached.
+func (c *Cache[T]) Acquire(key string, load func() (T, int64, error)) (*Handle[T], error) {
+	e, ok := c.entries[key]
+	if !ok {
+		value, bytes, err := load()
+		if err != nil {
+			return nil, err
+		}
+		e = &entry[T]{key: key, value: value, bytes: bytes}
+		c.entries[key] = e
+	}
+	e.refs++
+	return &Handle[T]{cache: c, entry: e}, nil
+}
+
+// Loaded reports whether the asset under key is held by any handle.
+func (c *Cache[T]) Loaded(key string) bool {
+	_, ok := c.entries[key]
+	return ok
+}
+
+func (c *Cache[T]) release(e *entry[T]) {
+	e.refs--
+	if e.refs > 0 || e.freed {
+		return
+	}
+	e.freed = true
+	delete(c.entries, e.key)
+	if c.free != nil {
+		c.free(e.value)
+	}
+}
+
+// Clear frees every asset, including those still held. Handles to them
+// must not be used afterwards except to be released, which does nothing.
+func (c *Cache[T]) Clear() {
+	for key, e := range c.entries {
+		e.freed = true
+		delete(c.entries, key)
+		if c.free != nil {
+			c.free(e.value)
+		}
+	}
+}
+
+// Usage is a summary of what a cache holds.
+type Usage struct {
+	Kind    string
+	Assets  int
+	Handles int
+	Bytes   int64
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%s: %d assets, %d handles, %s", u.Kind, u.Assets, u.Handles, FormatBytes(u.Bytes))
+}
+
+func (c *Cache[T]) Usage() Usage {
+	u := Usage{Kind: c.kind, Assets: len(c.entries)}
+	for _, e := range c.entries {
+		u.Handles += e.refs
+		u.Bytes += e.bytes
+	}
+	return u
+}
+
+// FormatBytes formats a byte count with a binary unit, as in "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Handle is one reference to a cached asset.
+type Handle[T any] struct {
+	cache *Cache[T]
+	entry *entry[T]
+}
+
+// Get returns the asset. It must not be used after the handle is
+// released.
+func (h *Handle[T]) Get() T {
+	if h.entry == nil {
+		var zero T
+		return zero
+	}
+	return h.entry.value
+}
+
+func (h *Handle[T]) Key() string {
+	if h.entry == nil {
+		return ""
+	}
+	return h.entry.key
+}
+
+// Clone returns another handle to the same asset, keeping it loaded until
+// both are released.
+func (h *Handle[T]) Clone() *Handle[T] {
+	if h.entry == nil {
+		panic("assets: clone of a released handle")
+	}
+	h.entry.refs++
+	return &Handle[T]{cache: h.cache, entry: h.entry}
+}
+
+// Release drops the handle's reference. Releasing a handle again does
+// nothing.
+func (h *Handle[T]) Release() {
+	if h == nil || h.entry == nil {
+		return
+	}
+	h.cache.release(h.entry)
+	h.entry = nil
+}
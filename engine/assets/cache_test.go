@@ -0,0 +1,88 @@
+package assets
+
+import (
+	"errors"
+	"testing"
+)
+
+type resource struct {
+	name  string
+	freed bool
+}
+
+func TestCacheRefCounting(t *testing.T) {
+	var freed []string
+	c := NewCache("things", func(r *resource) {
+		r.freed = true
+		freed = append(freed, r.name)
+	})
+	loads := 0
+	load := func(name string) func() (*resource, int64, error) {
+		return func() (*resource, int64, error) {
+			loads++
+			return &resource{name: name}, 100, nil
+		}
+	}
+
+	a, err := c.Acquire("a", load("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, _ := c.Acquire("a", load("a"))
+	b, _ := c.Acquire("b", load("b"))
+	if loads != 2 || a.Get() != a2.Get() {
+		t.Fatalf("loaded %d times", loads)
+	}
+	if u := c.Usage(); u != (Usage{Kind: "things", Assets: 2, Handles: 3, Bytes: 200}) {
+		t.Errorf("got %+v", u)
+	}
+
+	res := a.Get()
+	a.Release()
+	a.Release() // no effect on a2's reference
+	if res.freed || !c.Loaded("a") {
+		t.Fatal("freed while a handle is held")
+	}
+	clone := a2.Clone()
+	a2.Release()
+	if res.freed {
+		t.Fatal("freed while a clone is held")
+	}
+	clone.Release()
+	if !res.freed || c.Loaded("a") || a.Get() != nil {
+		t.Error("not freed after the last release")
+	}
+
+	// The next Acquire loads it again.
+	a3, _ := c.Acquire("a", load("a"))
+	if loads != 3 || a3.Get() == res {
+		t.Error("released asset was reused")
+	}
+
+	c.Clear()
+	b.Release()
+	a3.Release()
+	if len(freed) != 3 || c.Usage().Assets != 0 {
+		t.Errorf("freed %v", freed)
+	}
+}
+
+func TestCacheLoadError(t *testing.T) {
+	c := NewCache[int]("numbers", nil)
+	fail := errors.New("broken")
+	if _, err := c.Acquire("x", func() (int, int64, error) { return 0, 0, fail }); err != fail {
+		t.Errorf("got %v", err)
+	}
+	h, err := c.Acquire("x", func() (int, int64, error) { return 7, 8, nil })
+	if err != nil || h.Get() != 7 {
+		t.Errorf("got %v, %v", h.Get(), err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 3 << 20: "3.0 MiB"} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type store interface {
+	remove(e Entity)
+	has(e Entity) bool
+	len() int
+}
+
+// sparseSet stores one component type densely, with a sparse index from
+// entity slot to dense position. Lookups, inserts and removals are O(1)
+// and iteration walks contiguous memory.
+type sparseSet[T any] struct {
+	sparse   []int32 // entity slot -> dense index + 1, 0 when absent
+	entities []Entity
+	data     []T
+}
+
+func (s *sparseSet[T]) lookup(e Entity) int {
+	i := e.index()
+	if int(i) >= len(s.sparse) || s.sparse[i] == 0 {
+		return -1
+	}
+	d := int(s.sparse[i] - 1)
+	if s.entities[d] != e {
+		return -1
+	}
+	return d
+}
+
+func (s *sparseSet[T]) set(e Entity, c T) {
+	if d := s.lookup(e); d >= 0 {
+		s.data[d] = c
+		return
+	}
+	i := int(e.index())
+	if i >= len(s.sparse) {
+		s.sparse = append(s.sparse, make([]int32, i+1-len(s.sparse))...)
+	}
+	s.entities = append(s.entities, e)
+	s.data = append(s.data, c)
+	s.sparse[i] = int32(len(s.entities))
+}
+
+func (s *sparseSet[T]) remove(e Entity) {
+	d := s.lookup(e)
+	if d < 0 {
+		return
+	}
+	last := len(s.entities) - 1
+	moved := s.entities[last]
+	s.entities[d] = moved
+	s.data[d] = s.data[last]
+	s.sparse[moved.index()] = int32(d + 1)
+	s.sparse[e.index()] = 0
+
+	var zero T
+	s.data[last] = zero
+	s.entities = s.entities[:last]
+	s.data = s.data[:last]
+}
+
+func (s *sparseSet[T]) has(e Entity) bool {
+	return s.lookup(e) >= 0
+}
+
+func (s *sparseSet[T]) len() int {
+	return len(s.entities)
+}
+
+func storeOf[T any](w *World) *sparseSet[T] {
+	t := reflect.TypeFor[T]()
+	if s, ok := w.stores[t]; ok {
+		return s.(*sparseSet[T])
+	}
+	s := &sparseSet[T]{}
+	w.stores[t] = s
+	return s
+}
+
+// Add attaches c to e, replacing any component of the same type. It panics
+// if e is not alive.
+func Add[T any](w *World, e Entity, c T) {
+	if !w.Alive(e) {
+		panic(fmt.Sprintf("ecs: add to dead entity %v", e))
+	}
+	storeOf[T](w).set(e, c)
+}
+
+// Get returns e's component of type T. The pointer is only valid until
+// the next component of that type is added.
+func Get[T any](w *World, e Entity) (*T, bool) {
+	s := storeOf[T](w)
+	d := s.lookup(e)
+	if d < 0 {
+		return nil, false
+	}
+	return &s.data[d], true
+}
+
+func Has[T any](w *World, e Entity) bool {
+	return storeOf[T](w).has(e)
+}
+
+func Remove[T any](w *World, e Entity) {
+	storeOf[T](w).remove(e)
+}
+
+// Count returns how many entities have a component of type T.
+func Count[T any](w *World) int {
+	return storeOf[T](w).len()
+}
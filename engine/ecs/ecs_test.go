@@ -0,0 +1,151 @@
+package ecs
+
+import (
+	"slices"
+	"testing"
+)
+
+type position struct{ X, Y float64 }
+type velocity struct{ X, Y float64 }
+type tag struct{}
+
+func TestEntityLifecycle(t *testing.T) {
+	w := NewWorld()
+	a := w.NewEntity()
+	b := w.NewEntity()
+	if a == Nil || a == b || !w.Alive(a) || w.Len() != 2 {
+		t.Fatalf("got %v %v, len %d", a, b, w.Len())
+	}
+
+	Add(w, a, position{1, 2})
+	w.Destroy(a)
+	if w.Alive(a) || Has[position](w, a) || w.Len() != 1 {
+		t.Fatal("destroyed entity still has state")
+	}
+
+	// The slot is reused with a new generation; the old handle stays dead.
+	c := w.NewEntity()
+	if c.index() != a.index() || c == a || w.Alive(a) {
+		t.Errorf("got %v after destroying %v", c, a)
+	}
+	if _, ok := Get[position](w, c); ok {
+		t.Error("reused slot inherited a component")
+	}
+	if got := w.Entities(); !slices.Equal(got, []Entity{c, b}) && !slices.Equal(got, []Entity{b, c}) {
+		t.Errorf("got entities %v", got)
+	}
+}
+
+func TestComponents(t *testing.T) {
+	w := NewWorld()
+	entities := make([]Entity, 5)
+	for i := range entities {
+		entities[i] = w.NewEntity()
+		Add(w, entities[i], position{float64(i), 0})
+	}
+
+	// Removing from the middle keeps the others reachable.
+	Remove[position](w, entities[1])
+	for i, e := range entities {
+		p, ok := Get[position](w, e)
+		if i == 1 {
+			if ok {
+				t.Error("removed component still present")
+			}
+			continue
+		}
+		if !ok || p.X != float64(i) {
+			t.Errorf("entity %d: got %v, %v", i, p, ok)
+		}
+	}
+
+	p, _ := Get[position](w, entities[2])
+	p.X = 42
+	if p, _ := Get[position](w, entities[2]); p.X != 42 {
+		t.Error("Get did not return a pointer into storage")
+	}
+	if Count[position](w) != 4 {
+		t.Errorf("got count %d", Count[position](w))
+	}
+}
+
+func TestQueries(t *testing.T) {
+	w := NewWorld()
+	moving := w.NewEntity()
+	Add(w, moving, position{0, 0})
+	Add(w, moving, velocity{1, 2})
+	still := w.NewEntity()
+	Add(w, still, position{5, 5})
+	tagged := w.NewEntity()
+	Add(w, tagged, position{0, 0})
+	Add(w, tagged, velocity{1, 1})
+	Add(w, tagged, tag{})
+
+	visited := 0
+	Each2(w, func(e Entity, p *position, v *velocity) {
+		p.X += v.X
+		p.Y += v.Y
+		visited++
+	})
+	if visited != 2 {
+		t.Errorf("Each2 visited %d entities", visited)
+	}
+	if p, _ := Get[position](w, moving); *p != (position{1, 2}) {
+		t.Errorf("got %v", *p)
+	}
+
+	var found []Entity
+	Each3(w, func(e Entity, _ *position, _ *velocity, _ *tag) {
+		found = append(found, e)
+	})
+	if !slices.Equal(found, []Entity{tagged}) {
+		t.Errorf("Each3 found %v", found)
+	}
+
+	// Destroying entities mid-query skips them instead of breaking.
+	seen := 0
+	Each(w, func(e Entity, _ *position) {
+		seen++
+		for _, other := range []Entity{moving, still, tagged} {
+			if other != e {
+				w.Destroy(other)
+			}
+		}
+	})
+	if seen != 1 || w.Len() != 1 {
+		t.Errorf("visited %d, %d left", seen, w.Len())
+	}
+}
+
+func TestSystemOrder(t *testing.T) {
+	w := NewWorld()
+	var ran []string
+	record := func(name string) System {
+		return SystemFunc(func(*World, float64) { ran = append(ran, name) })
+	}
+
+	w.AddSystem("render", record("render"))
+	w.AddSystem("physics", record("physics"), Before("render"))
+	w.AddSystem("input", record("input"), Before("physics"), After("missing"))
+	w.AddSystem("audio", record("audio"))
+	if err := w.AddSystem("audio", record("audio")); err == nil {
+		t.Error("expected an error for a duplicate name")
+	}
+
+	if err := w.Update(0); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"input", "physics", "render", "audio"}
+	if !slices.Equal(ran, want) {
+		t.Errorf("got %v, want %v", ran, want)
+	}
+
+	w.AddSystem("loop", record("loop"), After("render"), Before("input"))
+	if err := w.Update(0); err == nil {
+		t.Error("expected a cycle error")
+	}
+	w.RemoveSystem("loop")
+	if _, err := w.Systems(); err != nil {
+		t.Error(err)
+	}
+}
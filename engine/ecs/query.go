@@ -0,0 +1,69 @@
+package ecs
+
+// Queries call fn for every entity that has all the listed component
+// types, passing pointers into the component storage. As with Get, a
+// pointer is only valid until another component of its type is added.
+//
+// fn may add and remove components and create and destroy entities. The
+// set of entities visited is fixed when the query starts, and entities
+// that lose a queried component before being reached are skipped.
+
+func Each[A any](w *World, fn func(Entity, *A)) {
+	a := storeOf[A](w)
+	for _, e := range driver(a) {
+		if da := a.lookup(e); da >= 0 {
+			fn(e, &a.data[da])
+		}
+	}
+}
+
+func Each2[A, B any](w *World, fn func(Entity, *A, *B)) {
+	a, b := storeOf[A](w), storeOf[B](w)
+	for _, e := range driver(a, b) {
+		da, db := a.lookup(e), b.lookup(e)
+		if da >= 0 && db >= 0 {
+			fn(e, &a.data[da], &b.data[db])
+		}
+	}
+}
+
+func Each3[A, B, C any](w *World, fn func(Entity, *A, *B, *C)) {
+	a, b, c := storeOf[A](w), storeOf[B](w), storeOf[C](w)
+	for _, e := range driver(a, b, c) {
+		da, db, dc := a.lookup(e), b.lookup(e), c.lookup(e)
+		if da >= 0 && db >= 0 && dc >= 0 {
+			fn(e, &a.data[da], &b.data[db], &c.data[dc])
+		}
+	}
+}
+
+func Each4[A, B, C, D any](w *World, fn func(Entity, *A, *B, *C, *D)) {
+	a, b, c, d := storeOf[A](w), storeOf[B](w), storeOf[C](w), storeOf[D](w)
+	for _, e := range driver(a, b, c, d) {
+		da, db, dc, dd := a.lookup(e), b.lookup(e), c.lookup(e), d.lookup(e)
+		if da >= 0 && db >= 0 && dc >= 0 && dd >= 0 {
+			fn(e, &a.data[da], &b.data[db], &c.data[dc], &d.data[dd])
+		}
+	}
+}
+
+type entityLister interface {
+	store
+	list() []Entity
+}
+
+func (s *sparseSet[T]) list() []Entity {
+	return s.entities
+}
+
+// driver returns a snapshot of the entities of the smallest store, which
+// bounds the query.
+func driver(stores ...entityLister) []Entity {
+	smallest := stores[0]
+	for _, s := range stores[1:] {
+		if s.len() < smallest.len() {
+			smallest = s
+		}
+	}
+	return append([]Entity(nil), smallest.list()...)
+}
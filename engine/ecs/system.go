@@ -0,0 +1,150 @@
+package ecs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// System updates the world once per frame.
+type System interface {
+	Update(w *World, dt float64)
+}
+
+// SystemFunc adapts a function to System.
+type SystemFunc func(w *World, dt float64)
+
+func (f SystemFunc) Update(w *World, dt float64) {
+	f(w, dt)
+}
+
+// Order constrains when a system runs relative to another, by name.
+// Constraints naming systems that are not registered are ignored, so
+// optional systems can be referred to safely.
+type Order struct {
+	before string
+	after  string
+}
+
+func Before(system string) Order {
+	return Order{before: system}
+}
+
+func After(system string) Order {
+	return Order{after: system}
+}
+
+type systemEntry struct {
+	name   string
+	system System
+	order  []Order
+}
+
+// AddSystem registers a system under a unique name. Systems run in the
+// order they were added unless constraints say otherwise.
+func (w *World) AddSystem(name string, system System, order ...Order) error {
+	if w.system(name) != nil {
+		return fmt.Errorf("ecs: system %q already registered", name)
+	}
+	w.systems = append(w.systems, &systemEntry{name: name, system: system, order: order})
+	w.sorted = false
+	return nil
+}
+
+func (w *World) RemoveSystem(name string) {
+	w.systems = slices.DeleteFunc(w.systems, func(s *systemEntry) bool { return s.name == name })
+	w.sorted = false
+}
+
+func (w *World) system(name string) *systemEntry {
+	for _, s := range w.systems {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Systems returns the system names in the order Update runs them.
+func (w *World) Systems() ([]string, error) {
+	if err := w.sort(); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(w.order))
+	for i, s := range w.order {
+		names[i] = s.name
+	}
+	return names, nil
+}
+
+// Update runs every system once. It fails without running any if the
+// ordering constraints contradict each other.
+func (w *World) Update(dt float64) error {
+	if err := w.sort(); err != nil {
+		return err
+	}
+	for _, s := range w.order {
+		s.system.Update(w, dt)
+	}
+	return nil
+}
+
+// sort orders the systems topologically, breaking ties by registration
+// order so the result is stable.
+func (w *World) sort() error {
+	if w.sorted {
+		return nil
+	}
+
+	index := make(map[string]int, len(w.systems))
+	for i, s := range w.systems {
+		index[s.name] = i
+	}
+	// edges[i] lists the systems that must run after system i.
+	edges := make([][]int, len(w.systems))
+	incoming := make([]int, len(w.systems))
+	addEdge := func(from, to int) {
+		edges[from] = append(edges[from], to)
+		incoming[to]++
+	}
+	for i, s := range w.systems {
+		for _, o := range s.order {
+			if j, ok := index[o.before]; ok && o.before != "" {
+				addEdge(i, j)
+			}
+			if j, ok := index[o.after]; ok && o.after != "" {
+				addEdge(j, i)
+			}
+		}
+	}
+
+	order := make([]*systemEntry, 0, len(w.systems))
+	done := make([]bool, len(w.systems))
+	for len(order) < len(w.systems) {
+		next := -1
+		for i := range w.systems {
+			if !done[i] && incoming[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var cycle []string
+			for i, s := range w.systems {
+				if !done[i] {
+					cycle = append(cycle, s.name)
+				}
+			}
+			return fmt.Errorf("ecs: system order has a cycle among %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		order = append(order, w.systems[next])
+		for _, j := range edges[next] {
+			incoming[j]--
+		}
+	}
+
+	w.order = order
+	w.sorted = true
+	return nil
+}
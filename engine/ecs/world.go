@@ -0,0 +1,101 @@
+package ecs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Entity identifies an entity in a World. The low 32 bits index the
+// entity's slot and the high 32 bits count how often the slot has been
+// reused, so handles to destroyed entities never match new ones.
+type Entity uint64
+
+// Nil is never returned by NewEntity.
+const Nil Entity = 0
+
+func makeEntity(index, generation uint32) Entity {
+	return Entity(generation)<<32 | Entity(index)
+}
+
+func (e Entity) index() uint32 {
+	return uint32(e)
+}
+
+func (e Entity) generation() uint32 {
+	return uint32(e >> 32)
+}
+
+func (e Entity) String() string {
+	return fmt.Sprintf("%d.%d", e.index(), e.generation())
+}
+
+// World holds entities, their components and the systems that update
+// them.
+type World struct {
+	generations []uint32
+	free        []uint32
+	alive       int
+	stores      map[reflect.Type]store
+
+	systems []*systemEntry
+	order   []*systemEntry
+	sorted  bool
+}
+
+func NewWorld() *World {
+	// Slot 0 stays unused so that Nil is never a live entity.
+	return &World{generations: []uint32{0}, stores: make(map[reflect.Type]store)}
+}
+
+func (w *World) NewEntity() Entity {
+	if n := len(w.free); n > 0 {
+		index := w.free[n-1]
+		w.free = w.free[:n-1]
+		w.alive++
+		return makeEntity(index, w.generations[index])
+	}
+	index := uint32(len(w.generations))
+	w.generations = append(w.generations, 1)
+	w.alive++
+	return makeEntity(index, 1)
+}
+
+// Alive reports whether e was created by this world and not destroyed.
+func (w *World) Alive(e Entity) bool {
+	i := e.index()
+	return i != 0 && int(i) < len(w.generations) && w.generations[i] == e.generation()
+}
+
+// Destroy removes e and all its components. Destroying a dead entity does
+// nothing.
+func (w *World) Destroy(e Entity) {
+	if !w.Alive(e) {
+		return
+	}
+	for _, s := range w.stores {
+		s.remove(e)
+	}
+	w.generations[e.index()]++
+	w.free = append(w.free, e.index())
+	w.alive--
+}
+
+// Len returns the number of live entities.
+func (w *World) Len() int {
+	return w.alive
+}
+
+// Entities returns every live entity, in slot order.
+func (w *World) Entities() []Entity {
+	entities := make([]Entity, 0, w.alive)
+	dead := make(map[uint32]bool, len(w.free))
+	for _, i := range w.free {
+		dead[i] = true
+	}
+	for i := 1; i < len(w.generations); i++ {
+		if !dead[uint32(i)] {
+			entities = append(entities, makeEntity(uint32(i), w.generations[i]))
+		}
+	}
+	return entities
+}
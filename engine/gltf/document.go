@@ -0,0 +1,151 @@
+package gltf
+
+import "encoding/json"
+
+// The types below mirror the parts of the glTF 2.0 JSON schema the importer
+// reads. Optional indices are pointers so that 0 and "absent" differ.
+
+type document struct {
+	Asset       asset        `json:"asset"`
+	Scene       *int         `json:"scene"`
+	Scenes      []scene      `json:"scenes"`
+	Nodes       []node       `json:"nodes"`
+	Meshes      []mesh       `json:"meshes"`
+	Materials   []material   `json:"materials"`
+	Textures    []texture    `json:"textures"`
+	Images      []image      `json:"images"`
+	Samplers    []sampler    `json:"samplers"`
+	Accessors   []accessor   `json:"accessors"`
+	BufferViews []bufferView `json:"bufferViews"`
+	Buffers     []buffer     `json:"buffers"`
+	Cameras     []camera     `json:"cameras"`
+	Skins       []skin       `json:"skins"`
+}
+
+type asset struct {
+	Version string `json:"version"`
+}
+
+type scene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+type node struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Skin        *int      `json:"skin"`
+	Matrix      []float32 `json:"matrix"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+}
+
+type mesh struct {
+	Name       string      `json:"name"`
+	Primitives []primitive `json:"primitives"`
+}
+
+type primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type material struct {
+	Name                 string       `json:"name"`
+	PbrMetallicRoughness *pbr         `json:"pbrMetallicRoughness"`
+	NormalTexture        *textureInfo `json:"normalTexture"`
+	OcclusionTexture     *textureInfo `json:"occlusionTexture"`
+	EmissiveTexture      *textureInfo `json:"emissiveTexture"`
+	EmissiveFactor       []float32    `json:"emissiveFactor"`
+	AlphaMode            string       `json:"alphaMode"`
+	DoubleSided          bool         `json:"doubleSided"`
+}
+
+type pbr struct {
+	BaseColorFactor          []float32    `json:"baseColorFactor"`
+	BaseColorTexture         *textureInfo `json:"baseColorTexture"`
+	MetallicFactor           *float32     `json:"metallicFactor"`
+	RoughnessFactor          *float32     `json:"roughnessFactor"`
+	MetallicRoughnessTexture *textureInfo `json:"metallicRoughnessTexture"`
+}
+
+type textureInfo struct {
+	Index    int      `json:"index"`
+	TexCoord int      `json:"texCoord"`
+	Scale    *float32 `json:"scale"`
+	Strength *float32 `json:"strength"`
+}
+
+type texture struct {
+	Sampler *int `json:"sampler"`
+	Source  *int `json:"source"`
+}
+
+type image struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type sampler struct {
+	MagFilter int  `json:"magFilter"`
+	MinFilter int  `json:"minFilter"`
+	WrapS     *int `json:"wrapS"`
+	WrapT     *int `json:"wrapT"`
+}
+
+type accessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type buffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type camera struct {
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	Perspective  *perspective  `json:"perspective"`
+	Orthographic *orthographic `json:"orthographic"`
+}
+
+type perspective struct {
+	AspectRatio *float32 `json:"aspectRatio"`
+	Yfov        float32  `json:"yfov"`
+	Znear       float32  `json:"znear"`
+	Zfar        *float32 `json:"zfar"`
+}
+
+type orthographic struct {
+	Xmag  float32 `json:"xmag"`
+	Ymag  float32 `json:"ymag"`
+	Znear float32 `json:"znear"`
+	Zfar  float32 `json:"zfar"`
+}
+
+type skin struct {
+	Name                string `json:"name"`
+	InverseBindMatrices *int   `json:"inverseBindMatrices"`
+	Skeleton            *int   `json:"skeleton"`
+	Joints              []int  `json:"joints"`
+}
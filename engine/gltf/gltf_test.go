@@ -0,0 +1,232 @@
+package gltf
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func vertex(m *obj.Mesh, index int) []float32 {
+	return m.Vertices[index*obj.VertexStride : (index+1)*obj.VertexStride]
+}
+
+func TestLoadQuad(t *testing.T) {
+	scene, err := Load("testdata/quad.gltf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scene.Meshes) != 1 || len(scene.Roots) != 1 || scene.Roots[0] != 0 {
+		t.Fatalf("got %d meshes, roots %v", len(scene.Meshes), scene.Roots)
+	}
+	mesh := scene.Meshes[0].Mesh
+	if mesh.VertexCount() != 4 || len(mesh.Indices) != 6 {
+		t.Fatalf("got %d vertices, %d indices", mesh.VertexCount(), len(mesh.Indices))
+	}
+	if len(mesh.Submeshes) != 1 || mesh.Submeshes[0].Material != "tiles" {
+		t.Fatalf("got submeshes %+v", mesh.Submeshes)
+	}
+	if mesh.Min != (mgl32.Vec3{0, 0, 0}) || mesh.Max != (mgl32.Vec3{1, 1, 0}) {
+		t.Errorf("got bounds %v %v", mesh.Min, mesh.Max)
+	}
+
+	// The first vertex has glTF UV (0, 1), which is OBJ's (0, 0).
+	v := vertex(mesh, 0)
+	if v[obj.UVOffset] != 0 || v[obj.UVOffset+1] != 0 {
+		t.Errorf("got uv %v", v[obj.UVOffset:obj.UVOffset+2])
+	}
+	tangent := mgl32.Vec3{v[obj.TangentOffset], v[obj.TangentOffset+1], v[obj.TangentOffset+2]}
+	if !tangent.ApproxEqual(mgl32.Vec3{1, 0, 0}) || v[obj.TangentOffset+3] != 1 {
+		t.Errorf("got tangent %v", v[obj.TangentOffset:obj.TangentOffset+4])
+	}
+
+	mat := mesh.Materials["tiles"]
+	if mat == nil || mat != scene.Materials[0] {
+		t.Fatalf("material not shared between scene and mesh")
+	}
+	if mat.Diffuse != (mgl32.Vec3{1, 0.5, 0.25}) || mat.Opacity != 0.5 || mat.Metallic != 0 || mat.Roughness != 0.5 {
+		t.Errorf("got material %+v", mat)
+	}
+	if mat.Emission != (mgl32.Vec3{0.1, 0.2, 0.3}) {
+		t.Errorf("got emission %v", mat.Emission)
+	}
+	wantPath := filepath.Join("testdata", "tiles.png")
+	if mat.DiffuseMap == nil || mat.DiffuseMap.Path != wantPath || !mat.DiffuseMap.Clamp || mat.Texture != wantPath {
+		t.Errorf("got diffuse map %+v", mat.DiffuseMap)
+	}
+	if mat.NormalMap == nil || mat.NormalMap.BumpMultiplier != 2 {
+		t.Errorf("got normal map %+v", mat.NormalMap)
+	}
+
+	node := scene.Nodes[0]
+	if node.Mesh != 0 || node.Camera != -1 || node.Translation != (mgl32.Vec3{1, 2, 3}) {
+		t.Errorf("got node %+v", node)
+	}
+}
+
+func TestLoadRig(t *testing.T) {
+	scene, err := Load("testdata/rig.gltf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scene.Roots) != 1 || scene.Roots[0] != 0 {
+		t.Fatalf("got roots %v", scene.Roots)
+	}
+	parents := []int{-1, 0, 1, 0, -1}
+	for i, want := range parents {
+		if scene.Nodes[i].Parent != want {
+			t.Errorf("node %d: got parent %d, want %d", i, scene.Nodes[i].Parent, want)
+		}
+	}
+
+	hip := scene.Nodes[1]
+	if hip.Translation != (mgl32.Vec3{0, 1, 0}) || hip.Scale != (mgl32.Vec3{2, 2, 2}) {
+		t.Errorf("matrix not decomposed: %+v", hip)
+	}
+	knee := scene.WorldMatrix(2).Mul4x1(mgl32.Vec4{1, 0, 0, 1})
+	if knee.Sub(mgl32.Vec4{0, 1, -2, 1}).Len() > 1e-5 {
+		t.Errorf("got knee world position %v", knee)
+	}
+
+	// A four-vertex strip is two triangles.
+	m := scene.Meshes[0]
+	if len(m.Mesh.Indices) != 6 || len(m.Mesh.Submeshes) != 1 || m.Mesh.Submeshes[0].Material != "" {
+		t.Fatalf("got indices %v submeshes %+v", m.Mesh.Indices, m.Mesh.Submeshes)
+	}
+	if len(m.Joints) != 4 || m.Joints[2] != [4]uint16{1, 0, 0, 0} || m.Weights[0] != [4]float32{1, 0, 0, 0} {
+		t.Errorf("got joints %v weights %v", m.Joints, m.Weights)
+	}
+
+	skin := scene.Skins[0]
+	if skin.Skeleton != 1 || len(skin.InverseBindMatrices) != 2 || skin.InverseBindMatrices[1].Col(3) != (mgl32.Vec4{0, -1, 0, 1}) {
+		t.Errorf("got skin %+v", skin)
+	}
+
+	cam := scene.Cameras[scene.Nodes[4].Camera]
+	if cam.Orthographic || cam.YFov != 0.8 || cam.AspectRatio != 1.5 || cam.ZFar != 0 {
+		t.Errorf("got camera %+v", cam)
+	}
+}
+
+func TestLoadGLB(t *testing.T) {
+	scene, err := Load("testdata/floor.glb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lines primitive is skipped and the fan is flat shaded, so every
+	// corner of its two triangles gets its own vertex.
+	mesh := scene.Meshes[0].Mesh
+	if mesh.VertexCount() != 6 || len(mesh.Submeshes) != 1 {
+		t.Fatalf("got %d vertices, submeshes %+v", mesh.VertexCount(), mesh.Submeshes)
+	}
+	for i := 0; i < mesh.VertexCount(); i++ {
+		v := vertex(mesh, i)
+		if normal := (mgl32.Vec3{v[obj.NormalOffset], v[obj.NormalOffset+1], v[obj.NormalOffset+2]}); !normal.ApproxEqual(mgl32.Vec3{0, 1, 0}) {
+			t.Errorf("vertex %d: got normal %v", i, normal)
+		}
+	}
+
+	if scene.Materials[0].Name != "material0" || scene.Materials[1].Name != "material1" {
+		t.Errorf("got material names %q %q", scene.Materials[0].Name, scene.Materials[1].Name)
+	}
+	diffuse := scene.Materials[0].DiffuseMap
+	if diffuse == nil || diffuse.Path != "checker" || string(diffuse.Data[8:]) != "fakepng!" {
+		t.Errorf("got embedded image %+v", diffuse)
+	}
+}
+
+func TestMaterialNames(t *testing.T) {
+	// The unnamed material's default name and the duplicate's replacement
+	// are both taken already.
+	scene, err := Decode([]byte(`{"asset":{"version":"2.0"},"materials":[`+
+		`{"name":"material1"},{},{"name":"a"},{"name":"a"},{"name":"material3"}]}`), "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, mat := range scene.Materials {
+		got = append(got, mat.Name)
+	}
+	if want := []string{"material1", "material2", "a", "material3", "material4"}; !slices.Equal(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+}
+
+// TestPartialTangents loads one quad twice as two primitives, the first
+// with authored tangents and the second without.
+func TestPartialTangents(t *testing.T) {
+	var buf bytes.Buffer
+	for _, values := range [][]float32{
+		{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},             // POSITION
+		{0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1},             // NORMAL
+		{0, 1, 1, 1, 1, 0, 0, 0},                         // TEXCOORD_0
+		{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1}, // TANGENT
+	} {
+		binary.Write(&buf, binary.LittleEndian, values)
+	}
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 2, 0, 2, 3})
+
+	doc := fmt.Sprintf(`{"asset": {"version": "2.0"},
+		"buffers": [{"byteLength": %d, "uri": "data:application/octet-stream;base64,%s"}],
+		"bufferViews": [{"buffer": 0, "byteOffset": 0, "byteLength": 192}, {"buffer": 0, "byteOffset": 192, "byteLength": 12}],
+		"accessors": [
+			{"bufferView": 0, "byteOffset": 0, "componentType": 5126, "count": 4, "type": "VEC3"},
+			{"bufferView": 0, "byteOffset": 48, "componentType": 5126, "count": 4, "type": "VEC3"},
+			{"bufferView": 0, "byteOffset": 96, "componentType": 5126, "count": 4, "type": "VEC2"},
+			{"bufferView": 0, "byteOffset": 128, "componentType": 5126, "count": 4, "type": "VEC4"},
+			{"bufferView": 1, "componentType": 5123, "count": 6, "type": "SCALAR"}
+		],
+		"meshes": [{"primitives": [
+			{"attributes": {"POSITION": 0, "NORMAL": 1, "TEXCOORD_0": 2, "TANGENT": 3}, "indices": 4},
+			{"attributes": {"POSITION": 0, "NORMAL": 1, "TEXCOORD_0": 2}, "indices": 4}
+		]}]}`, buf.Len(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	scene, err := Decode([]byte(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh := scene.Meshes[0].Mesh
+	if mesh.VertexCount() != 8 {
+		t.Fatalf("got %d vertices", mesh.VertexCount())
+	}
+	for v := 0; v < 8; v++ {
+		tangent := vertex(mesh, v)[obj.TangentOffset : obj.TangentOffset+4]
+		want := []float32{1, 0, 0, 1} // generated from the UVs
+		if v < 4 {
+			want = []float32{0, 1, 0, -1} // authored, with V flipped
+		}
+		if !mgl32.Vec4(tangent).ApproxEqual(mgl32.Vec4(want)) {
+			t.Errorf("vertex %d: got tangent %v, want %v", v, tangent, want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	glb, err := os.ReadFile("testdata/floor.glb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]byte{
+		"version":   []byte(`{"asset":{"version":"1.0"}}`),
+		"truncated": glb[:len(glb)-8],
+		"buffer":    []byte(`{"asset":{"version":"2.0"},"buffers":[{"byteLength":4}]}`),
+		"accessor":  []byte(`{"asset":{"version":"2.0"},"meshes":[{"primitives":[{"attributes":{"POSITION":3}}]}]}`),
+		"child":     []byte(`{"asset":{"version":"2.0"},"nodes":[{"children":[0]}]}`),
+		"cycle":     []byte(`{"asset":{"version":"2.0"},"nodes":[{"children":[1]},{"children":[2]},{"children":[0]}]}`),
+	}
+	for name, data := range cases {
+		if _, err := Decode(data, "testdata"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
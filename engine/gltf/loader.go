@@ -0,0 +1,578 @@
+package gltf
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Scene is everything imported from one .gltf or .glb file. Nodes refer to
+// meshes, cameras, skins and each other by index into these slices; -1
+// means none.
+type Scene struct {
+	Nodes     []*Node
+	Roots     []int
+	Meshes    []*Mesh
+	Materials []*obj.Material
+	Cameras   []*Camera
+	Skins     []*Skin
+}
+
+type Node struct {
+	Name     string
+	Parent   int
+	Children []int
+	Mesh     int
+	Camera   int
+	Skin     int
+
+	Translation mgl32.Vec3
+	Rotation    mgl32.Quat
+	Scale       mgl32.Vec3
+}
+
+// Mesh is a glTF mesh converted to the engine's vertex layout, one submesh
+// per primitive. Joints and Weights are per vertex and only set for meshes
+// that carry JOINTS_0/WEIGHTS_0.
+type Mesh struct {
+	Name    string
+	Mesh    *obj.Mesh
+	Joints  [][4]uint16
+	Weights [][4]float32
+}
+
+type Camera struct {
+	Name         string
+	Orthographic bool
+
+	// Perspective cameras; AspectRatio is 0 when the viewport's should be
+	// used and ZFar is 0 for an infinite projection.
+	YFov        float32
+	AspectRatio float32
+
+	// Orthographic cameras.
+	XMag float32
+	YMag float32
+
+	ZNear float32
+	ZFar  float32
+}
+
+type Skin struct {
+	Name                string
+	Joints              []int
+	InverseBindMatrices []mgl32.Mat4
+	Skeleton            int
+}
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A
+	glbChunkBIN  = 0x004E4942
+)
+
+// Load reads a .gltf or .glb file. External buffers and images are
+// resolved relative to the file.
+func Load(path string) (*Scene, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data, filepath.Dir(path))
+}
+
+// Decode parses glTF JSON or GLB data. dir is used to resolve external
+// buffer and image URIs.
+func Decode(data []byte, dir string) (*Scene, error) {
+	jsonData, bin, err := splitGLB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := &decoder{dir: dir, bin: bin}
+	if err := json.Unmarshal(jsonData, &dec.doc); err != nil {
+		return nil, fmt.Errorf("gltf: %w", err)
+	}
+	if !strings.HasPrefix(dec.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("gltf: unsupported asset version %q", dec.doc.Asset.Version)
+	}
+
+	return dec.decode()
+}
+
+// splitGLB returns the JSON and binary chunks of GLB data, or the data
+// itself when it is plain glTF JSON.
+func splitGLB(data []byte) ([]byte, []byte, error) {
+	if len(data) < 12 || binary.LittleEndian.Uint32(data) != glbMagic {
+		return data, nil, nil
+	}
+
+	version := binary.LittleEndian.Uint32(data[4:])
+	length := binary.LittleEndian.Uint32(data[8:])
+	if version != 2 {
+		return nil, nil, fmt.Errorf("gltf: unsupported GLB version %d", version)
+	}
+	if int(length) > len(data) {
+		return nil, nil, fmt.Errorf("gltf: GLB truncated")
+	}
+
+	var jsonData, bin []byte
+	for offset := 12; offset+8 <= int(length); {
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if chunkLength < 0 || start+chunkLength > int(length) {
+			return nil, nil, fmt.Errorf("gltf: GLB chunk overruns file")
+		}
+
+		switch chunkType {
+		case glbChunkJSON:
+			jsonData = data[start : start+chunkLength]
+		case glbChunkBIN:
+			if bin == nil {
+				bin = data[start : start+chunkLength]
+			}
+		}
+		offset = start + chunkLength
+	}
+
+	if jsonData == nil {
+		return nil, nil, fmt.Errorf("gltf: GLB has no JSON chunk")
+	}
+	return jsonData, bin, nil
+}
+
+type decoder struct {
+	doc     document
+	dir     string
+	bin     []byte
+	buffers [][]byte
+}
+
+func (dec *decoder) decode() (*Scene, error) {
+	scene := &Scene{}
+
+	if err := dec.loadBuffers(); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for i := range dec.doc.Materials {
+		mat, err := dec.material(i)
+		if err != nil {
+			return nil, err
+		}
+		// Submeshes refer to materials by name, so names must be unique.
+		for n := i; names[mat.Name]; n++ {
+			mat.Name = fmt.Sprintf("material%d", n)
+		}
+		names[mat.Name] = true
+		scene.Materials = append(scene.Materials, mat)
+	}
+
+	for i := range dec.doc.Meshes {
+		m, err := dec.mesh(i, scene.Materials)
+		if err != nil {
+			return nil, err
+		}
+		scene.Meshes = append(scene.Meshes, m)
+	}
+
+	for _, c := range dec.doc.Cameras {
+		scene.Cameras = append(scene.Cameras, convertCamera(c))
+	}
+
+	for i := range dec.doc.Skins {
+		s, err := dec.skin(i)
+		if err != nil {
+			return nil, err
+		}
+		scene.Skins = append(scene.Skins, s)
+	}
+
+	nodes, err := dec.nodes()
+	if err != nil {
+		return nil, err
+	}
+	scene.Nodes = nodes
+	scene.Roots = dec.roots(nodes)
+	return scene, nil
+}
+
+func (dec *decoder) loadBuffers() error {
+	for i, buf := range dec.doc.Buffers {
+		var data []byte
+		switch {
+		case buf.URI == "":
+			if i != 0 || dec.bin == nil {
+				return fmt.Errorf("gltf: buffer %d has no data", i)
+			}
+			data = dec.bin
+		default:
+			var err error
+			data, err = dec.readURI(buf.URI)
+			if err != nil {
+				return fmt.Errorf("gltf: buffer %d: %w", i, err)
+			}
+		}
+
+		if len(data) < buf.ByteLength {
+			return fmt.Errorf("gltf: buffer %d is %d bytes, expected %d", i, len(data), buf.ByteLength)
+		}
+		dec.buffers = append(dec.buffers, data[:buf.ByteLength])
+	}
+	return nil
+}
+
+// readURI returns the contents of a data URI or of a file relative to the
+// glTF file.
+func (dec *decoder) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("unsupported data URI")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	return os.ReadFile(dec.uriPath(uri))
+}
+
+func (dec *decoder) uriPath(uri string) string {
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	return filepath.Join(dec.dir, filepath.FromSlash(uri))
+}
+
+func (dec *decoder) bufferViewData(index int) ([]byte, int, error) {
+	if index < 0 || index >= len(dec.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d out of range", index)
+	}
+	view := dec.doc.BufferViews[index]
+	if view.Buffer < 0 || view.Buffer >= len(dec.buffers) {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d uses missing buffer %d", index, view.Buffer)
+	}
+
+	data := dec.buffers[view.Buffer]
+	end := view.ByteOffset + view.ByteLength
+	if view.ByteOffset < 0 || view.ByteLength < 0 || end > len(data) {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d overruns its buffer", index)
+	}
+	return data[view.ByteOffset:end], view.ByteStride, nil
+}
+
+func (dec *decoder) material(index int) (*obj.Material, error) {
+	src := dec.doc.Materials[index]
+
+	mat := &obj.Material{
+		Name:           src.Name,
+		Opacity:        1,
+		Metallic:       1,
+		Roughness:      1,
+		OpticalDensity: 1,
+		Diffuse:        mgl32.Vec3{1, 1, 1},
+		Transmission:   mgl32.Vec3{1, 1, 1},
+	}
+	if mat.Name == "" {
+		mat.Name = fmt.Sprintf("material%d", index)
+	}
+
+	if p := src.PbrMetallicRoughness; p != nil {
+		if len(p.BaseColorFactor) == 4 {
+			mat.Diffuse = mgl32.Vec3{p.BaseColorFactor[0], p.BaseColorFactor[1], p.BaseColorFactor[2]}
+			mat.Opacity = p.BaseColorFactor[3]
+		}
+		if p.MetallicFactor != nil {
+			mat.Metallic = *p.MetallicFactor
+		}
+		if p.RoughnessFactor != nil {
+			mat.Roughness = *p.RoughnessFactor
+		}
+
+		var err error
+		if mat.DiffuseMap, err = dec.textureMap(p.BaseColorTexture); err != nil {
+			return nil, err
+		}
+		if mat.DiffuseMap != nil {
+			mat.Texture = mat.DiffuseMap.Path
+		}
+		if mat.MetallicRoughnessMap, err = dec.textureMap(p.MetallicRoughnessTexture); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(src.EmissiveFactor) == 3 {
+		mat.Emission = mgl32.Vec3{src.EmissiveFactor[0], src.EmissiveFactor[1], src.EmissiveFactor[2]}
+	}
+
+	var err error
+	if mat.NormalMap, err = dec.textureMap(src.NormalTexture); err != nil {
+		return nil, err
+	}
+	if mat.NormalMap != nil && src.NormalTexture.Scale != nil {
+		mat.NormalMap.BumpMultiplier = *src.NormalTexture.Scale
+	}
+	if mat.OcclusionMap, err = dec.textureMap(src.OcclusionTexture); err != nil {
+		return nil, err
+	}
+	if mat.EmissiveMap, err = dec.textureMap(src.EmissiveTexture); err != nil {
+		return nil, err
+	}
+	return mat, nil
+}
+
+const wrapClampToEdge = 33071
+
+func (dec *decoder) textureMap(info *textureInfo) (*obj.TextureMap, error) {
+	if info == nil {
+		return nil, nil
+	}
+	if info.Index < 0 || info.Index >= len(dec.doc.Textures) {
+		return nil, fmt.Errorf("gltf: texture %d out of range", info.Index)
+	}
+	tex := dec.doc.Textures[info.Index]
+
+	texMap := &obj.TextureMap{
+		Scale:          mgl32.Vec3{1, 1, 1},
+		BumpMultiplier: 1,
+		Gain:           1,
+	}
+
+	if tex.Sampler != nil && *tex.Sampler >= 0 && *tex.Sampler < len(dec.doc.Samplers) {
+		s := dec.doc.Samplers[*tex.Sampler]
+		texMap.Clamp = s.WrapS != nil && *s.WrapS == wrapClampToEdge &&
+			s.WrapT != nil && *s.WrapT == wrapClampToEdge
+	}
+
+	if tex.Source == nil {
+		return texMap, nil
+	}
+	if *tex.Source < 0 || *tex.Source >= len(dec.doc.Images) {
+		return nil, fmt.Errorf("gltf: image %d out of range", *tex.Source)
+	}
+	img := dec.doc.Images[*tex.Source]
+
+	switch {
+	case img.BufferView != nil:
+		data, _, err := dec.bufferViewData(*img.BufferView)
+		if err != nil {
+			return nil, err
+		}
+		texMap.Data = data
+		texMap.Path = img.Name
+	case strings.HasPrefix(img.URI, "data:"):
+		data, err := dec.readURI(img.URI)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: image %d: %w", *tex.Source, err)
+		}
+		texMap.Data = data
+		texMap.Path = img.Name
+	default:
+		texMap.Path = dec.uriPath(img.URI)
+	}
+	return texMap, nil
+}
+
+func convertCamera(c camera) *Camera {
+	cam := &Camera{Name: c.Name}
+	if c.Type == "orthographic" && c.Orthographic != nil {
+		cam.Orthographic = true
+		cam.XMag = c.Orthographic.Xmag
+		cam.YMag = c.Orthographic.Ymag
+		cam.ZNear = c.Orthographic.Znear
+		cam.ZFar = c.Orthographic.Zfar
+		return cam
+	}
+	if c.Perspective != nil {
+		cam.YFov = c.Perspective.Yfov
+		cam.ZNear = c.Perspective.Znear
+		if c.Perspective.AspectRatio != nil {
+			cam.AspectRatio = *c.Perspective.AspectRatio
+		}
+		if c.Perspective.Zfar != nil {
+			cam.ZFar = *c.Perspective.Zfar
+		}
+	}
+	return cam
+}
+
+func (dec *decoder) skin(index int) (*Skin, error) {
+	src := dec.doc.Skins[index]
+	s := &Skin{
+		Name:     src.Name,
+		Joints:   src.Joints,
+		Skeleton: optionalIndex(src.Skeleton),
+	}
+
+	if src.InverseBindMatrices == nil {
+		for range src.Joints {
+			s.InverseBindMatrices = append(s.InverseBindMatrices, mgl32.Ident4())
+		}
+		return s, nil
+	}
+
+	vals, comps, err := dec.readFloats(*src.InverseBindMatrices)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 16 || len(vals)/16 < len(src.Joints) {
+		return nil, fmt.Errorf("gltf: skin %d has too few inverse bind matrices", index)
+	}
+	for j := range src.Joints {
+		var m mgl32.Mat4
+		copy(m[:], vals[j*16:j*16+16])
+		s.InverseBindMatrices = append(s.InverseBindMatrices, m)
+	}
+	return s, nil
+}
+
+func (dec *decoder) nodes() ([]*Node, error) {
+	nodes := make([]*Node, len(dec.doc.Nodes))
+	for i, src := range dec.doc.Nodes {
+		n := &Node{
+			Name:        src.Name,
+			Parent:      -1,
+			Children:    src.Children,
+			Mesh:        optionalIndex(src.Mesh),
+			Camera:      optionalIndex(src.Camera),
+			Skin:        optionalIndex(src.Skin),
+			Translation: mgl32.Vec3{0, 0, 0},
+			Rotation:    mgl32.QuatIdent(),
+			Scale:       mgl32.Vec3{1, 1, 1},
+		}
+
+		if len(src.Matrix) == 16 {
+			var m mgl32.Mat4
+			copy(m[:], src.Matrix)
+			n.Translation, n.Rotation, n.Scale = decompose(m)
+		}
+		if len(src.Translation) == 3 {
+			n.Translation = mgl32.Vec3{src.Translation[0], src.Translation[1], src.Translation[2]}
+		}
+		if len(src.Rotation) == 4 {
+			n.Rotation = mgl32.Quat{W: src.Rotation[3], V: mgl32.Vec3{src.Rotation[0], src.Rotation[1], src.Rotation[2]}}
+		}
+		if len(src.Scale) == 3 {
+			n.Scale = mgl32.Vec3{src.Scale[0], src.Scale[1], src.Scale[2]}
+		}
+
+		if n.Mesh >= len(dec.doc.Meshes) || n.Camera >= len(dec.doc.Cameras) || n.Skin >= len(dec.doc.Skins) {
+			return nil, fmt.Errorf("gltf: node %d references a missing mesh, camera or skin", i)
+		}
+		nodes[i] = n
+	}
+
+	for i, n := range nodes {
+		for _, child := range n.Children {
+			if child < 0 || child >= len(nodes) || child == i {
+				return nil, fmt.Errorf("gltf: node %d has invalid child %d", i, child)
+			}
+			if nodes[child].Parent != -1 {
+				return nil, fmt.Errorf("gltf: node %d has more than one parent", child)
+			}
+			nodes[child].Parent = i
+		}
+	}
+
+	// One parent per node still allows loops such as A -> B -> A, which
+	// WorldMatrix would never leave.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(nodes))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visiting:
+			return fmt.Errorf("gltf: node %d is its own ancestor", i)
+		case visited:
+			return nil
+		}
+		marks[i] = visiting
+		for _, child := range nodes[i].Children {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		marks[i] = visited
+		return nil
+	}
+	for i := range nodes {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// roots returns the nodes of the default scene, or every parentless node
+// when the file declares no scenes.
+func (dec *decoder) roots(nodes []*Node) []int {
+	if len(dec.doc.Scenes) > 0 {
+		index := 0
+		if dec.doc.Scene != nil && *dec.doc.Scene >= 0 && *dec.doc.Scene < len(dec.doc.Scenes) {
+			index = *dec.doc.Scene
+		}
+		roots := make([]int, 0)
+		for _, n := range dec.doc.Scenes[index].Nodes {
+			if n >= 0 && n < len(nodes) {
+				roots = append(roots, n)
+			}
+		}
+		return roots
+	}
+
+	roots := make([]int, 0)
+	for i, n := range nodes {
+		if n.Parent == -1 {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// LocalMatrix composes the node's translation, rotation and scale.
+func (n *Node) LocalMatrix() mgl32.Mat4 {
+	return mgl32.Translate3D(n.Translation.X(), n.Translation.Y(), n.Translation.Z()).
+		Mul4(n.Rotation.Mat4()).
+		Mul4(mgl32.Scale3D(n.Scale.X(), n.Scale.Y(), n.Scale.Z()))
+}
+
+// WorldMatrix walks up the parent chain of node index.
+func (s *Scene) WorldMatrix(index int) mgl32.Mat4 {
+	m := mgl32.Ident4()
+	for index >= 0 {
+		m = s.Nodes[index].LocalMatrix().Mul4(m)
+		index = s.Nodes[index].Parent
+	}
+	return m
+}
+
+func decompose(m mgl32.Mat4) (mgl32.Vec3, mgl32.Quat, mgl32.Vec3) {
+	translation := m.Col(3).Vec3()
+	scale := mgl32.Vec3{m.Col(0).Vec3().Len(), m.Col(1).Vec3().Len(), m.Col(2).Vec3().Len()}
+
+	rot := mgl32.Ident3()
+	for c := 0; c < 3; c++ {
+		if scale[c] != 0 {
+			rot.SetCol(c, m.Col(c).Vec3().Mul(1/scale[c]))
+		}
+	}
+	return translation, mgl32.Mat4ToQuat(rot.Mat4()), scale
+}
+
+func optionalIndex(i *int) int {
+	if i == nil {
+		return -1
+	}
+	return *i
+}
@@ -0,0 +1,413 @@
+package gltf
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"encoding/binary"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+const (
+	modeTriangles     = 4
+	modeTriangleStrip = 5
+	modeTriangleFan   = 6
+)
+
+var typeComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+func componentSize(componentType int) int {
+	switch componentType {
+	case componentByte, componentUnsignedByte:
+		return 1
+	case componentShort, componentUnsignedShort:
+		return 2
+	case componentUnsignedInt, componentFloat:
+		return 4
+	}
+	return 0
+}
+
+// readAccessor calls visit with every component of the accessor in order,
+// converted to float64 without normalisation.
+func (dec *decoder) readAccessor(index int, visit func(float64)) (int, int, error) {
+	if index < 0 || index >= len(dec.doc.Accessors) {
+		return 0, 0, fmt.Errorf("gltf: accessor %d out of range", index)
+	}
+	acc := dec.doc.Accessors[index]
+	if len(acc.Sparse) > 0 {
+		return 0, 0, fmt.Errorf("gltf: accessor %d: sparse accessors are not supported", index)
+	}
+
+	comps, ok := typeComponents[acc.Type]
+	size := componentSize(acc.ComponentType)
+	if !ok || size == 0 || acc.Count < 0 {
+		return 0, 0, fmt.Errorf("gltf: accessor %d has invalid type %s/%d", index, acc.Type, acc.ComponentType)
+	}
+
+	if acc.BufferView == nil {
+		for i := 0; i < acc.Count*comps; i++ {
+			visit(0)
+		}
+		return comps, acc.ComponentType, nil
+	}
+
+	data, stride, err := dec.bufferViewData(*acc.BufferView)
+	if err != nil {
+		return 0, 0, err
+	}
+	elemSize := comps * size
+	if stride == 0 {
+		stride = elemSize
+	}
+	if acc.Count > 0 && (acc.ByteOffset < 0 || acc.ByteOffset+(acc.Count-1)*stride+elemSize > len(data)) {
+		return 0, 0, fmt.Errorf("gltf: accessor %d overruns its buffer view", index)
+	}
+
+	for i := 0; i < acc.Count; i++ {
+		elem := data[acc.ByteOffset+i*stride:]
+		for c := 0; c < comps; c++ {
+			b := elem[c*size:]
+			switch acc.ComponentType {
+			case componentByte:
+				visit(float64(int8(b[0])))
+			case componentUnsignedByte:
+				visit(float64(b[0]))
+			case componentShort:
+				visit(float64(int16(binary.LittleEndian.Uint16(b))))
+			case componentUnsignedShort:
+				visit(float64(binary.LittleEndian.Uint16(b)))
+			case componentUnsignedInt:
+				visit(float64(binary.LittleEndian.Uint32(b)))
+			case componentFloat:
+				visit(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+			}
+		}
+	}
+	return comps, acc.ComponentType, nil
+}
+
+// readFloats returns the accessor's components as floats, applying the
+// normalisation rules for integer components when the accessor is
+// normalized.
+func (dec *decoder) readFloats(index int) ([]float32, int, error) {
+	var values []float64
+	comps, componentType, err := dec.readAccessor(index, func(v float64) {
+		values = append(values, v)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	normalized := dec.doc.Accessors[index].Normalized
+	out := make([]float32, len(values))
+	for i, v := range values {
+		if normalized {
+			v = normalize(v, componentType)
+		}
+		out[i] = float32(v)
+	}
+	return out, comps, nil
+}
+
+func normalize(v float64, componentType int) float64 {
+	switch componentType {
+	case componentByte:
+		return math.Max(v/127, -1)
+	case componentUnsignedByte:
+		return v / 255
+	case componentShort:
+		return math.Max(v/32767, -1)
+	case componentUnsignedShort:
+		return v / 65535
+	case componentUnsignedInt:
+		return v / 4294967295
+	}
+	return v
+}
+
+func (dec *decoder) readInts(index int) ([]uint32, int, error) {
+	var values []uint32
+	comps, _, err := dec.readAccessor(index, func(v float64) {
+		values = append(values, uint32(v))
+	})
+	return values, comps, err
+}
+
+// primitiveData holds one primitive's attributes after they have been read
+// and expanded to a triangle list.
+type primitiveData struct {
+	positions []float32
+	normals   []float32
+	uvs       []float32
+	tangents  []float32
+	joints    []uint32
+	weights   []float32
+	indices   []uint32
+}
+
+func (dec *decoder) mesh(index int, materials []*obj.Material) (*Mesh, error) {
+	src := dec.doc.Meshes[index]
+	m := &Mesh{
+		Name: src.Name,
+		Mesh: &obj.Mesh{Materials: make(map[string]*obj.Material)},
+	}
+
+	skinned := false
+	for p, prim := range src.Primitives {
+		mode := modeTriangles
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode != modeTriangles && mode != modeTriangleStrip && mode != modeTriangleFan {
+			continue
+		}
+
+		data, err := dec.primitive(prim, mode)
+		if err != nil {
+			return nil, fmt.Errorf("gltf: mesh %d primitive %d: %w", index, p, err)
+		}
+		if data.normals == nil {
+			data.flatten()
+		}
+
+		materialName := ""
+		if prim.Material != nil {
+			if *prim.Material < 0 || *prim.Material >= len(materials) {
+				return nil, fmt.Errorf("gltf: mesh %d primitive %d: material %d out of range", index, p, *prim.Material)
+			}
+			mat := materials[*prim.Material]
+			materialName = mat.Name
+			m.Mesh.Materials[mat.Name] = mat
+		}
+
+		if data.joints != nil && !skinned {
+			skinned = true
+			m.Joints = make([][4]uint16, m.Mesh.VertexCount())
+			m.Weights = make([][4]float32, m.Mesh.VertexCount())
+		}
+		first := m.Mesh.VertexCount()
+		m.appendPrimitive(data, materialName, skinned)
+		if data.tangents == nil {
+			// Generate over this primitive's vertices only, keeping the
+			// tangents other primitives supply.
+			generated := &obj.Mesh{Vertices: m.Mesh.Vertices[first*obj.VertexStride:], Indices: data.indices}
+			generated.GenerateTangents()
+		}
+	}
+
+	m.Mesh.ComputeBounds()
+	return m, nil
+}
+
+func (dec *decoder) primitive(prim primitive, mode int) (*primitiveData, error) {
+	position, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return nil, fmt.Errorf("missing POSITION attribute")
+	}
+
+	data := &primitiveData{}
+	var err error
+	if data.positions, err = dec.readAttribute(position, 3); err != nil {
+		return nil, err
+	}
+	count := len(data.positions) / 3
+
+	attributes := []struct {
+		name  string
+		comps int
+		dst   *[]float32
+	}{
+		{"NORMAL", 3, &data.normals},
+		{"TEXCOORD_0", 2, &data.uvs},
+		{"TANGENT", 4, &data.tangents},
+		{"WEIGHTS_0", 4, &data.weights},
+	}
+	for _, attr := range attributes {
+		accessor, ok := prim.Attributes[attr.name]
+		if !ok {
+			continue
+		}
+		values, err := dec.readAttribute(accessor, attr.comps)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != count*attr.comps {
+			return nil, fmt.Errorf("%s has %d elements, expected %d", attr.name, len(values)/attr.comps, count)
+		}
+		*attr.dst = values
+	}
+
+	if accessor, ok := prim.Attributes["JOINTS_0"]; ok {
+		joints, comps, err := dec.readInts(accessor)
+		if err != nil {
+			return nil, err
+		}
+		if comps != 4 || len(joints) != count*4 {
+			return nil, fmt.Errorf("JOINTS_0 must be VEC4 with one element per vertex")
+		}
+		data.joints = joints
+	}
+
+	var indices []uint32
+	if prim.Indices != nil {
+		if indices, _, err = dec.readInts(*prim.Indices); err != nil {
+			return nil, err
+		}
+		for _, i := range indices {
+			if int(i) >= count {
+				return nil, fmt.Errorf("index %d out of range", i)
+			}
+		}
+	} else {
+		indices = make([]uint32, count)
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+	data.indices = triangulate(indices, mode)
+	return data, nil
+}
+
+func (dec *decoder) readAttribute(accessor, comps int) ([]float32, error) {
+	values, got, err := dec.readFloats(accessor)
+	if err != nil {
+		return nil, err
+	}
+	if got != comps {
+		return nil, fmt.Errorf("accessor %d has %d components, expected %d", accessor, got, comps)
+	}
+	return values, nil
+}
+
+// triangulate converts strip and fan index lists to a plain triangle list.
+func triangulate(indices []uint32, mode int) []uint32 {
+	switch mode {
+	case modeTriangleStrip:
+		var out []uint32
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				out = append(out, indices[i], indices[i+1], indices[i+2])
+			} else {
+				out = append(out, indices[i+1], indices[i], indices[i+2])
+			}
+		}
+		return out
+	case modeTriangleFan:
+		var out []uint32
+		for i := 1; i+1 < len(indices); i++ {
+			out = append(out, indices[0], indices[i], indices[i+1])
+		}
+		return out
+	}
+	return indices[:len(indices)/3*3]
+}
+
+// flatten de-indexes the primitive so every triangle corner gets its own
+// vertex, then assigns each corner its face normal. The spec requires flat
+// shading when NORMAL is absent.
+func (data *primitiveData) flatten() {
+	expand := func(src []float32, comps int) []float32 {
+		if src == nil {
+			return nil
+		}
+		out := make([]float32, 0, len(data.indices)*comps)
+		for _, i := range data.indices {
+			out = append(out, src[int(i)*comps:int(i)*comps+comps]...)
+		}
+		return out
+	}
+
+	data.positions = expand(data.positions, 3)
+	data.uvs = expand(data.uvs, 2)
+	data.tangents = expand(data.tangents, 4)
+	data.weights = expand(data.weights, 4)
+	if data.joints != nil {
+		joints := make([]uint32, 0, len(data.indices)*4)
+		for _, i := range data.indices {
+			joints = append(joints, data.joints[i*4:i*4+4]...)
+		}
+		data.joints = joints
+	}
+
+	data.normals = make([]float32, len(data.positions))
+	data.indices = make([]uint32, len(data.positions)/3)
+	for t := 0; t+2 < len(data.indices); t += 3 {
+		p := func(c int) mgl32.Vec3 {
+			base := (t + c) * 3
+			return mgl32.Vec3{data.positions[base], data.positions[base+1], data.positions[base+2]}
+		}
+		normal := p(1).Sub(p(0)).Cross(p(2).Sub(p(0)))
+		if normal.Len() > 0 {
+			normal = normal.Normalize()
+		}
+		for c := 0; c < 3; c++ {
+			copy(data.normals[(t+c)*3:], normal[:])
+			data.indices[t+c] = uint32(t + c)
+		}
+	}
+}
+
+func (m *Mesh) appendPrimitive(data *primitiveData, material string, skinned bool) {
+	base := uint32(m.Mesh.VertexCount())
+	count := len(data.positions) / 3
+
+	for v := 0; v < count; v++ {
+		var vertex [obj.VertexStride]float32
+		copy(vertex[obj.PositionOffset:], data.positions[v*3:v*3+3])
+		if data.uvs != nil {
+			// glTF puts the UV origin at the top left, OBJ at the bottom left.
+			vertex[obj.UVOffset] = data.uvs[v*2]
+			vertex[obj.UVOffset+1] = 1 - data.uvs[v*2+1]
+		}
+		copy(vertex[obj.NormalOffset:], data.normals[v*3:v*3+3])
+		if data.tangents != nil {
+			copy(vertex[obj.TangentOffset:], data.tangents[v*4:v*4+4])
+			// Flipping V mirrors the bitangent.
+			vertex[obj.TangentOffset+3] = -vertex[obj.TangentOffset+3]
+		}
+		m.Mesh.Vertices = append(m.Mesh.Vertices, vertex[:]...)
+
+		if skinned {
+			var joints [4]uint16
+			var weights [4]float32
+			if data.joints != nil {
+				for c := 0; c < 4; c++ {
+					joints[c] = uint16(data.joints[v*4+c])
+				}
+			}
+			if data.weights != nil {
+				copy(weights[:], data.weights[v*4:v*4+4])
+			}
+			m.Joints = append(m.Joints, joints)
+			m.Weights = append(m.Weights, weights)
+		}
+	}
+
+	start := len(m.Mesh.Indices)
+	for _, i := range data.indices {
+		m.Mesh.Indices = append(m.Mesh.Indices, base+i)
+	}
+	m.Mesh.Submeshes = append(m.Mesh.Submeshes, obj.Submesh{
+		Material: material,
+		Start:    start,
+		Count:    len(data.indices),
+	})
+}
@@ -0,0 +1,288 @@
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Binary mesh cache layout, all values little-endian:
+//
+//	magic "PGEM", version uint32
+//	checksum [32]byte, SHA-256 over the OBJ and its material libraries
+//	library count uint32, then per library: length uint16, name
+//	vertex stride, vertex count, index count, submesh count: uint32 each
+//	bounds min and max: 3 float32 each
+//	vertices float32 * stride * count, indices uint32 * count
+//	per submesh: name length uint16, name, start uint32, count uint32
+//
+// Materials are not cached; the MTL files are small and always re-read.
+const (
+	meshCacheMagic   = "PGEM"
+	meshCacheVersion = 1
+)
+
+var errStaleCache = errors.New("mesh cache is stale")
+
+// CachePath returns where LoadMesh keeps the cache for objPath.
+func CachePath(objPath string) string {
+	return objPath + ".meshcache"
+}
+
+// LoadMesh returns the mesh for the OBJ at objPath, reading it from the
+// binary cache next to the file when the cache matches the current OBJ and
+// MTL contents, and decoding the OBJ and rewriting the cache otherwise.
+// Failing to write the cache is not an error. Like LoadObject it returns a
+// *MissingLibraryError alongside a usable mesh when libraries are missing.
+func LoadMesh(objPath string) (*Mesh, error) {
+	objData, err := os.ReadFile(objPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mesh, libs, err := readMeshCache(CachePath(objPath), objData, filepath.Dir(objPath))
+	if err == nil {
+		return mesh, loadCachedMaterials(mesh, objPath, libs)
+	}
+
+	dec, err := LoadObject(objPath)
+	var missing *MissingLibraryError
+	if err != nil && !errors.As(err, &missing) {
+		return nil, err
+	}
+
+	mesh, buildErr := dec.BuildMesh()
+	if buildErr != nil {
+		return nil, buildErr
+	}
+
+	libs = dec.MaterialLibraries()
+	checksum := sourceChecksum(objData, filepath.Dir(objPath), libs)
+	_ = writeMeshCache(CachePath(objPath), mesh, checksum, libs)
+	return mesh, err
+}
+
+func loadCachedMaterials(mesh *Mesh, objPath string, libs []string) error {
+	dec := newDecodedObject(DecodeOptions{})
+	var missing []string
+	for _, lib := range libs {
+		path := filepath.Join(filepath.Dir(objPath), filepath.FromSlash(lib))
+		err := dec.loadMaterialLibrary(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, path)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Materials referenced by usemtl but defined nowhere still get an
+	// entry, as they would when decoding the OBJ.
+	for _, sub := range mesh.Submeshes {
+		if sub.Material != "" && dec.Materials[sub.Material] == nil {
+			dec.Materials[sub.Material] = &Material{Name: sub.Material, placeholder: true}
+		}
+	}
+	mesh.Materials = dec.Materials
+
+	if len(missing) > 0 {
+		return &MissingLibraryError{Paths: missing}
+	}
+	return nil
+}
+
+func sourceChecksum(objData []byte, dir string, libs []string) [32]byte {
+	hash := sha256.New()
+	hash.Write(objData)
+	for _, lib := range libs {
+		fmt.Fprintf(hash, "\x00%s\x00", lib)
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(lib)))
+		if err != nil {
+			hash.Write([]byte("missing"))
+			continue
+		}
+		hash.Write(data)
+	}
+
+	var sum [32]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+func writeMeshCache(path string, mesh *Mesh, checksum [32]byte, libs []string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = EncodeMeshCache(tmp, mesh, checksum, libs)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// EncodeMeshCache writes mesh in the binary cache format. checksum and libs
+// identify the source files the mesh was built from.
+func EncodeMeshCache(writer io.Writer, mesh *Mesh, checksum [32]byte, libs []string) error {
+	w := bufio.NewWriter(writer)
+	le := binary.LittleEndian
+
+	w.WriteString(meshCacheMagic)
+	binary.Write(w, le, uint32(meshCacheVersion))
+	w.Write(checksum[:])
+
+	binary.Write(w, le, uint32(len(libs)))
+	for _, lib := range libs {
+		writeCacheString(w, lib)
+	}
+
+	binary.Write(w, le, []uint32{
+		VertexStride,
+		uint32(mesh.VertexCount()),
+		uint32(len(mesh.Indices)),
+		uint32(len(mesh.Submeshes)),
+	})
+	binary.Write(w, le, mesh.Min)
+	binary.Write(w, le, mesh.Max)
+	binary.Write(w, le, mesh.Vertices)
+	binary.Write(w, le, mesh.Indices)
+
+	for _, sub := range mesh.Submeshes {
+		writeCacheString(w, sub.Material)
+		binary.Write(w, le, []uint32{uint32(sub.Start), uint32(sub.Count)})
+	}
+
+	return w.Flush()
+}
+
+func writeCacheString(w *bufio.Writer, s string) {
+	binary.Write(w, binary.LittleEndian, uint16(len(s)))
+	w.WriteString(s)
+}
+
+func readMeshCache(path string, objData []byte, dir string) (*Mesh, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	checksum, libs, mesh, err := DecodeMeshCache(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	if checksum != sourceChecksum(objData, dir, libs) {
+		return nil, nil, errStaleCache
+	}
+	return mesh, libs, nil
+}
+
+// DecodeMeshCache reads a mesh written by EncodeMeshCache. size bounds the
+// allocations made for a corrupt header; pass the length of the input.
+func DecodeMeshCache(reader io.Reader, size int64) ([32]byte, []string, *Mesh, error) {
+	var checksum [32]byte
+	r := bufio.NewReader(reader)
+	le := binary.LittleEndian
+
+	magic := make([]byte, len(meshCacheMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != meshCacheMagic {
+		return checksum, nil, nil, fmt.Errorf("not a mesh cache")
+	}
+
+	var version uint32
+	if err := binary.Read(r, le, &version); err != nil {
+		return checksum, nil, nil, err
+	}
+	if version != meshCacheVersion {
+		return checksum, nil, nil, fmt.Errorf("mesh cache version %d, want %d", version, meshCacheVersion)
+	}
+	if _, err := io.ReadFull(r, checksum[:]); err != nil {
+		return checksum, nil, nil, err
+	}
+
+	var libCount uint32
+	if err := binary.Read(r, le, &libCount); err != nil {
+		return checksum, nil, nil, err
+	}
+	if int64(libCount)*2 > size {
+		return checksum, nil, nil, fmt.Errorf("mesh cache truncated")
+	}
+	libs := make([]string, libCount)
+	for i := range libs {
+		lib, err := readCacheString(r)
+		if err != nil {
+			return checksum, nil, nil, err
+		}
+		libs[i] = lib
+	}
+
+	var header [4]uint32
+	if err := binary.Read(r, le, &header); err != nil {
+		return checksum, nil, nil, err
+	}
+	stride, vertexCount, indexCount, submeshCount := header[0], header[1], header[2], header[3]
+	if stride != VertexStride {
+		return checksum, nil, nil, fmt.Errorf("mesh cache vertex stride %d, want %d", stride, VertexStride)
+	}
+	if (int64(vertexCount)*VertexStride+int64(indexCount)+int64(submeshCount)*2)*4 > size {
+		return checksum, nil, nil, fmt.Errorf("mesh cache truncated")
+	}
+
+	mesh := &Mesh{
+		Vertices:  make([]float32, int(vertexCount)*VertexStride),
+		Indices:   make([]uint32, indexCount),
+		Submeshes: make([]Submesh, submeshCount),
+		Materials: make(map[string]*Material),
+	}
+	for _, dst := range []interface{}{&mesh.Min, &mesh.Max, mesh.Vertices, mesh.Indices} {
+		if err := binary.Read(r, le, dst); err != nil {
+			return checksum, nil, nil, err
+		}
+	}
+
+	for i := range mesh.Submeshes {
+		name, err := readCacheString(r)
+		if err != nil {
+			return checksum, nil, nil, err
+		}
+		var rng [2]uint32
+		if err := binary.Read(r, le, &rng); err != nil {
+			return checksum, nil, nil, err
+		}
+		if uint64(rng[0])+uint64(rng[1]) > uint64(indexCount) {
+			return checksum, nil, nil, fmt.Errorf("mesh cache submesh %q out of range", name)
+		}
+		mesh.Submeshes[i] = Submesh{Material: name, Start: int(rng[0]), Count: int(rng[1])}
+	}
+
+	for _, index := range mesh.Indices {
+		if index >= vertexCount {
+			return checksum, nil, nil, fmt.Errorf("mesh cache index %d out of range", index)
+		}
+	}
+	return checksum, libs, mesh, nil
+}
+
+func readCacheString(r *bufio.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,66 @@
+package obj
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func copyModel(t *testing.T, dir, name string) string {
+	for _, file := range []string{name + ".obj", name + ".mtl"} {
+		data, err := os.ReadFile(filepath.Join(modelsDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, name+".obj")
+}
+
+func TestLoadMeshCache(t *testing.T) {
+	objPath := copyModel(t, t.TempDir(), "dagoth")
+
+	built, err := LoadMesh(objPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(CachePath(objPath)); err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+
+	cached, err := LoadMesh(objPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(built, cached) {
+		t.Fatal("cached mesh differs from the built one")
+	}
+
+	// Editing the MTL must invalidate the cache.
+	mtlPath := filepath.Join(filepath.Dir(objPath), "dagoth.mtl")
+	if err := os.WriteFile(mtlPath, []byte("newmtl Mask\nKd 1 0 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readMeshCache(CachePath(objPath), mustRead(t, objPath), filepath.Dir(objPath)); err != errStaleCache {
+		t.Fatalf("got %v, want stale cache", err)
+	}
+
+	rebuilt, err := LoadMesh(objPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.Materials["Mask"].Diffuse[0] != 1 {
+		t.Error("rebuilt mesh did not pick up the new material")
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,159 @@
+package obj
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/models.golden.json")
+
+const modelsDir = "../res/models"
+
+type modelCounts struct {
+	Vertices     int
+	UVs          int
+	Normals      int
+	Faces        int
+	Objects      int
+	Materials    int
+	MeshVertices int
+	MeshIndices  int
+	Warnings     int
+}
+
+func countModel(t *testing.T, path string) modelCounts {
+	dec, err := LoadObject(path)
+	if _, missing := err.(*MissingLibraryError); err != nil && !missing {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	mesh, err := dec.BuildMesh()
+	if err != nil {
+		t.Fatalf("%s: building mesh: %v", path, err)
+	}
+
+	counts := modelCounts{
+		Vertices:     len(dec.Vertices) / 3,
+		UVs:          len(dec.UVs) / 2,
+		Normals:      len(dec.Normals) / 3,
+		Objects:      len(dec.Objects),
+		Materials:    len(dec.Materials),
+		MeshVertices: mesh.VertexCount(),
+		MeshIndices:  len(mesh.Indices),
+		Warnings:     len(dec.Warnings),
+	}
+	for _, o := range dec.Objects {
+		counts.Faces += len(o.Faces)
+	}
+	return counts
+}
+
+func TestGoldenModels(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(modelsDir, "*.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no models found in %s", modelsDir)
+	}
+
+	got := make(map[string]modelCounts)
+	for _, path := range paths {
+		got[filepath.Base(path)] = countModel(t, path)
+	}
+
+	goldenPath := filepath.Join("testdata", "models.golden.json")
+	if *update {
+		data, err := json.MarshalIndent(got, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenPath, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	want := make(map[string]modelCounts)
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, counts := range got {
+		expected, ok := want[name]
+		if !ok {
+			t.Errorf("%s: no golden entry", name)
+			continue
+		}
+		if counts != expected {
+			t.Errorf("%s:\n got %+v\nwant %+v", name, counts, expected)
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s: golden entry but no model", name)
+		}
+	}
+}
+
+func readSeed(f *testing.F, name string) []byte {
+	data, err := os.ReadFile(filepath.Join(modelsDir, name))
+	if err != nil {
+		f.Fatal(err)
+	}
+	return data
+}
+
+func FuzzDecodeObject(f *testing.F) {
+	f.Add(readSeed(f, "cube.obj"), readSeed(f, "cube.mtl"))
+	f.Add(readSeed(f, "plane.obj"), readSeed(f, "2b.mtl"))
+	f.Add([]byte("v 1\nvt\nvn 1 2\nf 1/1/1 -1 2//9\ns\no\ng\nusemtl\nmtllib\n"), []byte(""))
+
+	f.Fuzz(func(t *testing.T, objData, mtlData []byte) {
+		for _, strict := range []bool{false, true} {
+			dec, err := DecodeObjectWithOptions(bytes.NewReader(objData), bytes.NewReader(mtlData), DecodeOptions{Strict: strict})
+			if err != nil {
+				if _, ok := err.(*ParseError); !ok {
+					t.Fatalf("error of type %T: %v", err, err)
+				}
+				continue
+			}
+			if _, err := dec.BuildMesh(); err != nil {
+				t.Fatalf("decoded object does not build: %v", err)
+			}
+			dec.GenerateNormals(30)
+		}
+	})
+}
+
+func FuzzDecodeMaterials(f *testing.F) {
+	f.Add(readSeed(f, "2b.mtl"))
+	f.Add(readSeed(f, "dagoth.mtl"))
+	f.Add([]byte("Kd 1\nnewmtl\nnewmtl a\nKa 1 2\nTf xyz\nmap_Kd -mm 1\nrefl -type\nd -halo\nillum x\n"))
+
+	f.Fuzz(func(t *testing.T, mtlData []byte) {
+		for _, strict := range []bool{false, true} {
+			dec, err := DecodeObjectWithOptions(strings.NewReader(""), bytes.NewReader(mtlData), DecodeOptions{Strict: strict})
+			if err != nil {
+				if _, ok := err.(*ParseError); !ok {
+					t.Fatalf("error of type %T: %v", err, err)
+				}
+				continue
+			}
+			for name, mat := range dec.Materials {
+				if mat == nil || mat.Name != name {
+					t.Fatalf("material %q stored inconsistently", name)
+				}
+			}
+		}
+	})
+}
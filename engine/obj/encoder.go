@@ -0,0 +1,245 @@
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encode writes dec as an OBJ to objWriter and its materials as an MTL to
+// mtlWriter. mtlLib is the name written in the OBJ's mtllib statement and
+// may be empty when there are no materials to reference. Normals generated
+// by GenerateNormals are left out, so decoding the output regenerates them.
+func Encode(objWriter, mtlWriter io.Writer, dec *DecodedObject, mtlLib string) error {
+	err := encodeObj(objWriter, dec, mtlLib)
+	if err != nil {
+		return err
+	}
+	return encodeMtl(mtlWriter, dec, "")
+}
+
+// SaveObject writes dec to objPath and its materials to a companion MTL
+// with the same base name. Texture paths are rewritten relative to the
+// MTL's directory where possible.
+func SaveObject(objPath string, dec *DecodedObject) error {
+	mtlPath := strings.TrimSuffix(objPath, filepath.Ext(objPath)) + ".mtl"
+
+	objFile, err := os.Create(objPath)
+	if err != nil {
+		return err
+	}
+	defer objFile.Close()
+
+	mtlFile, err := os.Create(mtlPath)
+	if err != nil {
+		return err
+	}
+	defer mtlFile.Close()
+
+	err = encodeObj(objFile, dec, filepath.Base(mtlPath))
+	if err != nil {
+		return err
+	}
+	err = encodeMtl(mtlFile, dec, filepath.Dir(mtlPath))
+	if err != nil {
+		return err
+	}
+
+	if err := objFile.Close(); err != nil {
+		return err
+	}
+	return mtlFile.Close()
+}
+
+func formatFloat(f float32) string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 32)
+}
+
+func joinFloats(vals []float32) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = formatFloat(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeFloats(w *bufio.Writer, keyword string, vals []float32) {
+	fmt.Fprintf(w, "%s %s\n", keyword, joinFloats(vals))
+}
+
+func encodeObj(writer io.Writer, dec *DecodedObject, mtlLib string) error {
+	w := bufio.NewWriter(writer)
+
+	if mtlLib != "" {
+		fmt.Fprintf(w, "mtllib %s\n", mtlLib)
+	}
+
+	for v := 0; v+2 < len(dec.Vertices); v += 3 {
+		writeFloats(w, "v", dec.Vertices[v:v+3])
+	}
+	for vt := 0; vt+1 < len(dec.UVs); vt += 2 {
+		writeFloats(w, "vt", dec.UVs[vt:vt+2])
+	}
+
+	normals := len(dec.Normals) / 3
+	if dec.normalsGenerated {
+		normals = dec.parsedNormals
+	}
+	for vn := 0; vn < normals; vn++ {
+		writeFloats(w, "vn", dec.Normals[vn*3:vn*3+3])
+	}
+
+	material := ""
+	smooth := 0
+	for _, object := range dec.Objects {
+		if object.Group {
+			fmt.Fprintf(w, "g %s\n", object.Name)
+		} else {
+			fmt.Fprintf(w, "o %s\n", object.Name)
+		}
+
+		for _, face := range object.Faces {
+			if face.Material != material && face.Material != "" {
+				fmt.Fprintf(w, "usemtl %s\n", face.Material)
+				material = face.Material
+			}
+			if face.Smooth != smooth {
+				if face.Smooth == 0 {
+					w.WriteString("s off\n")
+				} else {
+					fmt.Fprintf(w, "s %d\n", face.Smooth)
+				}
+				smooth = face.Smooth
+			}
+
+			w.WriteString("f")
+			for pos, v := range face.Vertices {
+				fmt.Fprintf(w, " %d", v+1)
+
+				uv := face.UVs[pos]
+				normal := face.Normals[pos]
+				hasUV := uv != math.MaxUint32
+				hasNormal := normal != math.MaxUint32 && normal < normals
+				if hasUV || hasNormal {
+					w.WriteByte('/')
+				}
+				if hasUV {
+					fmt.Fprintf(w, "%d", uv+1)
+				}
+				if hasNormal {
+					fmt.Fprintf(w, "/%d", normal+1)
+				}
+			}
+			w.WriteByte('\n')
+		}
+	}
+
+	return w.Flush()
+}
+
+// encodeMtl writes texture paths relative to dir when dir is set.
+func encodeMtl(writer io.Writer, dec *DecodedObject, dir string) error {
+	w := bufio.NewWriter(writer)
+
+	names := make([]string, 0, len(dec.Materials))
+	for name, mat := range dec.Materials {
+		if !mat.placeholder {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		mat := dec.Materials[name]
+		if i > 0 {
+			w.WriteByte('\n')
+		}
+		fmt.Fprintf(w, "newmtl %s\n", name)
+		if mat.HasAmbient {
+			writeFloats(w, "Ka", mat.Ambient[:])
+		}
+		writeFloats(w, "Kd", mat.Diffuse[:])
+		writeFloats(w, "Ks", mat.Specular[:])
+		writeFloats(w, "Ke", mat.Emission[:])
+		writeFloats(w, "Tf", mat.Transmission[:])
+		writeFloats(w, "Ns", []float32{mat.Shininess})
+		writeFloats(w, "Ni", []float32{mat.OpticalDensity})
+		writeFloats(w, "d", []float32{mat.Opacity})
+		fmt.Fprintf(w, "illum %d\n", mat.Illumination)
+		if mat.Metallic != 0 {
+			writeFloats(w, "Pm", []float32{mat.Metallic})
+		}
+		if mat.Roughness != 0 {
+			writeFloats(w, "Pr", []float32{mat.Roughness})
+		}
+
+		diffuse := mat.DiffuseMap
+		if diffuse == nil && mat.Texture != "" {
+			diffuse = &TextureMap{Path: mat.Texture, Scale: mgl32.Vec3{1, 1, 1}, BumpMultiplier: 1, Gain: 1}
+		}
+
+		maps := []struct {
+			keyword string
+			texMap  *TextureMap
+		}{
+			{"map_Ka", mat.AmbientMap},
+			{"map_Kd", diffuse},
+			{"map_Ks", mat.SpecularMap},
+			{"map_Ke", mat.EmissiveMap},
+			{"map_Ns", mat.ShininessMap},
+			{"map_d", mat.DissolveMap},
+			{"map_Bump", mat.BumpMap},
+			{"norm", mat.NormalMap},
+			{"disp", mat.DisplacementMap},
+			{"refl", mat.ReflectionMap},
+		}
+		for _, m := range maps {
+			if m.texMap != nil {
+				writeTextureMap(w, m.keyword, m.texMap, dir)
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+func writeTextureMap(w *bufio.Writer, keyword string, texMap *TextureMap, dir string) {
+	w.WriteString(keyword)
+	if texMap.Type != "" {
+		fmt.Fprintf(w, " -type %s", texMap.Type)
+	}
+	if texMap.Offset != (mgl32.Vec3{}) {
+		fmt.Fprintf(w, " -o %s", joinFloats(texMap.Offset[:]))
+	}
+	if texMap.Scale != (mgl32.Vec3{1, 1, 1}) {
+		fmt.Fprintf(w, " -s %s", joinFloats(texMap.Scale[:]))
+	}
+	if texMap.Clamp {
+		w.WriteString(" -clamp on")
+	}
+	if texMap.BumpMultiplier != 1 {
+		fmt.Fprintf(w, " -bm %s", formatFloat(texMap.BumpMultiplier))
+	}
+	if texMap.Base != 0 || texMap.Gain != 1 {
+		fmt.Fprintf(w, " -mm %s %s", formatFloat(texMap.Base), formatFloat(texMap.Gain))
+	}
+
+	path := texMap.Path
+	if dir != "" {
+		absPath, err := filepath.Abs(path)
+		absDir, dirErr := filepath.Abs(dir)
+		if err == nil && dirErr == nil {
+			if rel, err := filepath.Rel(absDir, absPath); err == nil {
+				path = rel
+			}
+		}
+	}
+	fmt.Fprintf(w, " %s\n", filepath.ToSlash(path))
+}
@@ -0,0 +1,79 @@
+package obj
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func roundTrip(t *testing.T, dec *DecodedObject) *DecodedObject {
+	var objBuf, mtlBuf bytes.Buffer
+	if err := Encode(&objBuf, &mtlBuf, dec, "out.mtl"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := DecodeObjectWithOptions(&objBuf, &mtlBuf, DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("decoding encoder output: %v", err)
+	}
+	return out
+}
+
+func compareDecoded(t *testing.T, name string, got, want *DecodedObject) {
+	if !reflect.DeepEqual(got.Vertices, want.Vertices) {
+		t.Errorf("%s: vertices differ", name)
+	}
+	if !reflect.DeepEqual(got.UVs, want.UVs) {
+		t.Errorf("%s: UVs differ", name)
+	}
+	if !reflect.DeepEqual(got.Normals, want.Normals) {
+		t.Errorf("%s: normals differ", name)
+	}
+	if len(got.Objects) != len(want.Objects) {
+		t.Fatalf("%s: got %d objects, want %d", name, len(got.Objects), len(want.Objects))
+	}
+	for i := range want.Objects {
+		g, w := got.Objects[i], want.Objects[i]
+		if g.Name != w.Name || g.Group != w.Group || !reflect.DeepEqual(g.Faces, w.Faces) {
+			t.Errorf("%s: object %d (%s) differs", name, i, w.Name)
+		}
+	}
+	if !reflect.DeepEqual(got.Materials, want.Materials) {
+		t.Errorf("%s: materials differ", name)
+	}
+}
+
+func TestEncodeRoundTripModels(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(modelsDir, "*.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		dec, err := LoadObject(path)
+		if _, missing := err.(*MissingLibraryError); err != nil && !missing {
+			t.Fatalf("%s: %v", path, err)
+		}
+		compareDecoded(t, filepath.Base(path), roundTrip(t, dec), dec)
+	}
+}
+
+func TestEncodeRoundTripStatements(t *testing.T) {
+	src := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvt 0 0\nvt 1 1\nvn 0 0 1\n" +
+		"o first\nusemtl a\nf 1/1/1 2/2/1 3/1/1\ns 2\nf 1 3 4\n" +
+		"g second\nusemtl b\nf 1//1 3//1 4//1\ns off\nf 1/2 2/1 3/2 4/1\n"
+	mtl := "newmtl a\nKd 0.25 0.5 1\nNs 96\nPm 0.5\nPr 0.75\nmap_Ke glow.png\nmap_Kd -o 0.5 -s 2 2 -clamp on tex/diffuse map.png\n" +
+		"newmtl b\nKa 0 0 0\nd 0.5\nTf 0.1 0.2 0.3\nillum 4\nmap_Bump -bm 0.25 normal.png\nrefl -type sphere -mm 0.1 0.8 sky.png\n"
+
+	dec, err := DecodeObjectWithOptions(strings.NewReader(src), strings.NewReader(mtl), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only b gives Ka, and its black ambient must survive the trip.
+	if dec.Materials["a"].HasAmbient || !dec.Materials["b"].HasAmbient {
+		t.Error("HasAmbient does not follow Ka")
+	}
+	compareDecoded(t, "statements", roundTrip(t, dec), dec)
+}
@@ -0,0 +1,115 @@
+package obj
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// ParseError describes a problem with one statement of an OBJ or MTL file,
+// or one element of a PLY or STL file. Line and Column are 1-based; Column
+// is 0 when the problem concerns the statement as a whole, and Line is 0
+// for problems in binary data.
+type ParseError struct {
+	File      string
+	Line      int
+	Column    int
+	Statement string
+	Severity  Severity
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "<input>"
+	}
+	pos := file
+	if e.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, e.Line)
+	}
+	if e.Line > 0 && e.Column > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, e.Column)
+	}
+	if e.Statement != "" {
+		return fmt.Sprintf("%s: %s: %s: %v", pos, e.Severity, e.Statement, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", pos, e.Severity, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeOptions controls how problems in the input are handled. In the
+// default lenient mode every problem is recorded in DecodedObject.Warnings
+// and the offending statement is skipped (or, for v/vt/vn, padded with
+// zeros so later indices stay valid). In strict mode the first problem of
+// any severity is returned as a *ParseError.
+type DecodeOptions struct {
+	Strict bool
+
+	// ObjName and MtlName are used as ParseError.File when decoding from
+	// readers, which carry no name of their own.
+	ObjName string
+	MtlName string
+}
+
+func (dec *DecodedObject) newError(sev Severity, field int, err error) *ParseError {
+	column := 0
+	if field >= 0 && field < len(dec.cols) {
+		column = dec.cols[field] + 1
+	}
+	return &ParseError{
+		File:      dec.file,
+		Line:      int(dec.line),
+		Column:    column,
+		Statement: dec.stmt,
+		Severity:  sev,
+		Err:       err,
+	}
+}
+
+// errorf reports a problem that stops the current statement from being
+// applied. field indexes the statement's arguments, or is -1.
+func (dec *DecodedObject) errorf(field int, format string, args ...interface{}) error {
+	return dec.newError(SeverityError, field, fmt.Errorf(format, args...))
+}
+
+// warnf records a problem the parser can work around. It only returns an
+// error in strict mode.
+func (dec *DecodedObject) warnf(field int, format string, args ...interface{}) error {
+	perr := dec.newError(SeverityWarning, field, fmt.Errorf(format, args...))
+	if dec.strict {
+		return perr
+	}
+	dec.Warnings = append(dec.Warnings, perr)
+	return nil
+}
+
+// report decides what happens to an error returned by a statement parser:
+// strict mode stops, lenient mode records it and carries on.
+func (dec *DecodedObject) report(err error) error {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		perr = dec.newError(SeverityError, -1, err)
+	}
+	if dec.strict {
+		return perr
+	}
+	dec.Warnings = append(dec.Warnings, perr)
+	return nil
+}
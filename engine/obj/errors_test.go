@@ -0,0 +1,64 @@
+package obj
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const malformedObj = "v 1 2\nv 0 0 0\nv 1 0 0\nf 1 2\nf 1 2 x\nf 2 3 9\nf 1 2 3\ns maybe\n"
+const malformedMtl = "Kd 1 1 1\nnewmtl a\nKd 1 1\n"
+
+func TestLenientCollectsWarnings(t *testing.T) {
+	dec, err := DecodeObjectWithOptions(strings.NewReader(malformedObj), strings.NewReader(malformedMtl),
+		DecodeOptions{ObjName: "m.obj", MtlName: "m.mtl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, w := range dec.Warnings {
+		got = append(got, w.Error())
+	}
+	want := []string{
+		"m.obj:1: warning: v: expected 3 values, got 2",
+		"m.obj:4: error: f: less than 3 vertices in face definition",
+		`m.obj:5:7: error: f: invalid vertex index: "x"`,
+		"m.obj:6:7: error: f: vertex index 9 out of range (3 defined)",
+		`m.obj:8:3: error: s: invalid smoothing group: "maybe"`,
+		"m.mtl:1: error: Kd: statement before any newmtl",
+		"m.mtl:3: error: Kd: expected 1 or 3 values, got 2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings\n%s", strings.Join(got, "\n"))
+	}
+
+	// The short vertex is padded so the indices after it stay valid, and
+	// only the good face is kept.
+	if len(dec.Vertices) != 9 || len(dec.Objects[0].Faces) != 1 {
+		t.Errorf("got %d vertex floats, %d faces", len(dec.Vertices), len(dec.Objects[0].Faces))
+	}
+	if dec.Warnings[0].Severity != SeverityWarning || dec.Warnings[1].Severity != SeverityError {
+		t.Error("wrong severities")
+	}
+}
+
+func TestStrictStopsAtFirstProblem(t *testing.T) {
+	_, err := DecodeObjectWithOptions(strings.NewReader(malformedObj), strings.NewReader(""),
+		DecodeOptions{Strict: true, ObjName: "m.obj"})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v", err)
+	}
+	if perr.File != "m.obj" || perr.Line != 1 || perr.Statement != "v" || perr.Severity != SeverityWarning {
+		t.Errorf("got %+v", perr)
+	}
+}
+
+func TestMalformedInputDoesNotPanic(t *testing.T) {
+	for _, src := range []string{"f\n", "v\n", "vt\n", "vn 1\n", "usemtl\n", "o\n", "f 1/\n", "f //\n", "f -5 -6 -7\n", "mtllib\n"} {
+		if _, err := DecodeObject(strings.NewReader(src), strings.NewReader("newmtl\nmap_Kd -s\nKa\nillum x\n")); err != nil {
+			t.Errorf("%q: lenient decode failed: %v", src, err)
+		}
+	}
+}
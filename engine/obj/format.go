@@ -0,0 +1,100 @@
+package obj
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatOBJ
+	FormatPLY
+	FormatSTL
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatOBJ:
+		return "OBJ"
+	case FormatPLY:
+		return "PLY"
+	case FormatSTL:
+		return "STL"
+	}
+	return "unknown"
+}
+
+var formatExtensions = map[string]Format{
+	".obj": FormatOBJ,
+	".ply": FormatPLY,
+	".stl": FormatSTL,
+}
+
+// DetectFormat works out the format of a model file from its leading bytes
+// and, when those are not conclusive, from its name. data should be the
+// whole file for binary STL to be recognised; a prefix is enough for the
+// other formats.
+func DetectFormat(name string, data []byte) Format {
+	if bytes.HasPrefix(data, []byte("ply\n")) || bytes.HasPrefix(data, []byte("ply\r\n")) {
+		return FormatPLY
+	}
+	if stlSizeMatches(data) {
+		return FormatSTL
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("solid")) && bytes.Contains(trimmed, []byte("facet")) {
+		return FormatSTL
+	}
+
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(name))]; ok {
+		return format
+	}
+	if looksLikeOBJ(data) {
+		return FormatOBJ
+	}
+	return FormatUnknown
+}
+
+// looksLikeOBJ reports whether the first statement of data is an OBJ one.
+func looksLikeOBJ(data []byte) bool {
+	for _, line := range strings.SplitN(string(data[:min(len(data), 4096)]), "\n", 64) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "v", "vt", "vn", "f", "o", "g", "s", "mtllib", "usemtl":
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// LoadAny loads an OBJ, PLY or STL file, picking the decoder with
+// DetectFormat. OBJ files go through LoadObjectWithOptions, so their
+// material libraries are resolved and a *MissingLibraryError may be returned
+// alongside the object.
+func LoadAny(path string, opts DecodeOptions) (*DecodedObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.ObjName = path
+	switch format := DetectFormat(path, data); format {
+	case FormatOBJ:
+		return LoadObjectWithOptions(path, opts)
+	case FormatPLY:
+		return DecodePLY(bytes.NewReader(data), opts)
+	case FormatSTL:
+		return DecodeSTL(bytes.NewReader(data), opts)
+	default:
+		return nil, fmt.Errorf("%s: unrecognised model format", path)
+	}
+}
@@ -0,0 +1,302 @@
+package obj
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const asciiPLY = `ply
+format ascii 1.0
+comment a coloured quad with a confidence value per vertex
+element vertex 4
+property float x
+property float y
+property float z
+property float s
+property float t
+property uchar red
+property uchar green
+property uchar blue
+property float confidence
+element face 1
+property list uchar int vertex_indices
+property uchar flags
+end_header
+0 0 0 0 0 255 0 0 0.5
+1 0 0 1 0 0 255 0 0.25
+1 1 0 1 1 0 0 255 1
+0 1 0 0 1 255 255 255 0
+4 0 1 2 3 7
+`
+
+// binaryPLY encodes the same triangle twice over with per-vertex normals
+// and a per-corner texcoord list.
+func binaryPLY(order binary.ByteOrder, format string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat " + format + " 1.0\n" +
+		"element vertex 3\nproperty double x\nproperty double y\nproperty double z\n" +
+		"property float nx\nproperty float ny\nproperty float nz\nproperty ushort alpha\n" +
+		"element face 1\nproperty list uchar uint vertex_indices\nproperty list uchar float texcoord\n" +
+		"end_header\n")
+
+	positions := [][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 0, -1}}
+	for _, p := range positions {
+		binary.Write(&buf, order, p)
+		binary.Write(&buf, order, [3]float32{0, 1, 0})
+		binary.Write(&buf, order, uint16(65535))
+	}
+	buf.WriteByte(3)
+	binary.Write(&buf, order, [3]uint32{0, 1, 2})
+	buf.WriteByte(6)
+	binary.Write(&buf, order, [6]float32{0, 0, 1, 0, 0, 1})
+	return buf.Bytes()
+}
+
+func TestDecodePLY(t *testing.T) {
+	dec, err := DecodePLY(strings.NewReader(asciiPLY), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dec.Vertices) != 12 || len(dec.UVs) != 8 || len(dec.Objects[0].Faces) != 1 {
+		t.Fatalf("got %d vertices, %d uvs, %d faces", len(dec.Vertices)/3, len(dec.UVs)/2, len(dec.Objects[0].Faces))
+	}
+	if got := dec.Colors[4:8]; got[0] != 0 || got[1] != 1 || got[2] != 0 || got[3] != 1 {
+		t.Errorf("got second color %v", got)
+	}
+	if got := dec.Properties["vertex.confidence"]; len(got) != 4 || got[1] != 0.25 {
+		t.Errorf("got confidence %v", got)
+	}
+	if got := dec.Properties["face.flags"]; len(got) != 1 || got[0] != 7 {
+		t.Errorf("got flags %v", got)
+	}
+	if !slices.Equal(dec.Indices, []uint32{0, 1, 2, 0, 2, 3}) {
+		t.Errorf("got indices %v", dec.Indices)
+	}
+
+	// Normals are generated, smoothed across the shared vertices.
+	face := dec.Objects[0].Faces[0]
+	if face.UVs[2] != 2 || face.Normals[0] == math.MaxUint32 {
+		t.Errorf("got face %+v", face)
+	}
+	mesh, err := dec.BuildMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh.VertexCount() != 4 || len(mesh.Indices) != 6 {
+		t.Errorf("got %d mesh vertices, %d indices", mesh.VertexCount(), len(mesh.Indices))
+	}
+
+	for _, tc := range []struct {
+		order  binary.ByteOrder
+		format string
+	}{
+		{binary.LittleEndian, "binary_little_endian"},
+		{binary.BigEndian, "binary_big_endian"},
+	} {
+		dec, err := DecodePLY(bytes.NewReader(binaryPLY(tc.order, tc.format)), DecodeOptions{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if dec.Vertices[8] != -1 || dec.Normals[1] != 1 || dec.Colors[3] != 1 || dec.Colors[0] != 0 {
+			t.Errorf("%s: got vertices %v normals %v colors %v", tc.format, dec.Vertices, dec.Normals, dec.Colors)
+		}
+		face := dec.Objects[0].Faces[0]
+		if face.Normals[2] != 2 || face.UVs[1] != 1 || dec.UVs[2] != 1 {
+			t.Errorf("%s: got face %+v uvs %v", tc.format, face, dec.UVs)
+		}
+	}
+}
+
+func TestDecodePLYErrors(t *testing.T) {
+	header := "ply\nformat ascii 1.0\nelement vertex 2\nproperty float x\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	cases := map[string]string{
+		"magic":         "plx\n",
+		"format":        "ply\nformat binary 1.0\nend_header\n",
+		"property":      "ply\nformat ascii 1.0\nproperty float x\nend_header\n",
+		"no header end": "ply\nformat ascii 1.0\n",
+		"number":        header + "0\nx\n3 0 1 1\n",
+		"index":         header + "0\n1\n3 0 1 2\n",
+	}
+	for name, data := range cases {
+		if _, err := DecodePLY(strings.NewReader(data), DecodeOptions{Strict: true}); err == nil {
+			t.Errorf("%s: expected an error in strict mode", name)
+		}
+	}
+
+	// Lenient mode keeps the malformed vertex as zeros and drops the face.
+	dec, err := DecodePLY(strings.NewReader(header+"0\nx\n3 0 1 2\n"), DecodeOptions{ObjName: "bad.ply"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dec.Vertices) != 6 || len(dec.Objects[0].Faces) != 0 || len(dec.Warnings) != 2 {
+		t.Fatalf("got %d vertices, %d faces, warnings %v", len(dec.Vertices)/3, len(dec.Objects[0].Faces), dec.Warnings)
+	}
+	if got := dec.Warnings[0].Error(); got != `bad.ply:9:1: error: vertex: invalid number: "x"` {
+		t.Errorf("got %q", got)
+	}
+
+	truncated := binaryPLY(binary.LittleEndian, "binary_little_endian")
+	if _, err := DecodePLY(bytes.NewReader(truncated[:len(truncated)-4]), DecodeOptions{}); err == nil {
+		t.Error("truncated binary: expected an error")
+	}
+}
+
+const asciiSTL = `solid wedge
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 1 0
+    endloop
+  endfacet
+  facet normal 0 0 0
+    outer loop
+      vertex 1 0 0
+      vertex 1 1 0
+      vertex 0 1 0
+    endloop
+  endfacet
+endsolid wedge
+`
+
+func binarySTL(header string, triangles [][4][3]float32) []byte {
+	var buf bytes.Buffer
+	h := make([]byte, stlHeaderSize)
+	copy(h, header)
+	buf.Write(h)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(triangles)))
+	for _, tri := range triangles {
+		binary.Write(&buf, binary.LittleEndian, tri)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeSTL(t *testing.T) {
+	triangles := [][4][3]float32{
+		{{0, 0, 1}, {0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+	}
+	inputs := map[string][]byte{
+		"ascii": []byte(asciiSTL),
+		// A binary header starting with "solid" must not fool the reader.
+		"binary": binarySTL("solid exported by a CAD tool", triangles),
+	}
+
+	for name, data := range inputs {
+		dec, err := DecodeSTL(bytes.NewReader(data), DecodeOptions{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		faces := dec.Objects[0].Faces
+		if len(dec.Vertices) != 12 || len(faces) != 2 {
+			t.Fatalf("%s: got %d vertices, %d faces", name, len(dec.Vertices)/3, len(faces))
+		}
+		if !slices.Equal(dec.Indices, []uint32{0, 1, 2, 1, 3, 2}) {
+			t.Errorf("%s: got indices %v", name, dec.Indices)
+		}
+		if name == "ascii" && dec.Objects[0].Name != "wedge" {
+			t.Errorf("%s: got name %q", name, dec.Objects[0].Name)
+		}
+
+		// The second facet's zero normal is replaced by a generated one.
+		n := faces[1].Normals[0] * 3
+		if faces[1].Normals[0] == math.MaxUint32 || dec.Normals[n+2] != 1 {
+			t.Errorf("%s: got generated normal index %d in %v", name, faces[1].Normals[0], dec.Normals)
+		}
+	}
+
+	short := binarySTL("", triangles)
+	if _, err := DecodeSTL(bytes.NewReader(short[:len(short)-10]), DecodeOptions{Strict: true}); err == nil {
+		t.Error("truncated binary: expected an error")
+	}
+	if _, err := DecodeSTL(strings.NewReader("solid x\nvertex 0 0 0\nendsolid\n"), DecodeOptions{Strict: true}); err == nil {
+		t.Error("vertex outside facet: expected an error")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	stl := binarySTL("", [][4][3]float32{{}})
+	cases := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"scan.ply", []byte("ply\nformat ascii 1.0\n"), FormatPLY},
+		{"scan.dat", []byte("ply\r\nformat ascii 1.0\r\n"), FormatPLY},
+		{"part.stl", stl, FormatSTL},
+		{"part.bin", stl, FormatSTL},
+		{"part.txt", []byte(asciiSTL), FormatSTL},
+		{"CUBE.OBJ", []byte("garbage"), FormatOBJ},
+		{"model", []byte("# exported\n\nmtllib a.mtl\nv 0 0 0\n"), FormatOBJ},
+		{"notes.txt", []byte("hello"), FormatUnknown},
+	}
+	for _, tc := range cases {
+		if got := DetectFormat(tc.name, tc.data); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLoadAny(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"quad.ply":  []byte(asciiPLY),
+		"wedge.stl": []byte(asciiSTL),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objPath := copyModel(t, dir, "cube")
+
+	for _, path := range []string{filepath.Join(dir, "quad.ply"), filepath.Join(dir, "wedge.stl"), objPath} {
+		dec, err := LoadAny(path, DecodeOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if len(dec.Objects) == 0 || len(dec.Objects[0].Faces) == 0 {
+			t.Errorf("%s: no faces", path)
+		}
+	}
+}
+
+func FuzzDecodePLY(f *testing.F) {
+	f.Add([]byte(asciiPLY))
+	f.Add(binaryPLY(binary.LittleEndian, "binary_little_endian"))
+	f.Add(binaryPLY(binary.BigEndian, "binary_big_endian"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec, err := DecodePLY(bytes.NewReader(data), DecodeOptions{})
+		if err != nil {
+			return
+		}
+		if _, err := dec.BuildMesh(); err != nil {
+			t.Fatalf("decoded object does not build: %v", err)
+		}
+	})
+}
+
+func FuzzDecodeSTL(f *testing.F) {
+	f.Add([]byte(asciiSTL))
+	f.Add(binarySTL("", [][4][3]float32{{{0, 0, 1}, {0, 0, 0}, {1, 0, 0}, {0, 1, 0}}}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dec, err := DecodeSTL(bytes.NewReader(data), DecodeOptions{})
+		if err != nil {
+			return
+		}
+		if _, err := dec.BuildMesh(); err != nil {
+			t.Fatalf("decoded object does not build: %v", err)
+		}
+	})
+}
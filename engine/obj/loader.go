@@ -15,48 +15,107 @@ import (
 type Object struct {
 	Name      string
 	Faces     []Face
+	Group     bool // declared with "g" rather than "o"
 	materials []string
 }
 
 type DecodedObject struct {
-	Objects    []Object
-	Materials  map[string]*Material
-	Vertices   []float32
-	Indices    []uint32
-	Normals    []float32
-	UVs        []float32
+	Objects   []Object
+	Materials map[string]*Material
+	Vertices  []float32
+	Indices   []uint32
+	Normals   []float32
+	UVs       []float32
+	Warnings  []*ParseError
+
+	// Colors holds RGBA per vertex for formats that carry vertex colors,
+	// such as PLY; it is empty otherwise. Properties keeps any other
+	// per-element values those formats define, keyed "element.property",
+	// one value per element in file order.
+	Colors     []float32
+	Properties map[string][]float64
+
 	line       uint
+	file       string
+	stmt       string
+	cols       []int
+	strict     bool
 	objCur     *Object
 	matCur     *Material
 	mtlDir     string
-	matLib     string
-	smoothCurr bool
+	matLibs    []matLib
+	smoothCurr int
+
+	normalsGenerated bool
+	parsedNormals    int
 
 	VAO uint32
 	VBO uint32
 	EBO uint32
 	UBO uint32
-	
+
 	Texture uint32
 }
 
+type matLib struct {
+	name   string
+	line   uint
+	column int
+}
+
 type Face struct {
 	Vertices []int
 	Normals  []int
 	UVs      []int
 	Material string
+	Smooth   int // smoothing group, 0 when "s off"
 }
 
 type Material struct {
-	Name     string
-	Opacity  float32
-	Metallic float32
-	Ambient  mgl32.Vec3
-	Diffuse  mgl32.Vec3
-	Specular mgl32.Vec3
-	Emission mgl32.Vec3
+	Name           string
+	Opacity        float32
+	Metallic       float32
+	Roughness      float32
+	Shininess      float32
+	OpticalDensity float32
+	Illumination   int
+	Ambient        mgl32.Vec3
+	Diffuse        mgl32.Vec3
+	Specular       mgl32.Vec3
+	Emission       mgl32.Vec3
+	Transmission   mgl32.Vec3
+
+	// HasAmbient is set when Ambient was given, so that an explicit
+	// "Ka 0 0 0" can be told apart from no Ka at all.
+	HasAmbient bool
 
 	Texture string
+
+	AmbientMap      *TextureMap
+	DiffuseMap      *TextureMap
+	SpecularMap     *TextureMap
+	ShininessMap    *TextureMap
+	DissolveMap     *TextureMap
+	BumpMap         *TextureMap
+	NormalMap       *TextureMap
+	DisplacementMap *TextureMap
+	ReflectionMap   *TextureMap
+	EmissiveMap     *TextureMap
+
+	// Only filled by importers of PBR formats such as glTF, which pack
+	// metalness and roughness into one texture.
+	MetallicRoughnessMap *TextureMap
+	OcclusionMap         *TextureMap
+
+	// placeholder marks materials only known from usemtl, which have no
+	// definition to write back out.
+	placeholder bool
+}
+
+// Defined reports whether the material was declared with newmtl (or built
+// in code) rather than only named by a usemtl statement.
+func (mat *Material) Defined() bool {
+	return !mat.placeholder
 }
 
 func LoadModel(objPath, mtlPath string) (*DecodedObject, error) {
@@ -72,40 +131,56 @@ func LoadModel(objPath, mtlPath string) (*DecodedObject, error) {
 	}
 	defer mtlFile.Close()
 
-	decObj, err := DecodeObject(objFile, mtlFile)
-	if err != nil {
-		return nil, err
-	}
-
-	decObj.mtlDir = filepath.Dir(objPath)
-	return decObj, nil
+	opts := DecodeOptions{ObjName: objPath, MtlName: mtlPath}
+	return decodeObject(objFile, mtlFile, filepath.Dir(mtlPath), opts)
 }
 
 func DecodeObject(objReader, mtlReader io.Reader) (*DecodedObject, error) {
-	dec := new(DecodedObject)
-	dec.Objects = make([]Object, 0)
-	dec.Materials = make(map[string]*Material)
-	dec.Vertices = make([]float32, 0)
-	dec.Normals = make([]float32, 0)
-	dec.UVs = make([]float32, 0)
-	dec.line = 1
+	return decodeObject(objReader, mtlReader, "", DecodeOptions{})
+}
 
-	err := dec.parse(objReader, dec.parseObjLine)
+func DecodeObjectWithOptions(objReader, mtlReader io.Reader, opts DecodeOptions) (*DecodedObject, error) {
+	return decodeObject(objReader, mtlReader, "", opts)
+}
+
+// decodeObject resolves texture paths found in the MTL relative to mtlDir,
+// leaving them untouched when mtlDir is empty.
+func decodeObject(objReader, mtlReader io.Reader, mtlDir string, opts DecodeOptions) (*DecodedObject, error) {
+	dec := newDecodedObject(opts)
+
+	err := dec.parse(objReader, opts.ObjName, dec.parseObjLine)
 	if err != nil {
 		return nil, err
 	}
+	dec.GenerateNormals(0)
 
 	dec.matCur = nil
-	dec.line = 1
-	err = dec.parse(mtlReader, dec.parseMtlLine)
+	dec.mtlDir = mtlDir
+	err = dec.parse(mtlReader, opts.MtlName, dec.parseMtlLine)
 	if err != nil {
+		return nil, err
 	}
 
 	return dec, nil
 }
 
-func (dec *DecodedObject) parse(reader io.Reader, parseLine func(string) error) error {
+func newDecodedObject(opts DecodeOptions) *DecodedObject {
+	dec := new(DecodedObject)
+	dec.Objects = make([]Object, 0)
+	dec.Materials = make(map[string]*Material)
+	dec.Vertices = make([]float32, 0)
+	dec.Normals = make([]float32, 0)
+	dec.UVs = make([]float32, 0)
+	dec.Warnings = make([]*ParseError, 0)
+	dec.matLibs = make([]matLib, 0)
+	dec.strict = opts.Strict
+	dec.line = 1
+	return dec
+}
+
+func (dec *DecodedObject) parse(reader io.Reader, file string, parseLine func(string) error) error {
 	buf := bufio.NewReader(reader)
+	dec.file = file
 	dec.line = 1
 	for {
 		line, err := buf.ReadString('\n')
@@ -113,10 +188,12 @@ func (dec *DecodedObject) parse(reader io.Reader, parseLine func(string) error)
 			return err
 		}
 
-		line = strings.Trim(line, "\r\n\t ")
+		line = strings.TrimRight(line, "\r\n\t ")
 		perr := parseLine(line)
 		if perr != nil {
-			return perr
+			if rerr := dec.report(perr); rerr != nil {
+				return rerr
+			}
 		}
 
 		if err == io.EOF {
@@ -124,96 +201,113 @@ func (dec *DecodedObject) parse(reader io.Reader, parseLine func(string) error)
 		}
 		dec.line++
 	}
+	dec.stmt = ""
+	dec.cols = nil
 	return nil
 }
 
-func (dec *DecodedObject) parseObjLine(line string) error {
-	fields := strings.Fields(line)
-	if len(fields) == 0 {
-		return nil
-	}
+// splitStatement breaks a line into its keyword and arguments, remembering
+// where each argument starts for error columns. ok is false for blank lines
+// and comments.
+func (dec *DecodedObject) splitStatement(line string) (string, []string, bool) {
+	fields, offsets := fieldsWithOffsets(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return "", nil, false
+	}
+	dec.stmt = fields[0]
+	dec.cols = offsets[1:]
+	return fields[0], fields[1:], true
+}
 
-	lType := fields[0]
-	if strings.HasPrefix(lType, "#") {
+func (dec *DecodedObject) parseObjLine(line string) error {
+	lType, args, ok := dec.splitStatement(line)
+	if !ok {
 		return nil
 	}
 
 	switch lType {
 	case "mtllib":
-		return dec.parseMatlib(fields[1:])
+		return dec.parseMatlib(args)
 	case "g":
-		return dec.parseObject(fields[1:])
+		if err := dec.parseObject(args); err != nil {
+			return err
+		}
+		dec.objCur.Group = true
+		return nil
 	case "o":
-		return dec.parseObject(fields[1:])
+		return dec.parseObject(args)
 	case "v":
-		return dec.parseVertex(fields[1:])
+		return dec.parseVertex(args)
 	case "vn":
-		return dec.parseNormal(fields[1:])
+		return dec.parseNormal(args)
 	case "vt":
-		return dec.parseTex(fields[1:])
+		return dec.parseTex(args)
 	case "f":
-		return dec.parseFace(fields[1:])
+		return dec.parseFace(args)
 	case "usemtl":
-		return dec.parseUsemtl(fields[1:])
+		return dec.parseUsemtl(args)
 	case "s":
-		return dec.parseSmooth(fields[1:])
+		return dec.parseSmooth(args)
 	default:
-		fmt.Println("field not supported: " + lType + " OBJ")
+		return dec.warnf(-1, "statement not supported")
 	}
-	return nil
 }
 
-func (dec *DecodedObject) parseVertex(i []string) error {
-	if len(i) < 3 {
-		fmt.Println("Less than 3 vertices in line")
-	}
-
-	for _, f := range i[:3] {
-		val, err := strconv.ParseFloat(f, 32)
+// parseFloats reads want values from i. Missing or malformed values are
+// left as zero so callers can still append a placeholder and keep the
+// numbering of later elements intact.
+func (dec *DecodedObject) parseFloats(i []string, want int) ([]float32, error) {
+	vals := make([]float32, want)
+	for pos := 0; pos < want && pos < len(i); pos++ {
+		val, err := strconv.ParseFloat(i[pos], 32)
 		if err != nil {
-			return err
+			return vals, dec.errorf(pos, "invalid number: %q", i[pos])
 		}
-		dec.Vertices = append(dec.Vertices, float32(val))
+		vals[pos] = float32(val)
 	}
-	return nil
+	if len(i) < want {
+		return vals, dec.warnf(-1, "expected %d values, got %d", want, len(i))
+	}
+	return vals, nil
 }
 
-func (dec *DecodedObject) parseNormal(i []string) error {
-	if len(i) < 3 {
-		fmt.Println("Less than 3 normals in line")
-	}
+func (dec *DecodedObject) parseVertex(i []string) error {
+	vals, err := dec.parseFloats(i, 3)
+	dec.Vertices = append(dec.Vertices, vals...)
+	return err
+}
 
-	for _, f := range i[:3] {
-		val, err := strconv.ParseFloat(f, 32)
-		if err != nil {
-			return err
-		}
-		dec.Normals = append(dec.Normals, float32(val))
-	}
-	return nil
+func (dec *DecodedObject) parseNormal(i []string) error {
+	vals, err := dec.parseFloats(i, 3)
+	dec.Normals = append(dec.Normals, vals...)
+	return err
 }
 
 func (dec *DecodedObject) parseTex(i []string) error {
-	if len(i) < 2 {
-		fmt.Println("Less than 2 UVs in line")
+	// "vt u" is valid on its own, v defaults to 0.
+	want := 2
+	if len(i) == 1 {
+		want = 1
 	}
-
-	for _, f := range i[:2] {
-		val, err := strconv.ParseFloat(f, 32)
-		if err != nil {
-			return err
-		}
-		dec.UVs = append(dec.UVs, float32(val))
+	vals, err := dec.parseFloats(i, want)
+	if want == 1 {
+		vals = append(vals, 0)
 	}
-	return nil
+	dec.UVs = append(dec.UVs, vals...)
+	return err
 }
 
 func (dec *DecodedObject) parseObject(i []string) error {
+	name := fmt.Sprintf("unnamed%d", dec.line)
 	if len(i) < 1 {
-		fmt.Println("Object line with no fields")
+		if err := dec.warnf(-1, "no name given"); err != nil {
+			return err
+		}
+	} else {
+		name = i[0]
 	}
 
-	dec.Objects = append(dec.Objects, makeObject(i[0]))
+	dec.Objects = append(dec.Objects, makeObject(name))
 	dec.objCur = &dec.Objects[len(dec.Objects)-1]
 	return nil
 }
@@ -226,20 +320,43 @@ func makeObject(name string) Object {
 	return o
 }
 
-func (dec *DecodedObject) parseFace(i []string) error {
+func (dec *DecodedObject) ensureObject() {
 	if dec.objCur == nil {
-		err := dec.parseObject([]string{fmt.Sprintf("unnamed%d", dec.line)})
-		if err != nil {
-			return err
-		}
+		dec.Objects = append(dec.Objects, makeObject(fmt.Sprintf("unnamed%d", dec.line)))
+		dec.objCur = &dec.Objects[len(dec.Objects)-1]
+	}
+}
+
+// faceIndex turns a 1-based or negative relative OBJ index into a 0-based
+// index, checking it against the count elements defined so far.
+func (dec *DecodedObject) faceIndex(field int, kind, s string, count int) (int, error) {
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, dec.errorf(field, "invalid %s index: %q", kind, s)
+	}
+	if val == 0 {
+		return 0, dec.errorf(field, "%s index cannot be 0", kind)
 	}
 
+	index := val - 1
+	if val < 0 {
+		index = count + val
+	}
+	if index < 0 || index >= count {
+		return 0, dec.errorf(field, "%s index %d out of range (%d defined)", kind, val, count)
+	}
+	return index, nil
+}
+
+func (dec *DecodedObject) parseFace(i []string) error {
+	dec.ensureObject()
+
 	if len(dec.objCur.materials) == 0 && dec.matCur != nil {
 		dec.objCur.materials = append(dec.objCur.materials, dec.matCur.Name)
 	}
 
 	if len(i) < 3 {
-		return fmt.Errorf("less than 3 vertices in face definition at line %d", dec.line)
+		return dec.errorf(-1, "less than 3 vertices in face definition")
 	}
 
 	var face Face
@@ -250,107 +367,95 @@ func (dec *DecodedObject) parseFace(i []string) error {
 	if dec.matCur != nil {
 		face.Material = dec.matCur.Name
 	}
+	face.Smooth = dec.smoothCurr
 
 	for pos, f := range i {
 		vFields := strings.Split(f, "/")
-		if len(vFields) < 1 {
-			return fmt.Errorf("face field with no parts at line %d", dec.line)
+		if len(vFields) > 3 {
+			return dec.errorf(pos, "too many parts in face vertex: %q", f)
 		}
 
-		val, err := strconv.Atoi(vFields[0])
+		index, err := dec.faceIndex(pos, "vertex", vFields[0], len(dec.Vertices)/3)
 		if err != nil {
-			return fmt.Errorf("invalid vertex index: %s at line %d", vFields[0], dec.line)
-		}
-		if val > 0 {
-			face.Vertices[pos] = val - 1
-		} else if val < 0 {
-			cur := (len(dec.Vertices) / 3) - 1
-			face.Vertices[pos] = cur + val + 1
-		} else {
-			return fmt.Errorf("vertex index cannot be 0 at line %d", dec.line)
+			return err
 		}
+		face.Vertices[pos] = index
 
+		face.UVs[pos] = math.MaxUint32
 		if len(vFields) > 1 && vFields[1] != "" {
-			val, err := strconv.Atoi(vFields[1])
+			index, err := dec.faceIndex(pos, "UV", vFields[1], len(dec.UVs)/2)
 			if err != nil {
-				return fmt.Errorf("invalid UV index: %s at line %d", vFields[1], dec.line)
+				return err
 			}
-			if val > 0 {
-				face.UVs[pos] = val - 1
-			} else if val < 0 {
-				curr := (len(dec.UVs) / 2) - 1
-				face.UVs[pos] = curr + val + 1
-			} else {
-				return fmt.Errorf("UV index cannot be 0 at line %d", dec.line)
-			}
-		} else {
-			face.UVs[pos] = math.MaxUint32
+			face.UVs[pos] = index
 		}
 
+		face.Normals[pos] = math.MaxUint32
 		if len(vFields) > 2 && vFields[2] != "" {
-			val, err := strconv.Atoi(vFields[2])
+			index, err := dec.faceIndex(pos, "normal", vFields[2], len(dec.Normals)/3)
 			if err != nil {
-				return fmt.Errorf("invalid normal index: %s at line %d", vFields[2], dec.line)
-			}
-			if len(dec.Normals) > 0 {
-				if val > 0 {
-					face.Normals[pos] = val - 1
-				} else if val < 0 {
-					curr := (len(dec.Normals) / 3) - 1
-					face.Normals[pos] = curr + val + 1
-				} else {
-					return fmt.Errorf("normal index cannot be 0 at line %d", dec.line)
-				}
+				return err
 			}
-		} else {
-			face.Normals[pos] = math.MaxUint32
+			face.Normals[pos] = index
 		}
 	}
 
-	if len(face.Vertices) >= 3 {
-		for j := 1; j < len(face.Vertices)-1; j++ {
-			dec.Indices = append(dec.Indices, uint32(face.Vertices[0]), uint32(face.Vertices[j]), uint32(face.Vertices[j+1]))
-		}
-	}
+	dec.appendFace(face)
+	return nil
+}
 
+// appendFace adds face to the current object and its fan triangulation to
+// Indices.
+func (dec *DecodedObject) appendFace(face Face) {
+	for j := 1; j < len(face.Vertices)-1; j++ {
+		dec.Indices = append(dec.Indices, uint32(face.Vertices[0]), uint32(face.Vertices[j]), uint32(face.Vertices[j+1]))
+	}
 	dec.objCur.Faces = append(dec.objCur.Faces, face)
-	return nil
 }
 
 func (dec *DecodedObject) parseMatlib(i []string) error {
 	if len(i) < 1 {
-		fmt.Println("Material library line with no fields")
+		return dec.warnf(-1, "no material library given")
+	}
+	for pos, name := range i {
+		dec.matLibs = append(dec.matLibs, matLib{name, dec.line, dec.cols[pos] + 1})
 	}
-	dec.matLib = i[0]
 	return nil
 }
 
 func (dec *DecodedObject) parseSmooth(i []string) error {
 	if len(i) < 1 {
-		fmt.Println("Smooth line with no fields")
+		return dec.warnf(-1, "no smoothing group given")
 	}
 	if i[0] == "0" || i[0] == "off" {
-		dec.smoothCurr = false
+		dec.smoothCurr = 0
+		return nil
+	}
+	if i[0] == "on" {
+		dec.smoothCurr = 1
 		return nil
 	}
-	dec.smoothCurr = true
+	val, err := strconv.Atoi(i[0])
+	if err != nil {
+		return dec.errorf(0, "invalid smoothing group: %q", i[0])
+	}
+	dec.smoothCurr = val
 	return nil
 }
 
 func (dec *DecodedObject) parseUsemtl(i []string) error {
 	if len(i) < 1 {
-		fmt.Println("Usemtl line with no fields")
+		return dec.warnf(-1, "no material name given")
 	}
 
-	if dec.objCur == nil {
-		dec.parseObject([]string{fmt.Sprintf("unnamed%d", dec.line)})
-	}
+	dec.ensureObject()
 
 	name := i[0]
 	mat := dec.Materials[name]
 	if mat == nil {
 		mat = new(Material)
 		mat.Name = name
+		mat.placeholder = true
 		dec.Materials[name] = mat
 	}
 	dec.objCur.materials = append(dec.objCur.materials, name)
@@ -361,36 +466,56 @@ func (dec *DecodedObject) parseUsemtl(i []string) error {
 // MTL PARSE
 
 func (dec *DecodedObject) parseMtlLine(l string) error {
-	fields := strings.Fields(l)
-	if len(fields) == 0 {
+	lType, args, ok := dec.splitStatement(l)
+	if !ok {
 		return nil
 	}
 
-	lType := fields[0]
-	if strings.HasPrefix(lType, "#") {
-		return nil
+	if lType != "newmtl" && dec.matCur == nil {
+		return dec.errorf(-1, "statement before any newmtl")
 	}
 
 	switch lType {
 	case "newmtl":
-		return dec.parseNewmtl(fields[1:])
+		return dec.parseNewmtl(args)
 	case "d":
-		return dec.parseDissolve(fields[1:])
+		return dec.parseDissolve(args)
 	case "Ka":
-		return dec.parseKa(fields[1:])
+		if err := dec.parseColor(args, &dec.matCur.Ambient); err != nil {
+			return err
+		}
+		dec.matCur.HasAmbient = true
+		return nil
+	case "Kd":
+		return dec.parseColor(args, &dec.matCur.Diffuse)
 	case "Ke":
-		return dec.parseKe(fields[1:])
+		return dec.parseColor(args, &dec.matCur.Emission)
 	case "Ks":
-		return dec.parseKs(fields[1:])
+		return dec.parseColor(args, &dec.matCur.Specular)
+	case "Ns":
+		return dec.parseScalar(args, &dec.matCur.Shininess)
+	case "Ni":
+		return dec.parseScalar(args, &dec.matCur.OpticalDensity)
+	case "Pm":
+		return dec.parseScalar(args, &dec.matCur.Metallic)
+	case "Pr":
+		return dec.parseScalar(args, &dec.matCur.Roughness)
+	case "illum":
+		return dec.parseIllum(args)
+	case "Tf":
+		return dec.parseTf(args)
+	case "map_Ka", "map_Kd", "map_Ks", "map_Ke", "map_Ns", "map_d",
+		"bump", "map_Bump", "map_bump", "norm", "disp", "refl":
+		return dec.parseMap(l)
 	default:
-		fmt.Println("Field not supported: " + lType + " MTL")
+		return dec.warnf(-1, "statement not supported")
 	}
-	return nil
 }
 
 func (dec *DecodedObject) parseNewmtl(i []string) error {
 	if len(i) < 1 {
-		fmt.Println("Newmtl line with no fields")
+		dec.matCur = nil
+		return dec.errorf(-1, "no material name given")
 	}
 
 	name := i[0]
@@ -400,69 +525,82 @@ func (dec *DecodedObject) parseNewmtl(i []string) error {
 		mat.Name = name
 		dec.Materials[name] = mat
 	}
+	mat.placeholder = false
+	mat.Opacity = 1
+	mat.OpticalDensity = 1
+	mat.Transmission = mgl32.Vec3{1, 1, 1}
 	dec.matCur = mat
 	return nil
 }
 
 func (dec *DecodedObject) parseDissolve(i []string) error {
+	// "d -halo factor" is accepted but the halo flag is ignored.
+	if len(i) > 0 && i[0] == "-halo" {
+		i = i[1:]
+		dec.cols = dec.cols[1:]
+	}
+	return dec.parseScalar(i, &dec.matCur.Opacity)
+}
+
+func (dec *DecodedObject) parseScalar(i []string, dst *float32) error {
 	if len(i) < 1 {
-		fmt.Println("Dissolve line with no fields")
+		return dec.errorf(-1, "no value given")
 	}
 	val, err := strconv.ParseFloat(i[0], 32)
 	if err != nil {
-		fmt.Println("Dissolve float error")
+		return dec.errorf(0, "invalid number: %q", i[0])
 	}
-	dec.matCur.Opacity = float32(val)
+	*dst = float32(val)
 	return nil
 }
 
-func (dec *DecodedObject) parseKa(i []string) error {
-	if len(i) < 3 {
-		fmt.Println("Ka line with less than 3 fields")
+// parseColor accepts "r g b" or a single "r" that is applied to all three
+// channels.
+func (dec *DecodedObject) parseColor(i []string, dst *mgl32.Vec3) error {
+	if len(i) < 1 {
+		return dec.errorf(-1, "no color given")
+	}
+	if len(i) == 2 {
+		return dec.errorf(-1, "expected 1 or 3 values, got 2")
 	}
 
 	var color mgl32.Vec3
-	for pos, f := range i[:3] {
-		val, err := strconv.ParseFloat(f, 32)
+	for pos := range color {
+		field := 0
+		if len(i) >= 3 {
+			field = pos
+		}
+		val, err := strconv.ParseFloat(i[field], 32)
 		if err != nil {
-			return err
+			return dec.errorf(field, "invalid number: %q", i[field])
 		}
 		color[pos] = float32(val)
 	}
-	dec.matCur.Ambient = color
+	*dst = color
 	return nil
 }
 
-func (dec *DecodedObject) parseKe(fields []string) error {
-
-	if len(fields) < 3 {
-		fmt.Println("'Ke' with less than 3 fields")
+func (dec *DecodedObject) parseIllum(fields []string) error {
+	if len(fields) < 1 {
+		return dec.errorf(-1, "no illumination model given")
 	}
-	var colors [3]float32
-	for pos, f := range fields[:3] {
-		val, err := strconv.ParseFloat(f, 32)
-		if err != nil {
-			return err
-		}
-		colors[pos] = float32(val)
+	val, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return dec.errorf(0, "invalid illumination model: %q", fields[0])
 	}
-	dec.matCur.Emission = colors
+	dec.matCur.Illumination = val
 	return nil
 }
 
-func (dec *DecodedObject) parseKs(fields []string) error {
-
-	if len(fields) < 3 {
-		fmt.Println("'Ks' with less than 3 fields")
+// parseTf accepts "Tf r [g b]" and "Tf xyz x [y z]"; a single component is
+// applied to all three channels. Spectral curves are not supported.
+func (dec *DecodedObject) parseTf(fields []string) error {
+	if len(fields) > 0 && fields[0] == "spectral" {
+		return dec.warnf(0, "spectral transmission filter not supported")
 	}
-	var colors [3]float32
-	for pos, f := range fields[:3] {
-		val, err := strconv.ParseFloat(f, 32)
-		if err != nil {
-			return err
-		}
-		colors[pos] = float32(val)
+	if len(fields) > 0 && fields[0] == "xyz" {
+		fields = fields[1:]
+		dec.cols = dec.cols[1:]
 	}
-	dec.matCur.Specular = colors
-	return nil
+	return dec.parseColor(fields, &dec.matCur.Transmission)
 }
@@ -0,0 +1,162 @@
+package obj
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// Layout of a single vertex in Mesh.Vertices, in floats.
+const (
+	PositionOffset = 0
+	UVOffset       = 3
+	NormalOffset   = 5
+	TangentOffset  = 8
+	VertexStride   = 12
+)
+
+// Mesh is a de-indexed copy of a DecodedObject: every unique (v, vt, vn)
+// tuple referenced by a face becomes one interleaved vertex. Tuples used by
+// faces with mirrored UVs are kept apart so each vertex has one handedness.
+// Indices are grouped by material, one Submesh per material.
+type Mesh struct {
+	Vertices  []float32
+	Indices   []uint32
+	Submeshes []Submesh
+	Materials map[string]*Material
+	Min       mgl32.Vec3
+	Max       mgl32.Vec3
+}
+
+// Submesh is a range of Mesh.Indices drawn with one material. Material is
+// empty for faces that came before any usemtl.
+type Submesh struct {
+	Material string
+	Start    int
+	Count    int
+}
+
+type vertexKey struct {
+	v, vt, vn int
+	mirrored  bool
+}
+
+func (m *Mesh) VertexCount() int {
+	return len(m.Vertices) / VertexStride
+}
+
+func (dec *DecodedObject) BuildMesh() (*Mesh, error) {
+	return dec.buildMesh(dec.Objects)
+}
+
+// BuildObjectMesh builds a mesh from the faces of Objects[i] alone, for
+// drawing the objects and groups of a file as separate parts.
+func (dec *DecodedObject) BuildObjectMesh(i int) (*Mesh, error) {
+	if i < 0 || i >= len(dec.Objects) {
+		return nil, fmt.Errorf("object index %d out of range", i)
+	}
+	return dec.buildMesh(dec.Objects[i : i+1])
+}
+
+func (dec *DecodedObject) buildMesh(objects []Object) (*Mesh, error) {
+	mesh := &Mesh{
+		Vertices:  make([]float32, 0),
+		Indices:   make([]uint32, 0),
+		Submeshes: make([]Submesh, 0),
+		Materials: dec.Materials,
+	}
+	lookup := make(map[vertexKey]uint32)
+	groups := make(map[string][]uint32)
+	order := make([]string, 0)
+
+	for _, object := range objects {
+		for _, face := range object.Faces {
+			if len(face.Vertices) < 3 {
+				continue
+			}
+
+			mirrored := dec.uvMirrored(&face)
+			corners := make([]uint32, len(face.Vertices))
+			for pos := range face.Vertices {
+				key := vertexKey{face.Vertices[pos], face.UVs[pos], face.Normals[pos], mirrored}
+				index, ok := lookup[key]
+				if !ok {
+					var err error
+					index, err = dec.appendVertex(mesh, key)
+					if err != nil {
+						return nil, err
+					}
+					lookup[key] = index
+				}
+				corners[pos] = index
+			}
+
+			group, ok := groups[face.Material]
+			if !ok {
+				order = append(order, face.Material)
+			}
+			for j := 1; j < len(corners)-1; j++ {
+				group = append(group, corners[0], corners[j], corners[j+1])
+			}
+			groups[face.Material] = group
+		}
+	}
+
+	for _, material := range order {
+		mesh.Submeshes = append(mesh.Submeshes, Submesh{
+			Material: material,
+			Start:    len(mesh.Indices),
+			Count:    len(groups[material]),
+		})
+		mesh.Indices = append(mesh.Indices, groups[material]...)
+	}
+
+	mesh.GenerateTangents()
+	mesh.ComputeBounds()
+	return mesh, nil
+}
+
+func (dec *DecodedObject) appendVertex(mesh *Mesh, key vertexKey) (uint32, error) {
+	if key.v < 0 || key.v*3+2 >= len(dec.Vertices) {
+		return 0, fmt.Errorf("vertex index %d out of range", key.v+1)
+	}
+
+	var vertex [VertexStride]float32
+	copy(vertex[PositionOffset:], dec.Vertices[key.v*3:key.v*3+3])
+
+	if key.vt != math.MaxUint32 {
+		if key.vt < 0 || key.vt*2+1 >= len(dec.UVs) {
+			return 0, fmt.Errorf("UV index %d out of range", key.vt+1)
+		}
+		copy(vertex[UVOffset:], dec.UVs[key.vt*2:key.vt*2+2])
+	}
+
+	if key.vn != math.MaxUint32 {
+		if key.vn < 0 || key.vn*3+2 >= len(dec.Normals) {
+			return 0, fmt.Errorf("normal index %d out of range", key.vn+1)
+		}
+		copy(vertex[NormalOffset:], dec.Normals[key.vn*3:key.vn*3+3])
+	}
+
+	index := uint32(mesh.VertexCount())
+	mesh.Vertices = append(mesh.Vertices, vertex[:]...)
+	return index, nil
+}
+
+// ComputeBounds updates Min and Max from the vertex positions.
+func (m *Mesh) ComputeBounds() {
+	if m.VertexCount() == 0 {
+		m.Min, m.Max = mgl32.Vec3{}, mgl32.Vec3{}
+		return
+	}
+
+	m.Min = m.vec3(0, PositionOffset)
+	m.Max = m.Min
+	for v := 1; v < m.VertexCount(); v++ {
+		p := m.vec3(uint32(v), PositionOffset)
+		for axis := 0; axis < 3; axis++ {
+			m.Min[axis] = float32(math.Min(float64(m.Min[axis]), float64(p[axis])))
+			m.Max[axis] = float32(math.Max(float64(m.Max[axis]), float64(p[axis])))
+		}
+	}
+}
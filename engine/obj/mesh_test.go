@@ -0,0 +1,140 @@
+package obj
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// corner is one face corner with its attributes looked up, so triangles
+// can be compared without depending on vertex or index order.
+type corner [8]float32
+
+func sourceCorner(t *testing.T, dec *DecodedObject, face Face, pos int) corner {
+	t.Helper()
+	var c corner
+	v, vt, vn := face.Vertices[pos], face.UVs[pos], face.Normals[pos]
+	copy(c[0:3], dec.Vertices[v*3:v*3+3])
+	if vt != math.MaxUint32 {
+		copy(c[3:5], dec.UVs[vt*2:vt*2+2])
+	}
+	if vn != math.MaxUint32 {
+		copy(c[5:8], dec.Normals[vn*3:vn*3+3])
+	}
+	return c
+}
+
+func meshCorner(m *Mesh, index uint32) corner {
+	var c corner
+	vertex := m.Vertices[int(index)*VertexStride:]
+	copy(c[0:3], vertex[PositionOffset:PositionOffset+3])
+	copy(c[3:5], vertex[UVOffset:UVOffset+2])
+	copy(c[5:8], vertex[NormalOffset:NormalOffset+3])
+	return c
+}
+
+func sortedTriangles(tris [][3]corner) []string {
+	keys := make([]string, len(tris))
+	for i, tri := range tris {
+		keys[i] = fmt.Sprint(tri)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestBuildMeshModels(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		vertices, indices int
+	}{
+		{"cube", 24, 36},
+		{"plane", 4, 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, err := LoadModel("../res/models/"+tc.name+".obj", "../res/models/"+tc.name+".mtl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			mesh, err := dec.BuildMesh()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mesh.VertexCount() != tc.vertices || len(mesh.Indices) != tc.indices {
+				t.Fatalf("got %d vertices, %d indices, want %d, %d", mesh.VertexCount(), len(mesh.Indices), tc.vertices, tc.indices)
+			}
+			if len(mesh.Vertices) != mesh.VertexCount()*VertexStride {
+				t.Errorf("vertex buffer holds %d floats", len(mesh.Vertices))
+			}
+
+			// Every face, fanned into triangles, must come out with the
+			// same positions, UVs and normals.
+			var want, got [][3]corner
+			for _, o := range dec.Objects {
+				for _, face := range o.Faces {
+					for j := 1; j < len(face.Vertices)-1; j++ {
+						want = append(want, [3]corner{
+							sourceCorner(t, dec, face, 0), sourceCorner(t, dec, face, j), sourceCorner(t, dec, face, j+1),
+						})
+					}
+				}
+			}
+			for i := 0; i+2 < len(mesh.Indices); i += 3 {
+				got = append(got, [3]corner{
+					meshCorner(mesh, mesh.Indices[i]), meshCorner(mesh, mesh.Indices[i+1]), meshCorner(mesh, mesh.Indices[i+2]),
+				})
+			}
+			if !slices.Equal(sortedTriangles(got), sortedTriangles(want)) {
+				t.Error("triangles do not match the faces")
+			}
+		})
+	}
+}
+
+func TestBuildMeshDeduplicates(t *testing.T) {
+	dec := &DecodedObject{
+		Vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+		Objects: []Object{{Faces: []Face{
+			{Vertices: []int{0, 1, 2}, UVs: []int{math.MaxUint32, math.MaxUint32, math.MaxUint32}, Normals: []int{math.MaxUint32, math.MaxUint32, math.MaxUint32}},
+			{Vertices: []int{0, 2, 3}, UVs: []int{math.MaxUint32, math.MaxUint32, math.MaxUint32}, Normals: []int{math.MaxUint32, math.MaxUint32, math.MaxUint32}},
+		}}},
+	}
+	mesh, err := dec.BuildMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh.VertexCount() != 4 || !slices.Equal(mesh.Indices, []uint32{0, 1, 2, 0, 2, 3}) {
+		t.Errorf("got %d vertices, indices %v", mesh.VertexCount(), mesh.Indices)
+	}
+
+	dec.Objects[0].Faces[1].Vertices[2] = 9
+	if _, err := dec.BuildMesh(); err == nil {
+		t.Error("out of range vertex index accepted")
+	}
+}
+
+func TestBuildObjectMesh(t *testing.T) {
+	src := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\n" +
+		"o first\nf 1 2 3\ng second\nf 1 3 4\nf 1 2 4\n"
+	dec, err := DecodeObject(strings.NewReader(src), strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dec.Objects) != 2 {
+		t.Fatalf("got %d objects, want 2", len(dec.Objects))
+	}
+
+	for i, want := range []int{3, 6} {
+		mesh, err := dec.BuildObjectMesh(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mesh.Indices) != want {
+			t.Errorf("object %d: got %d indices, want %d", i, len(mesh.Indices), want)
+		}
+	}
+	if _, err := dec.BuildObjectMesh(2); err == nil {
+		t.Error("expected an error for an out of range object")
+	}
+}
@@ -0,0 +1,93 @@
+package obj
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MissingLibraryError is returned by LoadObject alongside a usable
+// DecodedObject when one or more mtllib files could not be found. It is a
+// warning: faces referencing materials from those libraries keep their
+// material names but the materials carry no parameters.
+type MissingLibraryError struct {
+	Paths []string
+}
+
+func (e *MissingLibraryError) Error() string {
+	return "missing material libraries: " + strings.Join(e.Paths, ", ")
+}
+
+// LoadObject decodes the OBJ at objPath and every material library it
+// declares with mtllib, resolved relative to the OBJ file.
+func LoadObject(objPath string) (*DecodedObject, error) {
+	return LoadObjectWithOptions(objPath, DecodeOptions{})
+}
+
+func LoadObjectWithOptions(objPath string, opts DecodeOptions) (*DecodedObject, error) {
+	objFile, err := os.Open(objPath)
+	if err != nil {
+		return nil, err
+	}
+	defer objFile.Close()
+
+	dec := newDecodedObject(opts)
+	err = dec.parse(objFile, objPath, dec.parseObjLine)
+	if err != nil {
+		return nil, err
+	}
+	dec.GenerateNormals(0)
+
+	var missing []string
+	for _, lib := range dec.matLibs {
+		path := filepath.Join(filepath.Dir(objPath), filepath.FromSlash(lib.name))
+		err := dec.loadMaterialLibrary(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			perr := &ParseError{
+				File:      objPath,
+				Line:      int(lib.line),
+				Column:    lib.column,
+				Statement: "mtllib",
+				Severity:  SeverityWarning,
+				Err:       err,
+			}
+			if dec.strict {
+				return nil, perr
+			}
+			dec.Warnings = append(dec.Warnings, perr)
+			missing = append(missing, path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return dec, &MissingLibraryError{Paths: missing}
+	}
+	return dec, nil
+}
+
+// MaterialLibraries returns the mtllib entries in the order they appeared.
+func (dec *DecodedObject) MaterialLibraries() []string {
+	names := make([]string, len(dec.matLibs))
+	for i, lib := range dec.matLibs {
+		names[i] = lib.name
+	}
+	return names
+}
+
+func (dec *DecodedObject) loadMaterialLibrary(path string) error {
+	mtlFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer mtlFile.Close()
+
+	dec.matCur = nil
+	dec.mtlDir = filepath.Dir(path)
+	return dec.parse(mtlFile, path, dec.parseMtlLine)
+}
@@ -0,0 +1,154 @@
+package obj
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+type smoothKey struct {
+	group, v int
+}
+
+type smoothNormal struct {
+	key    smoothKey
+	normal mgl32.Vec3
+}
+
+type cornerRef struct {
+	face   *Face
+	normal mgl32.Vec3
+}
+
+// GenerateNormals fills in normals for every face that has none. Faces
+// outside a smoothing group get a flat normal; faces sharing a group share
+// angle-weighted averaged normals at common positions. A creaseAngle in
+// degrees greater than zero stops faces whose normals differ by more than it
+// from being averaged together. Calling it again replaces the previously
+// generated normals, so a different crease angle can be applied after load.
+func (dec *DecodedObject) GenerateNormals(creaseAngle float32) {
+	if !dec.normalsGenerated {
+		dec.parsedNormals = len(dec.Normals) / 3
+		dec.normalsGenerated = true
+	}
+	dec.Normals = dec.Normals[:dec.parsedNormals*3]
+
+	faceNormals := make(map[*Face]mgl32.Vec3)
+	shared := make(map[smoothKey][]cornerRef)
+	for o := range dec.Objects {
+		for f := range dec.Objects[o].Faces {
+			face := &dec.Objects[o].Faces[f]
+			if !dec.needsNormals(face) {
+				continue
+			}
+
+			normal := dec.faceNormal(face)
+			faceNormals[face] = normal
+			for c, v := range face.Vertices {
+				if face.Smooth == 0 {
+					continue
+				}
+				key := smoothKey{face.Smooth, v}
+				weighted := normal.Mul(dec.cornerAngle(face, c))
+				shared[key] = append(shared[key], cornerRef{face, weighted})
+			}
+		}
+	}
+
+	cosCrease := float32(-2)
+	if creaseAngle > 0 {
+		cosCrease = float32(math.Cos(float64(mgl32.DegToRad(creaseAngle))))
+	}
+
+	// Corners of a smoothing group that end up with the same normal share
+	// one entry so the mesh builder can merge them into a single vertex.
+	indices := make(map[smoothNormal]int)
+	for o := range dec.Objects {
+		for f := range dec.Objects[o].Faces {
+			face := &dec.Objects[o].Faces[f]
+			normal, ok := faceNormals[face]
+			if !ok {
+				continue
+			}
+
+			if face.Smooth == 0 {
+				index := dec.appendNormal(normal)
+				for c := range face.Normals {
+					face.Normals[c] = index
+				}
+				continue
+			}
+
+			for c, v := range face.Vertices {
+				key := smoothKey{face.Smooth, v}
+				var sum mgl32.Vec3
+				for _, other := range shared[key] {
+					if normal.Dot(faceNormals[other.face]) >= cosCrease {
+						sum = sum.Add(other.normal)
+					}
+				}
+				sum = safeNormalize(sum, normal)
+
+				index, ok := indices[smoothNormal{key, sum}]
+				if !ok {
+					index = dec.appendNormal(sum)
+					indices[smoothNormal{key, sum}] = index
+				}
+				face.Normals[c] = index
+			}
+		}
+	}
+}
+
+func (dec *DecodedObject) needsNormals(face *Face) bool {
+	for _, n := range face.Normals {
+		if n < 0 || n >= dec.parsedNormals {
+			return true
+		}
+	}
+	return false
+}
+
+// faceNormal uses Newell's method so that non-planar polygons still get a
+// sensible normal.
+func (dec *DecodedObject) faceNormal(face *Face) mgl32.Vec3 {
+	var normal mgl32.Vec3
+	for c := range face.Vertices {
+		cur := dec.position(face.Vertices[c])
+		next := dec.position(face.Vertices[(c+1)%len(face.Vertices)])
+		normal[0] += (cur[1] - next[1]) * (cur[2] + next[2])
+		normal[1] += (cur[2] - next[2]) * (cur[0] + next[0])
+		normal[2] += (cur[0] - next[0]) * (cur[1] + next[1])
+	}
+	return safeNormalize(normal, mgl32.Vec3{})
+}
+
+func (dec *DecodedObject) cornerAngle(face *Face, c int) float32 {
+	n := len(face.Vertices)
+	cur := dec.position(face.Vertices[c])
+	prev := dec.position(face.Vertices[(c+n-1)%n]).Sub(cur)
+	next := dec.position(face.Vertices[(c+1)%n]).Sub(cur)
+	if prev.Len() == 0 || next.Len() == 0 {
+		return 0
+	}
+	cos := mgl32.Clamp(prev.Normalize().Dot(next.Normalize()), -1, 1)
+	return float32(math.Acos(float64(cos)))
+}
+
+func (dec *DecodedObject) position(v int) mgl32.Vec3 {
+	if v < 0 || v*3+2 >= len(dec.Vertices) {
+		return mgl32.Vec3{}
+	}
+	return mgl32.Vec3{dec.Vertices[v*3], dec.Vertices[v*3+1], dec.Vertices[v*3+2]}
+}
+
+func (dec *DecodedObject) appendNormal(normal mgl32.Vec3) int {
+	dec.Normals = append(dec.Normals, normal[:]...)
+	return len(dec.Normals)/3 - 1
+}
+
+func safeNormalize(v, fallback mgl32.Vec3) mgl32.Vec3 {
+	if v.Len() < 1e-12 {
+		return fallback
+	}
+	return v.Normalize()
+}
@@ -0,0 +1,77 @@
+package obj
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"strings"
+	"testing"
+)
+
+// hinge is two triangles meeting at a right angle along the x axis, the
+// first facing +z and the second -y.
+const hinge = "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 0 0 -1\n"
+
+func decodeString(t *testing.T, src string) *DecodedObject {
+	t.Helper()
+	dec, err := DecodeObject(strings.NewReader(src), strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dec
+}
+
+func cornerNormal(dec *DecodedObject, face, corner int) mgl32.Vec3 {
+	n := dec.Objects[0].Faces[face].Normals[corner]
+	return mgl32.Vec3{dec.Normals[n*3], dec.Normals[n*3+1], dec.Normals[n*3+2]}
+}
+
+func TestGenerateNormalsFlat(t *testing.T) {
+	dec := decodeString(t, hinge+"o hinge\ns off\nf 1 2 3\nf 2 1 4\n")
+	for c := 0; c < 3; c++ {
+		if n := cornerNormal(dec, 0, c); !n.ApproxEqual(mgl32.Vec3{0, 0, 1}) {
+			t.Errorf("face 0 corner %d: got %v", c, n)
+		}
+		if n := cornerNormal(dec, 1, c); !n.ApproxEqual(mgl32.Vec3{0, -1, 0}) {
+			t.Errorf("face 1 corner %d: got %v", c, n)
+		}
+	}
+}
+
+func TestGenerateNormalsSmoothingGroup(t *testing.T) {
+	dec := decodeString(t, hinge+"o hinge\ns 1\nf 1 2 3\nf 2 1 4\n")
+
+	// Both faces have equal corner angles at the shared edge, so the
+	// averaged normal lies halfway between theirs.
+	shared := mgl32.Vec3{0, -1, 1}.Normalize()
+	for _, c := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		if n := cornerNormal(dec, c[0], c[1]); !n.ApproxEqual(shared) {
+			t.Errorf("face %d corner %d: got %v", c[0], c[1], n)
+		}
+	}
+	if n := cornerNormal(dec, 0, 2); !n.ApproxEqual(mgl32.Vec3{0, 0, 1}) {
+		t.Errorf("unshared corner: got %v", n)
+	}
+	if dec.Objects[0].Faces[0].Normals[0] != dec.Objects[0].Faces[1].Normals[1] {
+		t.Error("corners with the same normal do not share it")
+	}
+
+	// A crease angle below the hinge angle keeps the faces flat.
+	dec.GenerateNormals(60)
+	if n := cornerNormal(dec, 0, 0); !n.ApproxEqual(mgl32.Vec3{0, 0, 1}) {
+		t.Errorf("creased corner: got %v", n)
+	}
+	count := len(dec.Normals)
+	dec.GenerateNormals(60)
+	if len(dec.Normals) != count {
+		t.Errorf("regenerating kept old normals: %d floats, then %d", count, len(dec.Normals))
+	}
+}
+
+func TestGenerateNormalsKeepsParsed(t *testing.T) {
+	dec := decodeString(t, hinge+"vn 1 0 0\no hinge\ns 1\nf 1//1 2//1 3//1\nf 2 1 4\n")
+	if n := cornerNormal(dec, 0, 0); !n.ApproxEqual(mgl32.Vec3{1, 0, 0}) {
+		t.Errorf("parsed normal replaced: got %v", n)
+	}
+	if n := cornerNormal(dec, 1, 0); !n.ApproxEqual(mgl32.Vec3{0, -1, 0}) {
+		t.Errorf("got %v", n)
+	}
+}
@@ -0,0 +1,523 @@
+package obj
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type plyType int
+
+const (
+	plyInvalid plyType = iota
+	plyInt8
+	plyUint8
+	plyInt16
+	plyUint16
+	plyInt32
+	plyUint32
+	plyFloat32
+	plyFloat64
+)
+
+var plyTypes = map[string]plyType{
+	"char": plyInt8, "int8": plyInt8,
+	"uchar": plyUint8, "uint8": plyUint8,
+	"short": plyInt16, "int16": plyInt16,
+	"ushort": plyUint16, "uint16": plyUint16,
+	"int": plyInt32, "int32": plyInt32,
+	"uint": plyUint32, "uint32": plyUint32,
+	"float": plyFloat32, "float32": plyFloat32,
+	"double": plyFloat64, "float64": plyFloat64,
+}
+
+func (t plyType) size() int {
+	switch t {
+	case plyInt8, plyUint8:
+		return 1
+	case plyInt16, plyUint16:
+		return 2
+	case plyFloat64:
+		return 8
+	}
+	return 4
+}
+
+// colorScale maps integer color channels to [0, 1].
+func (t plyType) colorScale() float64 {
+	switch t {
+	case plyUint8:
+		return 255
+	case plyUint16:
+		return 65535
+	}
+	return 1
+}
+
+type plyProperty struct {
+	name      string
+	typ       plyType
+	list      bool
+	countType plyType
+}
+
+type plyElement struct {
+	name  string
+	count int
+	props []plyProperty
+}
+
+func (el *plyElement) index(names ...string) int {
+	for _, name := range names {
+		for i, prop := range el.props {
+			if prop.name == name && !prop.list {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// plyValues reads the properties of one element item, either from an ASCII
+// line or from the binary body.
+type plyValues interface {
+	begin(el *plyElement) error
+	value(field int, t plyType) (float64, error)
+}
+
+// DecodePLY reads an ASCII or binary (either byte order) PLY file. The
+// vertex element's x/y/z, nx/ny/nz, u/v (or s/t) and red/green/blue/alpha
+// properties fill Vertices, Normals, UVs and Colors; the face element's
+// vertex_indices and optional per-corner texcoord lists become faces. Every
+// other scalar property, of any element, is kept in Properties.
+func DecodePLY(reader io.Reader, opts DecodeOptions) (*DecodedObject, error) {
+	dec := newDecodedObject(opts)
+	dec.file = opts.ObjName
+	buf := bufio.NewReader(reader)
+
+	format, elements, err := dec.readPlyHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var values plyValues
+	switch format {
+	case "ascii":
+		values = &plyASCII{dec: dec, buf: buf}
+	case "binary_little_endian":
+		values = &plyBinary{buf: buf, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = &plyBinary{buf: buf, order: binary.BigEndian}
+	}
+
+	name := "ply"
+	if opts.ObjName != "" {
+		name = strings.TrimSuffix(filepath.Base(opts.ObjName), filepath.Ext(opts.ObjName))
+	}
+	dec.Objects = append(dec.Objects, makeObject(name))
+	dec.objCur = &dec.Objects[0]
+
+	p := &plyDecoder{dec: dec, values: values, vertexCount: -1}
+	for _, el := range elements {
+		if el.name == "vertex" && p.vertexCount < 0 {
+			p.vertexCount = el.count
+		}
+	}
+	for e := range elements {
+		if err := p.readElement(&elements[e]); err != nil {
+			return nil, err
+		}
+	}
+
+	dec.stmt = ""
+	dec.cols = nil
+	dec.GenerateNormals(0)
+	return dec, nil
+}
+
+func (dec *DecodedObject) readPlyHeader(buf *bufio.Reader) (string, []plyElement, error) {
+	dec.line = 0
+	format := ""
+	elements := make([]plyElement, 0)
+
+	for {
+		line, err := buf.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return "", nil, dec.newError(SeverityError, -1, errors.New("header has no end_header"))
+			}
+			return "", nil, err
+		}
+		dec.line++
+		line = strings.TrimRight(line, "\r\n\t ")
+
+		if dec.line == 1 {
+			if line != "ply" {
+				return "", nil, dec.newError(SeverityError, -1, errors.New("not a PLY file"))
+			}
+			continue
+		}
+
+		stmt, args, ok := dec.splitStatement(line)
+		if !ok {
+			continue
+		}
+
+		switch stmt {
+		case "format":
+			if len(args) < 1 {
+				return "", nil, dec.errorf(-1, "missing format")
+			}
+			switch args[0] {
+			case "ascii", "binary_little_endian", "binary_big_endian":
+				format = args[0]
+			default:
+				return "", nil, dec.errorf(0, "unknown format %q", args[0])
+			}
+		case "comment", "obj_info":
+		case "element":
+			if len(args) < 2 {
+				return "", nil, dec.errorf(-1, "expected a name and a count")
+			}
+			count, err := strconv.Atoi(args[1])
+			if err != nil || count < 0 {
+				return "", nil, dec.errorf(1, "invalid count %q", args[1])
+			}
+			elements = append(elements, plyElement{name: args[0], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, dec.errorf(-1, "property before any element")
+			}
+			prop, err := dec.parsePlyProperty(args)
+			if err != nil {
+				return "", nil, err
+			}
+			el := &elements[len(elements)-1]
+			el.props = append(el.props, prop)
+		case "end_header":
+			if format == "" {
+				return "", nil, dec.errorf(-1, "header has no format")
+			}
+			return format, elements, nil
+		default:
+			if err := dec.warnf(-1, "statement not supported"); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+}
+
+func (dec *DecodedObject) parsePlyProperty(args []string) (plyProperty, error) {
+	if len(args) >= 1 && args[0] == "list" {
+		if len(args) < 4 {
+			return plyProperty{}, dec.errorf(-1, "expected count type, item type and name")
+		}
+		countType, itemType := plyTypes[args[1]], plyTypes[args[2]]
+		if countType == plyInvalid || countType == plyFloat32 || countType == plyFloat64 {
+			return plyProperty{}, dec.errorf(1, "invalid list count type %q", args[1])
+		}
+		if itemType == plyInvalid {
+			return plyProperty{}, dec.errorf(2, "unknown type %q", args[2])
+		}
+		return plyProperty{name: args[3], typ: itemType, list: true, countType: countType}, nil
+	}
+
+	if len(args) < 2 {
+		return plyProperty{}, dec.errorf(-1, "expected a type and a name")
+	}
+	typ := plyTypes[args[0]]
+	if typ == plyInvalid {
+		return plyProperty{}, dec.errorf(0, "unknown type %q", args[0])
+	}
+	return plyProperty{name: args[1], typ: typ}, nil
+}
+
+type plyDecoder struct {
+	dec         *DecodedObject
+	values      plyValues
+	vertexCount int
+
+	hasNormals bool
+	hasUVs     bool
+}
+
+func (p *plyDecoder) readElement(el *plyElement) error {
+	scalars := make([]float64, len(el.props))
+	lists := make([][]float64, len(el.props))
+
+	for item := 0; item < el.count; item++ {
+		for i := range scalars {
+			scalars[i] = 0
+			lists[i] = lists[i][:0]
+		}
+
+		err := p.readItem(el, scalars, lists)
+		var perr *ParseError
+		if err != nil && !errors.As(err, &perr) {
+			return fmt.Errorf("reading %s %d: %w", el.name, item, err)
+		}
+		if err != nil {
+			if rerr := p.dec.report(err); rerr != nil {
+				return rerr
+			}
+		}
+
+		// A malformed item still produces a (zeroed) vertex and property
+		// values so later indices line up; only faces are dropped.
+		switch {
+		case el.name == "vertex":
+			p.addVertex(el, scalars)
+		case el.name == "face" && err == nil:
+			if err := p.addFace(el, scalars, lists); err != nil {
+				if rerr := p.dec.report(err); rerr != nil {
+					return rerr
+				}
+			}
+		default:
+			p.addProperties(el, scalars, nil)
+		}
+	}
+	return nil
+}
+
+func (p *plyDecoder) readItem(el *plyElement, scalars []float64, lists [][]float64) error {
+	if err := p.values.begin(el); err != nil {
+		return err
+	}
+
+	field := 0
+	for i, prop := range el.props {
+		if !prop.list {
+			v, err := p.values.value(field, prop.typ)
+			if err != nil {
+				return err
+			}
+			scalars[i] = v
+			field++
+			continue
+		}
+
+		n, err := p.values.value(field, prop.countType)
+		if err != nil {
+			return err
+		}
+		field++
+		if n < 0 {
+			return p.dec.errorf(field-1, "negative list length")
+		}
+		for j := 0; j < int(n); j++ {
+			v, err := p.values.value(field, prop.typ)
+			if err != nil {
+				return err
+			}
+			lists[i] = append(lists[i], v)
+			field++
+		}
+	}
+	return nil
+}
+
+var plyUVNames = [][2]string{{"u", "v"}, {"s", "t"}, {"texture_u", "texture_v"}, {"texture_s", "texture_t"}}
+
+func (p *plyDecoder) addVertex(el *plyElement, scalars []float64) {
+	dec := p.dec
+	used := make([]bool, len(el.props))
+	get := func(name string) (float64, bool) {
+		i := el.index(name)
+		if i < 0 {
+			return 0, false
+		}
+		used[i] = true
+		return scalars[i], true
+	}
+
+	for _, name := range []string{"x", "y", "z"} {
+		v, _ := get(name)
+		dec.Vertices = append(dec.Vertices, float32(v))
+	}
+
+	if el.index("nx") >= 0 && el.index("ny") >= 0 && el.index("nz") >= 0 {
+		p.hasNormals = true
+		for _, name := range []string{"nx", "ny", "nz"} {
+			v, _ := get(name)
+			dec.Normals = append(dec.Normals, float32(v))
+		}
+	}
+
+	for _, names := range plyUVNames {
+		if el.index(names[0]) >= 0 && el.index(names[1]) >= 0 {
+			p.hasUVs = true
+			u, _ := get(names[0])
+			v, _ := get(names[1])
+			dec.UVs = append(dec.UVs, float32(u), float32(v))
+			break
+		}
+	}
+
+	if el.index("red", "green", "blue", "alpha") >= 0 {
+		color := [4]float32{0, 0, 0, 1}
+		for c, name := range []string{"red", "green", "blue", "alpha"} {
+			if i := el.index(name); i >= 0 {
+				v, _ := get(name)
+				color[c] = float32(v / el.props[i].typ.colorScale())
+			}
+		}
+		dec.Colors = append(dec.Colors, color[:]...)
+	}
+
+	p.addProperties(el, scalars, used)
+}
+
+func (p *plyDecoder) addFace(el *plyElement, scalars []float64, lists [][]float64) error {
+	dec := p.dec
+	indices := -1
+	texcoords := -1
+	used := make([]bool, len(el.props))
+	for i, prop := range el.props {
+		switch {
+		case prop.list && (prop.name == "vertex_indices" || prop.name == "vertex_index"):
+			indices = i
+		case prop.list && prop.name == "texcoord":
+			texcoords = i
+		}
+	}
+	p.addProperties(el, scalars, used)
+	if indices < 0 {
+		return nil
+	}
+
+	corners := lists[indices]
+	if len(corners) < 3 {
+		return dec.warnf(-1, "face with %d vertices", len(corners))
+	}
+
+	face := Face{
+		Vertices: make([]int, len(corners)),
+		Normals:  make([]int, len(corners)),
+		UVs:      make([]int, len(corners)),
+		Smooth:   1,
+	}
+	for c, v := range corners {
+		index := int(v)
+		if index < 0 || index >= p.vertexCount {
+			return dec.errorf(-1, "vertex index %d out of range", index)
+		}
+		face.Vertices[c] = index
+		face.Normals[c] = math.MaxUint32
+		face.UVs[c] = math.MaxUint32
+		if p.hasNormals {
+			face.Normals[c] = index
+		}
+		if p.hasUVs {
+			face.UVs[c] = index
+		}
+	}
+
+	if texcoords >= 0 && len(lists[texcoords]) == 2*len(corners) {
+		for c := range corners {
+			face.UVs[c] = len(dec.UVs) / 2
+			dec.UVs = append(dec.UVs, float32(lists[texcoords][2*c]), float32(lists[texcoords][2*c+1]))
+		}
+	}
+
+	dec.appendFace(face)
+	return nil
+}
+
+// addProperties stores the element's scalar properties not marked used
+// under "element.property".
+func (p *plyDecoder) addProperties(el *plyElement, scalars []float64, used []bool) {
+	for i, prop := range el.props {
+		if prop.list || (used != nil && used[i]) {
+			continue
+		}
+		if p.dec.Properties == nil {
+			p.dec.Properties = make(map[string][]float64)
+		}
+		key := el.name + "." + prop.name
+		p.dec.Properties[key] = append(p.dec.Properties[key], scalars[i])
+	}
+}
+
+type plyASCII struct {
+	dec    *DecodedObject
+	buf    *bufio.Reader
+	fields []string
+}
+
+func (r *plyASCII) begin(el *plyElement) error {
+	for {
+		line, err := r.buf.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		r.dec.line++
+
+		fields, offsets := fieldsWithOffsets(strings.TrimRight(line, "\r\n\t "))
+		if len(fields) == 0 {
+			continue
+		}
+		r.fields = fields
+		r.dec.stmt = el.name
+		r.dec.cols = offsets
+		return nil
+	}
+}
+
+func (r *plyASCII) value(field int, t plyType) (float64, error) {
+	if field >= len(r.fields) {
+		return 0, r.dec.errorf(-1, "expected more values, got %d", len(r.fields))
+	}
+	v, err := strconv.ParseFloat(r.fields[field], 64)
+	if err != nil {
+		return 0, r.dec.errorf(field, "invalid number: %q", r.fields[field])
+	}
+	return v, nil
+}
+
+type plyBinary struct {
+	buf   *bufio.Reader
+	order binary.ByteOrder
+	data  [8]byte
+}
+
+func (r *plyBinary) begin(el *plyElement) error {
+	return nil
+}
+
+func (r *plyBinary) value(field int, t plyType) (float64, error) {
+	b := r.data[:t.size()]
+	if _, err := io.ReadFull(r.buf, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	switch t {
+	case plyInt8:
+		return float64(int8(b[0])), nil
+	case plyUint8:
+		return float64(b[0]), nil
+	case plyInt16:
+		return float64(int16(r.order.Uint16(b))), nil
+	case plyUint16:
+		return float64(r.order.Uint16(b)), nil
+	case plyInt32:
+		return float64(int32(r.order.Uint32(b))), nil
+	case plyUint32:
+		return float64(r.order.Uint32(b)), nil
+	case plyFloat32:
+		return float64(math.Float32frombits(r.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(r.order.Uint64(b)), nil
+}
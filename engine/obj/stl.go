@@ -0,0 +1,202 @@
+package obj
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	stlHeaderSize   = 80
+	stlTriangleSize = 50
+)
+
+// DecodeSTL reads an ASCII or binary STL file. STL stores every triangle
+// with its own copy of each corner, so identical positions are merged into
+// one vertex to give the same indexed shape an OBJ file has. Facet normals
+// are kept; facets with a zero normal get a generated flat one.
+func DecodeSTL(reader io.Reader, opts DecodeOptions) (*DecodedObject, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := newDecodedObject(opts)
+	s := &stlDecoder{dec: dec, positions: make(map[[3]float32]int)}
+	if isBinarySTL(data) {
+		dec.file = opts.ObjName
+		err = s.decodeBinary(data, opts.ObjName)
+	} else {
+		err = dec.parse(bytes.NewReader(data), opts.ObjName, s.parseLine)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec.GenerateNormals(0)
+	return dec, nil
+}
+
+// isBinarySTL tells the two encodings apart. Binary files may start with
+// "solid" too, so a file whose size matches its triangle count is binary
+// whatever its header says.
+func isBinarySTL(data []byte) bool {
+	if stlSizeMatches(data) {
+		return true
+	}
+	return !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("solid"))
+}
+
+func stlSizeMatches(data []byte) bool {
+	if len(data) < stlHeaderSize+4 {
+		return false
+	}
+	count := uint64(binary.LittleEndian.Uint32(data[stlHeaderSize:]))
+	return stlHeaderSize+4+count*stlTriangleSize == uint64(len(data))
+}
+
+type stlDecoder struct {
+	dec       *DecodedObject
+	positions map[[3]float32]int
+
+	// State of the ASCII facet being read.
+	inFacet bool
+	normal  int
+	corners []int
+}
+
+func (s *stlDecoder) decodeBinary(data []byte, name string) error {
+	dec := s.dec
+	if len(data) < stlHeaderSize+4 {
+		return dec.newError(SeverityError, -1, fmt.Errorf("file too short for a binary STL header"))
+	}
+
+	objName := "stl"
+	if name != "" {
+		objName = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	dec.Objects = append(dec.Objects, makeObject(objName))
+	dec.objCur = &dec.Objects[0]
+
+	count := int(binary.LittleEndian.Uint32(data[stlHeaderSize:]))
+	body := data[stlHeaderSize+4:]
+	if available := len(body) / stlTriangleSize; available < count {
+		err := dec.newError(SeverityError, -1, fmt.Errorf("%d triangles declared, only %d present", count, available))
+		if rerr := dec.report(err); rerr != nil {
+			return rerr
+		}
+		count = available
+	}
+
+	for t := 0; t < count; t++ {
+		tri := body[t*stlTriangleSize:]
+		var v [4][3]float32
+		for i := range v {
+			for c := range v[i] {
+				bits := binary.LittleEndian.Uint32(tri[(i*3+c)*4:])
+				v[i][c] = math.Float32frombits(bits)
+			}
+		}
+
+		s.normal = s.appendNormal(v[0])
+		s.corners = s.corners[:0]
+		for _, p := range v[1:] {
+			s.corners = append(s.corners, s.vertex(p))
+		}
+		s.appendFace()
+	}
+	return nil
+}
+
+func (s *stlDecoder) parseLine(line string) error {
+	dec := s.dec
+	stmt, args, ok := dec.splitStatement(line)
+	if !ok {
+		return nil
+	}
+
+	switch stmt {
+	case "solid":
+		name := strings.Join(args, " ")
+		if name == "" {
+			name = fmt.Sprintf("unnamed%d", dec.line)
+		}
+		dec.Objects = append(dec.Objects, makeObject(name))
+		dec.objCur = &dec.Objects[len(dec.Objects)-1]
+	case "facet":
+		if len(args) < 1 || args[0] != "normal" {
+			return dec.errorf(-1, "expected \"facet normal\"")
+		}
+		vals, err := dec.parseFloats(args[1:], 3)
+		s.inFacet = true
+		s.corners = s.corners[:0]
+		s.normal = s.appendNormal(toArray3(vals))
+		return err
+	case "vertex":
+		if !s.inFacet {
+			return dec.errorf(-1, "vertex outside a facet")
+		}
+		vals, err := dec.parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		s.corners = append(s.corners, s.vertex(toArray3(vals)))
+	case "endfacet":
+		if !s.inFacet {
+			return dec.errorf(-1, "endfacet without facet")
+		}
+		s.inFacet = false
+		if len(s.corners) < 3 {
+			return dec.warnf(-1, "facet with %d vertices", len(s.corners))
+		}
+		dec.ensureObject()
+		s.appendFace()
+	case "outer", "endloop", "endsolid":
+	default:
+		return dec.warnf(-1, "statement not supported")
+	}
+	return nil
+}
+
+func toArray3(vals []float32) [3]float32 {
+	return [3]float32{vals[0], vals[1], vals[2]}
+}
+
+func (s *stlDecoder) vertex(p [3]float32) int {
+	if index, ok := s.positions[p]; ok {
+		return index
+	}
+	index := len(s.dec.Vertices) / 3
+	s.dec.Vertices = append(s.dec.Vertices, p[0], p[1], p[2])
+	s.positions[p] = index
+	return index
+}
+
+// appendNormal stores a facet normal, returning math.MaxUint32 for the zero
+// vector so that GenerateNormals computes one instead.
+func (s *stlDecoder) appendNormal(n [3]float32) int {
+	if n == [3]float32{} {
+		return math.MaxUint32
+	}
+	index := len(s.dec.Normals) / 3
+	s.dec.Normals = append(s.dec.Normals, n[0], n[1], n[2])
+	return index
+}
+
+func (s *stlDecoder) appendFace() {
+	face := Face{
+		Vertices: make([]int, len(s.corners)),
+		Normals:  make([]int, len(s.corners)),
+		UVs:      make([]int, len(s.corners)),
+	}
+	for c, v := range s.corners {
+		face.Vertices[c] = v
+		face.Normals[c] = s.normal
+		face.UVs[c] = math.MaxUint32
+	}
+	s.dec.appendFace(face)
+}
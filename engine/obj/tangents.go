@@ -0,0 +1,123 @@
+package obj
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// GenerateTangents fills the tangent attribute of every vertex following the
+// MikkTSpace conventions: per-triangle tangents are weighted by corner angle,
+// accumulated per vertex, orthogonalised against the normal and stored as
+// xyz plus a handedness sign in w. Shaders rebuild the bitangent as
+// w * cross(normal, tangent.xyz).
+func (m *Mesh) GenerateTangents() {
+	count := m.VertexCount()
+	tangents := make([]mgl32.Vec3, count)
+	bitangents := make([]mgl32.Vec3, count)
+
+	for t := 0; t+2 < len(m.Indices); t += 3 {
+		tri := [3]uint32{m.Indices[t], m.Indices[t+1], m.Indices[t+2]}
+		tangent, bitangent, ok := m.triangleTangent(tri)
+		if !ok {
+			continue
+		}
+
+		for c, index := range tri {
+			angle := m.cornerAngle(index, tri[(c+1)%3], tri[(c+2)%3])
+			tangents[index] = tangents[index].Add(tangent.Mul(angle))
+			bitangents[index] = bitangents[index].Add(bitangent.Mul(angle))
+		}
+	}
+
+	for v := 0; v < count; v++ {
+		normal := m.vec3(uint32(v), NormalOffset)
+		tangent := tangents[v]
+
+		tangent = tangent.Sub(normal.Mul(normal.Dot(tangent)))
+		if tangent.Len() < 1e-12 {
+			tangent = perpendicular(normal)
+		} else {
+			tangent = tangent.Normalize()
+		}
+
+		sign := float32(1)
+		if normal.Cross(tangent).Dot(bitangents[v]) < 0 {
+			sign = -1
+		}
+
+		base := v*VertexStride + TangentOffset
+		copy(m.Vertices[base:], tangent[:])
+		m.Vertices[base+3] = sign
+	}
+}
+
+func (m *Mesh) triangleTangent(tri [3]uint32) (mgl32.Vec3, mgl32.Vec3, bool) {
+	p0 := m.vec3(tri[0], PositionOffset)
+	e1 := m.vec3(tri[1], PositionOffset).Sub(p0)
+	e2 := m.vec3(tri[2], PositionOffset).Sub(p0)
+
+	uv0 := m.vec2(tri[0], UVOffset)
+	d1 := m.vec2(tri[1], UVOffset).Sub(uv0)
+	d2 := m.vec2(tri[2], UVOffset).Sub(uv0)
+
+	det := d1[0]*d2[1] - d2[0]*d1[1]
+	if math.Abs(float64(det)) < 1e-12 {
+		return mgl32.Vec3{}, mgl32.Vec3{}, false
+	}
+
+	r := 1 / det
+	tangent := e1.Mul(d2[1]).Sub(e2.Mul(d1[1])).Mul(r)
+	bitangent := e2.Mul(d1[0]).Sub(e1.Mul(d2[0])).Mul(r)
+	return tangent, bitangent, true
+}
+
+func (m *Mesh) cornerAngle(at, a, b uint32) float32 {
+	p := m.vec3(at, PositionOffset)
+	ea := m.vec3(a, PositionOffset).Sub(p)
+	eb := m.vec3(b, PositionOffset).Sub(p)
+	if ea.Len() == 0 || eb.Len() == 0 {
+		return 0
+	}
+	cos := mgl32.Clamp(ea.Normalize().Dot(eb.Normalize()), -1, 1)
+	return float32(math.Acos(float64(cos)))
+}
+
+func (m *Mesh) vec3(index uint32, offset int) mgl32.Vec3 {
+	base := int(index)*VertexStride + offset
+	return mgl32.Vec3{m.Vertices[base], m.Vertices[base+1], m.Vertices[base+2]}
+}
+
+func (m *Mesh) vec2(index uint32, offset int) mgl32.Vec2 {
+	base := int(index)*VertexStride + offset
+	return mgl32.Vec2{m.Vertices[base], m.Vertices[base+1]}
+}
+
+// uvMirrored reports whether the face's texture coordinates wind clockwise,
+// as happens on mirrored UV islands.
+func (dec *DecodedObject) uvMirrored(face *Face) bool {
+	var area float32
+	for c := range face.UVs {
+		cur, ok := dec.uv(face.UVs[c])
+		next, okNext := dec.uv(face.UVs[(c+1)%len(face.UVs)])
+		if !ok || !okNext {
+			return false
+		}
+		area += cur[0]*next[1] - next[0]*cur[1]
+	}
+	return area < 0
+}
+
+func (dec *DecodedObject) uv(vt int) (mgl32.Vec2, bool) {
+	if vt < 0 || vt*2+1 >= len(dec.UVs) {
+		return mgl32.Vec2{}, false
+	}
+	return mgl32.Vec2{dec.UVs[vt*2], dec.UVs[vt*2+1]}, true
+}
+
+func perpendicular(normal mgl32.Vec3) mgl32.Vec3 {
+	axis := mgl32.Vec3{1, 0, 0}
+	if math.Abs(float64(normal[0])) > 0.9 {
+		axis = mgl32.Vec3{0, 1, 0}
+	}
+	return axis.Sub(normal.Mul(normal.Dot(axis))).Normalize()
+}
@@ -0,0 +1,75 @@
+package obj
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+func vertexTangent(m *Mesh, v int) (mgl32.Vec3, float32) {
+	base := v*VertexStride + TangentOffset
+	return mgl32.Vec3{m.Vertices[base], m.Vertices[base+1], m.Vertices[base+2]}, m.Vertices[base+3]
+}
+
+func buildString(t *testing.T, src string) *Mesh {
+	t.Helper()
+	mesh, err := decodeString(t, src).BuildMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mesh
+}
+
+func TestTangentsPlane(t *testing.T) {
+	dec, err := LoadObject("../res/models/plane.obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh, err := dec.BuildMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// u runs along +x and v along -z on a plane facing +y, which is
+	// right-handed.
+	for v := 0; v < mesh.VertexCount(); v++ {
+		if tangent, w := vertexTangent(mesh, v); !tangent.ApproxEqual(mgl32.Vec3{1, 0, 0}) || w != 1 {
+			t.Errorf("vertex %d: got %v, %v", v, tangent, w)
+		}
+	}
+}
+
+func TestTangentsMirroredUVs(t *testing.T) {
+	const quad = "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvn 0 0 1\n"
+	mirrored := buildString(t, quad+"vt 1 0\nvt 0 0\nvt 0 1\nvt 1 1\nf 1/1/1 2/2/1 3/3/1 4/4/1\n")
+	for v := 0; v < mirrored.VertexCount(); v++ {
+		if tangent, w := vertexTangent(mirrored, v); !tangent.ApproxEqual(mgl32.Vec3{-1, 0, 0}) || w != -1 {
+			t.Errorf("vertex %d: got %v, %v", v, tangent, w)
+		}
+	}
+
+	// Two quads mirrored about a shared seam: the seam corners have the
+	// same v/vt/vn on both sides but need opposite handedness, so they are
+	// split into separate vertices.
+	seam := buildString(t, "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nv 2 0 0\nv 2 1 0\nvn 0 0 1\n"+
+		"vt 0 0\nvt 1 0\nvt 1 1\nvt 0 1\nf 1/1/1 2/2/1 3/3/1 4/4/1\nf 2/2/1 5/1/1 6/4/1 3/3/1\n")
+	if seam.VertexCount() != 8 {
+		t.Fatalf("got %d vertices", seam.VertexCount())
+	}
+	signs := map[float32]int{}
+	for v := 0; v < seam.VertexCount(); v++ {
+		_, w := vertexTangent(seam, v)
+		signs[w]++
+	}
+	if signs[1] != 4 || signs[-1] != 4 {
+		t.Errorf("got handedness counts %v", signs)
+	}
+}
+
+func TestTangentsWithoutUVs(t *testing.T) {
+	mesh := buildString(t, "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//1\n")
+	for v := 0; v < mesh.VertexCount(); v++ {
+		tangent, _ := vertexTangent(mesh, v)
+		if l := tangent.Len(); l < 0.999 || l > 1.001 || tangent.Dot(mgl32.Vec3{0, 0, 1}) != 0 {
+			t.Errorf("vertex %d: got %v", v, tangent)
+		}
+	}
+}
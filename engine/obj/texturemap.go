@@ -0,0 +1,194 @@
+package obj
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TextureMap is a map_* (or bump/disp/refl/norm) statement from an MTL file
+// together with the options that preceded the file name.
+type TextureMap struct {
+	Path           string
+	Offset         mgl32.Vec3 // -o
+	Scale          mgl32.Vec3 // -s
+	Clamp          bool       // -clamp
+	BumpMultiplier float32    // -bm
+	Base           float32    // -mm base
+	Gain           float32    // -mm gain
+	Type           string     // -type, only meaningful for refl
+
+	// Data holds the encoded image when it is embedded in the model file
+	// rather than referenced by Path, as glTF allows.
+	Data []byte
+}
+
+// textureOptionArgs lists options that are recognised but not stored, with
+// the number of arguments they take.
+var textureOptionArgs = map[string]int{
+	"-blendu":  1,
+	"-blendv":  1,
+	"-boost":   1,
+	"-cc":      1,
+	"-imfchan": 1,
+	"-texres":  1,
+}
+
+func (dec *DecodedObject) parseMap(line string) error {
+	texMap, err := dec.parseTextureMap(line)
+	if err != nil {
+		return err
+	}
+
+	mat := dec.matCur
+	switch dec.stmt {
+	case "map_Ka":
+		mat.AmbientMap = texMap
+	case "map_Kd":
+		mat.DiffuseMap = texMap
+		mat.Texture = texMap.Path
+	case "map_Ks":
+		mat.SpecularMap = texMap
+	case "map_Ke":
+		mat.EmissiveMap = texMap
+	case "map_Ns":
+		mat.ShininessMap = texMap
+	case "map_d":
+		mat.DissolveMap = texMap
+	case "bump", "map_Bump", "map_bump":
+		mat.BumpMap = texMap
+	case "norm":
+		mat.NormalMap = texMap
+	case "disp":
+		mat.DisplacementMap = texMap
+	case "refl":
+		mat.ReflectionMap = texMap
+	}
+	return nil
+}
+
+// parseTextureMap works on the raw line rather than on its fields so that
+// file names containing spaces survive.
+func (dec *DecodedObject) parseTextureMap(line string) (*TextureMap, error) {
+	fields, offsets := fieldsWithOffsets(line)
+
+	texMap := &TextureMap{
+		Scale:          mgl32.Vec3{1, 1, 1},
+		BumpMultiplier: 1,
+		Gain:           1,
+	}
+
+	pos := 1
+	for pos < len(fields) && strings.HasPrefix(fields[pos], "-") {
+		opt := fields[pos]
+		pos++
+		switch opt {
+		case "-o", "-s", "-t":
+			vec := mgl32.Vec3{}
+			if opt == "-s" {
+				vec = mgl32.Vec3{1, 1, 1}
+			}
+			n := 0
+			for n < 3 && pos < len(fields) {
+				val, err := strconv.ParseFloat(fields[pos], 32)
+				if err != nil {
+					break
+				}
+				vec[n] = float32(val)
+				n++
+				pos++
+			}
+			if n == 0 {
+				return nil, dec.errorf(pos-2, "option %s with no values", opt)
+			}
+			if opt == "-o" {
+				texMap.Offset = vec
+			} else if opt == "-s" {
+				texMap.Scale = vec
+			}
+		case "-clamp":
+			if pos >= len(fields) {
+				return nil, dec.errorf(pos-2, "option -clamp with no value")
+			}
+			texMap.Clamp = fields[pos] == "on"
+			pos++
+		case "-bm":
+			if pos >= len(fields) {
+				return nil, dec.errorf(pos-2, "option -bm with no value")
+			}
+			val, err := strconv.ParseFloat(fields[pos], 32)
+			if err != nil {
+				return nil, dec.errorf(pos-1, "invalid -bm value: %q", fields[pos])
+			}
+			texMap.BumpMultiplier = float32(val)
+			pos++
+		case "-mm":
+			if pos+1 >= len(fields) {
+				return nil, dec.errorf(pos-2, "option -mm needs base and gain")
+			}
+			base, err := strconv.ParseFloat(fields[pos], 32)
+			if err != nil {
+				return nil, dec.errorf(pos-1, "invalid -mm base: %q", fields[pos])
+			}
+			gain, err := strconv.ParseFloat(fields[pos+1], 32)
+			if err != nil {
+				return nil, dec.errorf(pos, "invalid -mm gain: %q", fields[pos+1])
+			}
+			texMap.Base = float32(base)
+			texMap.Gain = float32(gain)
+			pos += 2
+		case "-type":
+			if pos >= len(fields) {
+				return nil, dec.errorf(pos-2, "option -type with no value")
+			}
+			texMap.Type = fields[pos]
+			pos++
+		default:
+			n, ok := textureOptionArgs[opt]
+			if !ok {
+				if err := dec.warnf(pos-2, "texture option not supported: %s", opt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			pos += n
+		}
+	}
+
+	if pos >= len(fields) {
+		return nil, dec.errorf(-1, "no file name given")
+	}
+
+	texMap.Path = dec.resolveTexturePath(strings.TrimSpace(line[offsets[pos]:]))
+	return texMap, nil
+}
+
+func (dec *DecodedObject) resolveTexturePath(path string) string {
+	path = filepath.FromSlash(strings.ReplaceAll(path, "\\", "/"))
+	if dec.mtlDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dec.mtlDir, path)
+}
+
+func fieldsWithOffsets(s string) ([]string, []int) {
+	fields := make([]string, 0)
+	offsets := make([]int, 0)
+	start := -1
+	for i, r := range s {
+		space := r == ' ' || r == '\t'
+		if space && start >= 0 {
+			fields = append(fields, s[start:i])
+			offsets = append(offsets, start)
+			start = -1
+		} else if !space && start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+		offsets = append(offsets, start)
+	}
+	return fields, offsets
+}
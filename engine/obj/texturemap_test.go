@@ -0,0 +1,26 @@
+package obj
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextureMapOptions(t *testing.T) {
+	mtl := "newmtl a\nmap_Bump -bm 0.5 -s 2 2 -clamp on textures/normal map.png\n"
+	dec, err := DecodeObjectWithOptions(strings.NewReader(""), strings.NewReader(mtl), DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bump := dec.Materials["a"].BumpMap
+	if bump == nil {
+		t.Fatal("no bump map")
+	}
+	if bump.Path != filepath.FromSlash("textures/normal map.png") {
+		t.Errorf("got path %q", bump.Path)
+	}
+	if bump.BumpMultiplier != 0.5 || bump.Scale[0] != 2 || bump.Scale[1] != 2 || bump.Scale[2] != 1 || !bump.Clamp {
+		t.Errorf("options not parsed: %+v", bump)
+	}
+}
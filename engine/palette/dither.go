@@ -0,0 +1,185 @@
+package palette
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// Dither selects the threshold pattern used for ordered dithering.
+type Dither int
+
+const (
+	DitherNone Dither = iota
+	DitherBayer4
+	DitherBayer8
+	DitherBlueNoise
+)
+
+var ditherNames = [...]string{
+	DitherNone:      "none",
+	DitherBayer4:    "bayer4",
+	DitherBayer8:    "bayer8",
+	DitherBlueNoise: "bluenoise",
+}
+
+func (d Dither) String() string {
+	if d >= 0 && int(d) < len(ditherNames) {
+		return ditherNames[d]
+	}
+	return fmt.Sprintf("Dither(%d)", int(d))
+}
+
+// ParseDither returns the dither with the given String name. An empty name
+// is DitherNone.
+func ParseDither(name string) (Dither, error) {
+	if name == "" {
+		return DitherNone, nil
+	}
+	for d, n := range ditherNames {
+		if strings.EqualFold(name, n) {
+			return Dither(d), nil
+		}
+	}
+	return DitherNone, fmt.Errorf("palette: unknown dither %q", name)
+}
+
+const blueNoiseSize = 32
+
+var (
+	blueNoiseOnce sync.Once
+	blueNoise     []float32
+)
+
+// ThresholdMap returns the dither's square threshold matrix, row-major with
+// values in (0, 1). Pixel (x, y) uses entry (x mod size, y mod size).
+// DitherNone is a single 0.5, which leaves colors unchanged.
+func (d Dither) ThresholdMap() (size int, values []float32) {
+	switch d {
+	case DitherBayer4:
+		return 4, bayer(4)
+	case DitherBayer8:
+		return 8, bayer(8)
+	case DitherBlueNoise:
+		blueNoiseOnce.Do(func() { blueNoise = voidAndCluster(blueNoiseSize, 1.5) })
+		return blueNoiseSize, blueNoise
+	default:
+		return 1, []float32{0.5}
+	}
+}
+
+// bayer builds the recursive Bayer matrix of a power-of-two size.
+func bayer(size int) []float32 {
+	ranks := []int{0}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 4*n*n)
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				r := 4 * ranks[y*n+x]
+				next[y*2*n+x] = r
+				next[y*2*n+x+n] = r + 2
+				next[(y+n)*2*n+x] = r + 3
+				next[(y+n)*2*n+x+n] = r + 1
+			}
+		}
+		ranks = next
+	}
+	return rankThresholds(ranks)
+}
+
+func rankThresholds(ranks []int) []float32 {
+	values := make([]float32, len(ranks))
+	for i, r := range ranks {
+		values[i] = (float32(r) + 0.5) / float32(len(ranks))
+	}
+	return values
+}
+
+// voidAndCluster generates a tileable blue-noise threshold matrix with
+// Ulichney's void-and-cluster method. It is seeded deterministically so the
+// CPU and GPU paths always agree.
+func voidAndCluster(size int, sigma float64) []float32 {
+	n := size * size
+
+	// kernel[dy*size+dx] is the gaussian weight at a toroidal offset.
+	kernel := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			wx, wy := float64(min(dx, size-dx)), float64(min(dy, size-dy))
+			kernel[dy*size+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+
+	pattern := make([]bool, n)
+	energy := make([]float64, n)
+	toggle := func(i int, on bool) {
+		pattern[i] = on
+		sign := 1.0
+		if !on {
+			sign = -1
+		}
+		x, y := i%size, i/size
+		for j := range energy {
+			dx, dy := (j%size-x+size)%size, (j/size-y+size)%size
+			energy[j] += sign * kernel[dy*size+dx]
+		}
+	}
+	// tightest finds the set pixel with the highest energy, largest the
+	// unset pixel with the lowest.
+	tightest := func() int {
+		best := -1
+		for i := range pattern {
+			if pattern[i] && (best < 0 || energy[i] > energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	largest := func() int {
+		best := -1
+		for i := range pattern {
+			if !pattern[i] && (best < 0 || energy[i] < energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// Initial pattern: a tenth of the pixels at random, then relaxed by
+	// moving the tightest cluster into the largest void until stable.
+	rng := rand.New(rand.NewSource(1))
+	ones := n / 10
+	for _, i := range rng.Perm(n)[:ones] {
+		toggle(i, true)
+	}
+	for {
+		cluster := tightest()
+		toggle(cluster, false)
+		void := largest()
+		toggle(void, true)
+		if void == cluster {
+			break
+		}
+	}
+	initial := append([]bool(nil), pattern...)
+	initialEnergy := append([]float64(nil), energy...)
+
+	ranks := make([]int, n)
+	// Rank the initial pixels by removing clusters.
+	for rank := ones - 1; rank >= 0; rank-- {
+		i := tightest()
+		toggle(i, false)
+		ranks[i] = rank
+	}
+	// Rank the rest by filling voids.
+	copy(pattern, initial)
+	copy(energy, initialEnergy)
+	for rank := ones; rank < n; rank++ {
+		i := largest()
+		toggle(i, true)
+		ranks[i] = rank
+	}
+	return rankThresholds(ranks)
+}
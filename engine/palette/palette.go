@@ -0,0 +1,160 @@
+package palette
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Palette is an ordered list of opaque colors.
+type Palette []color.NRGBA
+
+// Load reads a palette from a .hex file (one RRGGBB per line, as exported by
+// Lospec), a GIMP .gpl file or a .png swatch image.
+func Load(path string) (Palette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var p Palette
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".hex":
+		p, err = ParseHex(file)
+	case ".gpl":
+		p, err = ParseGPL(file)
+	case ".png":
+		var img image.Image
+		img, _, err = image.Decode(bufio.NewReader(file))
+		if err == nil {
+			p = FromImage(img)
+		}
+	default:
+		return nil, fmt.Errorf("palette: %s: unsupported file type %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("palette: %s: %w", path, err)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("palette: %s: no colors", path)
+	}
+	return p, nil
+}
+
+// ParseHex reads one hex color per line. A leading '#' is allowed; blank
+// lines and lines starting with ';' are skipped.
+func ParseHex(r io.Reader) (Palette, error) {
+	var p Palette
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		c, err := parseHexColor(strings.TrimPrefix(text, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p = append(p, c)
+	}
+	return p, scanner.Err()
+}
+
+func parseHexColor(s string) (color.NRGBA, error) {
+	if len(s) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// ParseGPL reads a GIMP palette.
+func ParseGPL(r io.Reader) (Palette, error) {
+	var p Palette
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			if text != "GIMP Palette" {
+				return nil, fmt.Errorf("line 1: missing \"GIMP Palette\" header")
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") || strings.Contains(strings.SplitN(text, " ", 2)[0], ":") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected three color components", line)
+		}
+		var rgb [3]uint8
+		for i := range rgb {
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid component %q", line, fields[i])
+			}
+			rgb[i] = uint8(v)
+		}
+		p = append(p, color.NRGBA{rgb[0], rgb[1], rgb[2], 255})
+	}
+	return p, scanner.Err()
+}
+
+// FromImage collects the distinct opaque colors of a swatch image, scanning
+// rows top to bottom and left to right. Transparent pixels are ignored.
+func FromImage(img image.Image) Palette {
+	var p Palette
+	seen := make(map[color.NRGBA]bool)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				continue
+			}
+			c.A = 255
+			if !seen[c] {
+				seen[c] = true
+				p = append(p, c)
+			}
+		}
+	}
+	return p
+}
+
+// Nearest returns the index of the palette color closest to rgb by squared
+// RGB distance. Channels are nominally in [0, 1] but may fall outside after
+// dithering. Ties go to the earlier color.
+// The palette shader uses the same rule.
+func (p Palette) Nearest(rgb [3]float32) int {
+	if len(p) == 0 {
+		return 0
+	}
+	best, bestDist := 0, p.distance(0, rgb)
+	for i := 1; i < len(p); i++ {
+		if dist := p.distance(i, rgb); dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func (p Palette) distance(i int, rgb [3]float32) float32 {
+	dr := rgb[0] - float32(p[i].R)/255
+	dg := rgb[1] - float32(p[i].G)/255
+	db := rgb[2] - float32(p[i].B)/255
+	return dr*dr + dg*dg + db*db
+}
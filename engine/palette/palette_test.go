@@ -0,0 +1,158 @@
+package palette
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseHex(t *testing.T) {
+	p, err := ParseHex(strings.NewReader("; exported\nff0000\n\n#00FF00\n0000ff\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Palette{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	if len(p) != len(want) {
+		t.Fatalf("got %d colors, want %d", len(p), len(want))
+	}
+	for i := range want {
+		if p[i] != want[i] {
+			t.Errorf("color %d: got %v, want %v", i, p[i], want[i])
+		}
+	}
+
+	if _, err := ParseHex(strings.NewReader("ff00\n")); err == nil {
+		t.Error("expected an error for a short color")
+	}
+}
+
+func TestParseGPL(t *testing.T) {
+	src := "GIMP Palette\nName: Test\nColumns: 2\n#\n  0   0   0\tBlack\n255 255 255 White\n"
+	p, err := ParseGPL(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 2 || p[0] != (color.NRGBA{0, 0, 0, 255}) || p[1] != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("got %v", p)
+	}
+
+	if _, err := ParseGPL(strings.NewReader("0 0 0\n")); err == nil {
+		t.Error("expected an error without the header")
+	}
+}
+
+func TestFromImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.NRGBA{10, 20, 30, 255})
+	img.Set(1, 0, color.NRGBA{40, 50, 60, 255})
+	img.Set(2, 0, color.NRGBA{10, 20, 30, 255})
+	img.Set(0, 1, color.NRGBA{70, 80, 90, 255})
+
+	// Transparent pixels are skipped and duplicates collapse.
+	p := FromImage(img)
+	want := Palette{{10, 20, 30, 255}, {40, 50, 60, 255}, {70, 80, 90, 255}}
+	if len(p) != len(want) {
+		t.Fatalf("got %v, want %v", p, want)
+	}
+	for i := range want {
+		if p[i] != want[i] {
+			t.Errorf("color %d: got %v, want %v", i, p[i], want[i])
+		}
+	}
+}
+
+func TestBayer4(t *testing.T) {
+	size, values := DitherBayer4.ThresholdMap()
+	want := []int{0, 8, 2, 10, 12, 4, 14, 6, 3, 11, 1, 9, 15, 7, 13, 5}
+	if size != 4 {
+		t.Fatalf("got size %d", size)
+	}
+	for i, r := range want {
+		if got := values[i]*16 - 0.5; got != float32(r) {
+			t.Errorf("entry %d: got rank %v, want %d", i, got, r)
+		}
+	}
+}
+
+func TestBlueNoiseIsPermutation(t *testing.T) {
+	size, values := DitherBlueNoise.ThresholdMap()
+	seen := make(map[float32]bool)
+	for _, v := range values {
+		if v <= 0 || v >= 1 || seen[v] {
+			t.Fatalf("threshold %v repeated or out of range", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != size*size {
+		t.Errorf("got %d thresholds, want %d", len(seen), size*size)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	bw := Palette{{0, 0, 0, 255}, {255, 255, 255, 255}}
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []uint8{128, 128, 128, 200})
+	}
+
+	out := Quantize(img, bw, Options{})
+	for i := 0; i < len(out.Pix); i += 4 {
+		if got := out.Pix[i : i+4]; got[0] != 255 || got[3] != 200 {
+			t.Fatalf("undithered pixel %d: got %v", i/4, got)
+		}
+	}
+
+	// A mid gray dithered over a full Bayer tile is half black, half white.
+	out = Quantize(img, bw, Options{Dither: DitherBayer4, Strength: 1})
+	white := 0
+	for i := 0; i < len(out.Pix); i += 4 {
+		if out.Pix[i] == 255 {
+			white++
+		}
+	}
+	if white != 8 {
+		t.Errorf("got %d white pixels, want 8", white)
+	}
+}
+
+func TestNearestOutsideUnitCube(t *testing.T) {
+	p := Palette{{0, 0, 0, 255}, {255, 0, 0, 255}, {255, 255, 255, 255}}
+	for _, tc := range []struct {
+		rgb  [3]float32
+		want int
+	}{
+		{[3]float32{2.5, 2.5, 2.5}, 2},
+		{[3]float32{3, -1, -1}, 1},
+		{[3]float32{-1, -1, -1}, 0},
+	} {
+		if got := p.Nearest(tc.rgb); got != tc.want {
+			t.Errorf("Nearest(%v) = %d, want %d", tc.rgb, got, tc.want)
+		}
+	}
+}
+
+func TestLoadResources(t *testing.T) {
+	p, err := Load("../res/palettes/pico-8.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 16 {
+		t.Errorf("got %d colors, want 16", len(p))
+	}
+}
+
+func TestParseDither(t *testing.T) {
+	for _, d := range []Dither{DitherNone, DitherBayer4, DitherBayer8, DitherBlueNoise} {
+		got, err := ParseDither(d.String())
+		if err != nil || got != d {
+			t.Errorf("%v: got %v, %v", d, got, err)
+		}
+	}
+	if got, err := ParseDither("Bayer8"); err != nil || got != DitherBayer8 {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if _, err := ParseDither("floyd"); err == nil {
+		t.Error("expected an error for an unknown dither")
+	}
+}
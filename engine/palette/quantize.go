@@ -0,0 +1,59 @@
+package palette
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Options control how colors are mapped onto a palette.
+type Options struct {
+	Dither Dither
+
+	// Strength scales the dither offset; 0 disables dithering and 1 spreads
+	// it over roughly the gap between neighbouring palette colors.
+	Strength float32
+}
+
+// Spread is the dither amplitude at strength 1: the average distance
+// between colors if the palette were spread evenly over the RGB cube.
+func (p Palette) Spread() float32 {
+	if len(p) < 2 {
+		return 0
+	}
+	return float32(1 / math.Cbrt(float64(len(p))))
+}
+
+// Map returns the palette color for an RGB color (each channel in [0, 1])
+// drawn at pixel (x, y).
+func (p Palette) Map(rgb [3]float32, x, y int, opts Options) color.NRGBA {
+	if opts.Dither != DitherNone && opts.Strength != 0 {
+		size, thresholds := opts.Dither.ThresholdMap()
+		offset := (thresholds[(y%size)*size+x%size] - 0.5) * opts.Strength * p.Spread()
+		for i := range rgb {
+			rgb[i] += offset
+		}
+	}
+	return p[p.Nearest(rgb)]
+}
+
+// Quantize maps every pixel of img onto the palette, keeping its alpha. It
+// matches the palette post-process pass, so it can be used on screenshots
+// or to preview the effect without a GPU.
+func Quantize(img image.Image, p Palette, opts Options) *image.NRGBA {
+	bounds := img.Bounds()
+	out := image.NewNRGBA(bounds)
+	if len(p) == 0 {
+		return out
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb := [3]float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255}
+			mapped := p.Map(rgb, x-bounds.Min.X, y-bounds.Min.Y, opts)
+			mapped.A = c.A
+			out.SetNRGBA(x, y, mapped)
+		}
+	}
+	return out
+}
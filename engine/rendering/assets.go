@@ -0,0 +1,195 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/assets"
+	"3DPixelGameEngine/engine/obj"
+	"3DPixelGameEngine/engine/texture"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AssetManager loads models, meshes, textures and shaders once per path
+// and hands out reference-counted handles to them. GL objects are deleted
+// when the last handle to them is released.
+type AssetManager struct {
+	models   *assets.Cache[*obj.DecodedObject]
+	meshes   *assets.Cache[*MeshAsset]
+	textures *assets.Cache[*Texture]
+	shaders  *assets.Cache[*Shader]
+}
+
+func NewAssetManager() *AssetManager {
+	return &AssetManager{
+		models:   assets.NewCache[*obj.DecodedObject]("models", nil),
+		meshes:   assets.NewCache("meshes", (*MeshAsset).delete),
+		textures: assets.NewCache("textures", (*Texture).Delete),
+		shaders:  assets.NewCache("shaders", (*Shader).DeleteProgram),
+	}
+}
+
+// MeshAsset is a mesh built from a model file and uploaded to the GPU.
+type MeshAsset struct {
+	Mesh *obj.Mesh
+	GPU  *GPUMesh
+
+	model *assets.Handle[*obj.DecodedObject]
+}
+
+func (a *MeshAsset) delete() {
+	a.GPU.Delete()
+	a.model.Release()
+}
+
+// assetKey identifies files by absolute path, so different spellings of a
+// path share one asset.
+func assetKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Clean(path)
+}
+
+// Model returns the decoded model file at path, loaded with obj.LoadAny.
+// Missing material libraries are logged rather than returned, as the
+// model still draws without them.
+func (m *AssetManager) Model(path string) (*assets.Handle[*obj.DecodedObject], error) {
+	return m.models.Acquire(assetKey(path), func() (*obj.DecodedObject, int64, error) {
+		decoded, err := obj.LoadAny(path, obj.DecodeOptions{})
+		var missing *obj.MissingLibraryError
+		if errors.As(err, &missing) {
+			log.Printf("warning: %v", err)
+		} else if err != nil {
+			return nil, 0, err
+		}
+		size := 4 * (len(decoded.Vertices) + len(decoded.Normals) + len(decoded.UVs) + len(decoded.Colors) + len(decoded.Indices))
+		return decoded, int64(size), nil
+	})
+}
+
+// Mesh returns the mesh of the model file at path, or of its object or
+// group with the key object if that is not empty. Keys are names, with
+// "#n" appended to the nth repeat of a name as for part nodes. A whole OBJ
+// file is loaded with obj.LoadMesh, so its binary mesh cache is used;
+// other meshes are built from Model, which is kept while they are loaded.
+func (m *AssetManager) Mesh(path, object string) (*assets.Handle[*MeshAsset], error) {
+	return m.meshes.Acquire(assetKey(path)+"#"+object, func() (*MeshAsset, int64, error) {
+		var mesh *obj.Mesh
+		var model *assets.Handle[*obj.DecodedObject]
+		var err error
+		if object == "" && isOBJFile(path) {
+			mesh, err = obj.LoadMesh(path)
+			var missing *obj.MissingLibraryError
+			if errors.As(err, &missing) {
+				log.Printf("warning: %v", err)
+			} else if err != nil {
+				return nil, 0, err
+			}
+		} else {
+			model, err = m.Model(path)
+			if err != nil {
+				return nil, 0, err
+			}
+			mesh, err = buildObjectMesh(model.Get(), object)
+			if err != nil {
+				model.Release()
+				return nil, 0, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		// The vertex data is held twice, in mesh and in the GPU buffers.
+		size := 2 * 4 * (len(mesh.Vertices) + len(mesh.Indices))
+		return &MeshAsset{Mesh: mesh, GPU: UploadMesh(mesh), model: model}, int64(size), nil
+	})
+}
+
+// isOBJFile reports whether the file at path is an OBJ model. Files that
+// cannot be read are left for the loader to report.
+func isOBJFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && obj.DetectFormat(path, data) == obj.FormatOBJ
+}
+
+// textureSize is the memory taken by an RGBA8 texture with a full mipmap
+// chain.
+func textureSize(tex *Texture) int64 {
+	return int64(tex.Width) * int64(tex.Height) * 4 * 4 / 3
+}
+
+// Texture returns the image file at path as a texture created with opts.
+// Each image and options pair is one texture.
+func (m *AssetManager) Texture(path string, opts TextureOptions) (*assets.Handle[*Texture], error) {
+	key := fmt.Sprintf("%s|%v", assetKey(path), opts)
+	return m.textures.Acquire(key, func() (*Texture, int64, error) {
+		img, err := texture.Load(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		tex := NewTexture(img, opts)
+		return tex, textureSize(tex), nil
+	})
+}
+
+// TextureData is Texture for encoded images held in memory. They are
+// cached by content.
+func (m *AssetManager) TextureData(data []byte, opts TextureOptions) (*assets.Handle[*Texture], error) {
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("data:%s|%v", hex.EncodeToString(sum[:]), opts)
+	return m.textures.Acquire(key, func() (*Texture, int64, error) {
+		img, err := texture.DecodeBytes(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		tex := NewTexture(img, opts)
+		return tex, textureSize(tex), nil
+	})
+}
+
+// TextureMap loads the image a material texture map points at. Maps with
+// -clamp on (or a clamping glTF sampler) use CLAMP_TO_EDGE whatever opts
+// says.
+func (m *AssetManager) TextureMap(texMap *obj.TextureMap, opts TextureOptions) (*assets.Handle[*Texture], error) {
+	if texMap.Clamp {
+		opts.WrapS = gl.CLAMP_TO_EDGE
+		opts.WrapT = gl.CLAMP_TO_EDGE
+	}
+	if texMap.Data != nil {
+		return m.TextureData(texMap.Data, opts)
+	}
+	return m.Texture(texMap.Path, opts)
+}
+
+// Shader returns the program built by NewShader from the given sources
+// and defines.
+func (m *AssetManager) Shader(vPath, fPath string, defines ...string) (*assets.Handle[*Shader], error) {
+	key := strings.Join(append([]string{assetKey(vPath), assetKey(fPath)}, defines...), "|")
+	return m.shaders.Acquire(key, func() (*Shader, int64, error) {
+		shader, err := NewShader(vPath, fPath, defines...)
+		if err != nil {
+			return nil, 0, err
+		}
+		var size int32
+		gl.GetProgramiv(shader.Program, gl.PROGRAM_BINARY_LENGTH, &size)
+		return shader, int64(size), nil
+	})
+}
+
+// Usage reports how many assets of each type are loaded, how many handles
+// to them are held and roughly how much memory they take.
+func (m *AssetManager) Usage() []assets.Usage {
+	return []assets.Usage{m.models.Usage(), m.meshes.Usage(), m.textures.Usage(), m.shaders.Usage()}
+}
+
+// Delete frees every asset, including those still held.
+func (m *AssetManager) Delete() {
+	m.meshes.Clear()
+	m.models.Clear()
+	m.textures.Clear()
+	m.shaders.Clear()
+}
@@ -0,0 +1,127 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/assets"
+	"3DPixelGameEngine/engine/ecs"
+	"3DPixelGameEngine/engine/obj"
+	"3DPixelGameEngine/engine/scene"
+	"errors"
+	"fmt"
+)
+
+// Components the renderer draws for ECS entities. An entity is drawn when
+// it has a scene.Transform, a Mesh and a Material.
+
+// Mesh is the ECS component holding the geometry an entity draws.
+type Mesh struct {
+	GPU *GPUMesh
+
+	// uploaded marks meshes NewMeshEntity uploaded for the entity alone,
+	// which DeleteMeshEntity deletes. Shared meshes are left alone.
+	uploaded bool
+}
+
+// Material holds the surface of each submesh, keyed by material name. It
+// is both an ECS component and the material state of a RenderableObject.
+type Material struct {
+	Materials map[string]*obj.Material
+	Textures  map[string]uint32
+	Sampling  map[string]SamplingPreset
+
+	// handles holds the textures in Textures that came from an
+	// AssetManager, released when they are replaced.
+	handles map[string]*assets.Handle[*Texture]
+}
+
+func NewMaterial(materials map[string]*obj.Material) Material {
+	return Material{
+		Materials: materials,
+		Textures:  make(map[string]uint32),
+		Sampling:  make(map[string]SamplingPreset),
+		handles:   make(map[string]*assets.Handle[*Texture]),
+	}
+}
+
+// SetTexture makes h the diffuse texture of the named material. The
+// material owns h from then on and releases it with Release or when the
+// texture is replaced.
+func (m *Material) SetTexture(material string, h *assets.Handle[*Texture]) {
+	m.releaseTexture(material)
+	m.Textures[material] = h.Get().ID
+	m.handles[material] = h
+}
+
+func (m *Material) releaseTexture(material string) {
+	if h, ok := m.handles[material]; ok {
+		h.Release()
+		delete(m.handles, material)
+	}
+}
+
+// Release releases every texture set with SetTexture or LoadTextures.
+func (m *Material) Release() {
+	for name := range m.handles {
+		m.releaseTexture(name)
+		delete(m.Textures, name)
+	}
+}
+
+// LoadTextures loads the diffuse map of every material through a.
+// Materials whose image fails to load keep drawing with their diffuse
+// color; the failures are returned together.
+func (m *Material) LoadTextures(a *AssetManager, opts TextureOptions) error {
+	var errs []error
+	for name, mat := range m.Materials {
+		texMap := mat.DiffuseMap
+		if texMap == nil && mat.Texture != "" {
+			texMap = &obj.TextureMap{Path: mat.Texture}
+		}
+		if texMap == nil {
+			continue
+		}
+
+		h, err := a.TextureMap(texMap, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("material %s: %w", name, err))
+			continue
+		}
+		m.SetTexture(name, h)
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Material) sampler(material string, samplers *samplerCache, global SamplingPreset) uint32 {
+	if samplers == nil {
+		return 0
+	}
+	preset := m.Sampling[material]
+	if preset == SamplingInherit {
+		preset = global
+	}
+	mat := m.Materials[material]
+	clamp := mat != nil && mat.DiffuseMap != nil && mat.DiffuseMap.Clamp
+	return samplers.get(preset, clamp)
+}
+
+// NewMeshEntity uploads mesh and creates an entity drawing it at
+// transform. Its textures still have to be loaded through the entity's
+// Material. Destroy it with DeleteMeshEntity.
+func NewMeshEntity(w *ecs.World, mesh *obj.Mesh, transform scene.Transform) ecs.Entity {
+	e := w.NewEntity()
+	ecs.Add(w, e, transform)
+	ecs.Add(w, e, Mesh{GPU: UploadMesh(mesh), uploaded: true})
+	ecs.Add(w, e, NewMaterial(mesh.Materials))
+	return e
+}
+
+// DeleteMeshEntity destroys e, deleting the mesh NewMeshEntity uploaded
+// for it and releasing its Material's textures.
+func DeleteMeshEntity(w *ecs.World, e ecs.Entity) {
+	if m, ok := ecs.Get[Mesh](w, e); ok && m.uploaded {
+		m.GPU.Delete()
+	}
+	if m, ok := ecs.Get[Material](w, e); ok {
+		m.Release()
+	}
+	w.Destroy(e)
+}
@@ -0,0 +1,33 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/assets"
+	"3DPixelGameEngine/engine/ecs"
+	"3DPixelGameEngine/engine/obj"
+	"testing"
+)
+
+func TestDeleteMeshEntityReleasesTextures(t *testing.T) {
+	freed := 0
+	textures := assets.NewCache("textures", func(*Texture) { freed++ })
+	h, err := textures.Acquire("checker.png", func() (*Texture, int64, error) {
+		return &Texture{ID: 7}, 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The mesh is shared rather than uploaded for the entity, so only the
+	// texture is freed.
+	w := ecs.NewWorld()
+	e := w.NewEntity()
+	ecs.Add(w, e, Mesh{GPU: &GPUMesh{}})
+	material := NewMaterial(map[string]*obj.Material{"a": {Name: "a"}})
+	material.SetTexture("a", h)
+	ecs.Add(w, e, material)
+
+	DeleteMeshEntity(w, e)
+	if w.Alive(e) || freed != 1 || textures.Loaded("checker.png") {
+		t.Errorf("alive %v, %d textures freed", w.Alive(e), freed)
+	}
+}
@@ -0,0 +1,119 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"log"
+)
+
+// GPUMesh is a mesh uploaded to vertex and index buffers. Several objects
+// or entities can draw the same GPUMesh.
+type GPUMesh struct {
+	VAO, VBO, EBO uint32
+	Submeshes     []obj.Submesh
+}
+
+func UploadMesh(mesh *obj.Mesh) *GPUMesh {
+	m := &GPUMesh{Submeshes: mesh.Submeshes}
+
+	gl.GenVertexArrays(1, &m.VAO)
+	gl.BindVertexArray(m.VAO)
+
+	gl.GenBuffers(1, &m.VBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.VBO)
+
+	gl.BufferData(gl.ARRAY_BUFFER, len(mesh.Vertices)*4, gl.Ptr(mesh.Vertices), gl.STATIC_DRAW)
+
+	stride := int32(obj.VertexStride * 4)
+
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, stride, gl.PtrOffset(obj.PositionOffset*4))
+	gl.EnableVertexAttribArray(0)
+
+	gl.VertexAttribPointer(1, 2, gl.FLOAT, false, stride, gl.PtrOffset(obj.UVOffset*4))
+	gl.EnableVertexAttribArray(1)
+
+	gl.VertexAttribPointer(2, 3, gl.FLOAT, false, stride, gl.PtrOffset(obj.NormalOffset*4))
+	gl.EnableVertexAttribArray(2)
+
+	gl.VertexAttribPointer(3, 4, gl.FLOAT, false, stride, gl.PtrOffset(obj.TangentOffset*4))
+	gl.EnableVertexAttribArray(3)
+
+	gl.GenBuffers(1, &m.EBO)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.EBO)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(mesh.Indices)*4, gl.Ptr(mesh.Indices), gl.STATIC_DRAW)
+
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		log.Printf("OpenGL error during VAO/VBO setup: %v", err)
+	}
+
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	return m
+}
+
+func (m *GPUMesh) Delete() {
+	gl.DeleteVertexArrays(1, &m.VAO)
+	gl.DeleteBuffers(1, &m.VBO)
+	gl.DeleteBuffers(1, &m.EBO)
+}
+
+// drawContext is the renderer state shared by every draw in a frame.
+type drawContext struct {
+	ubo      uint32
+	program  uint32
+	samplers *samplerCache
+	sampling SamplingPreset
+}
+
+// drawable is one mesh instance to draw this frame, whether it came from
+// the scene graph or from an ECS entity.
+type drawable struct {
+	model          mgl32.Mat4
+	mesh           *GPUMesh
+	material       *Material
+	castShadows    bool
+	receiveShadows bool
+}
+
+func (d *drawable) setMatrices(ubo uint32, project, camera mgl32.Mat4) {
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 16*4, gl.Ptr(&project[0]))
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 16*4, 16*4, gl.Ptr(&camera[0]))
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 32*4, 16*4, gl.Ptr(&d.model[0]))
+}
+
+func (d *drawable) draw(ctx drawContext, project, camera mgl32.Mat4) {
+	gl.BindVertexArray(d.mesh.VAO)
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		log.Printf("Error after glBindVertexArray: %v", err)
+	}
+
+	d.setMatrices(ctx.ubo, project, camera)
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		log.Printf("OpenGL error after uniform loc: %v", err)
+	}
+
+	gl.UseProgram(ctx.program)
+	if d.receiveShadows {
+		gl.Uniform1i(uniformReceiveShadows, 1)
+	} else {
+		gl.Uniform1i(uniformReceiveShadows, 0)
+	}
+
+	for _, sub := range d.mesh.Submeshes {
+		mat := d.material.Materials[sub.Material]
+		bindMaterial(mat, d.material.Textures[sub.Material], d.material.sampler(sub.Material, ctx.samplers, ctx.sampling))
+		gl.DrawElements(gl.TRIANGLES, int32(sub.Count), gl.UNSIGNED_INT, gl.PtrOffset(sub.Start*4))
+	}
+}
+
+// drawDepth draws the mesh with whatever program is bound, without
+// materials, for depth-only passes.
+func (d *drawable) drawDepth(ubo uint32, project, camera mgl32.Mat4) {
+	gl.BindVertexArray(d.mesh.VAO)
+	d.setMatrices(ubo, project, camera)
+	for _, sub := range d.mesh.Submeshes {
+		gl.DrawElements(gl.TRIANGLES, int32(sub.Count), gl.UNSIGNED_INT, gl.PtrOffset(sub.Start*4))
+	}
+}
@@ -0,0 +1,136 @@
+package rendering
+
+import (
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+type LightKind int
+
+const (
+	LightDirectional LightKind = iota
+	LightPoint
+	LightSpot
+)
+
+// DefaultMaxLights is how many lights the scene shader is built for until
+// Renderer.SetMaxLights changes it.
+const DefaultMaxLights = 16
+
+// lightBlockBinding is the uniform buffer binding of LightBlock in
+// shader.frag.
+const lightBlockBinding = 2
+
+// Light is a light registered on the Renderer with AddLight. Fields can be
+// changed at any time; they are uploaded every frame.
+type Light struct {
+	Kind      LightKind
+	Color     mgl32.Vec3
+	Intensity float32
+
+	// Position is used by point and spot lights, Direction (the way the
+	// light travels) by directional and spot lights.
+	Position  mgl32.Vec3
+	Direction mgl32.Vec3
+
+	// Range is the distance at which point and spot lights fade out
+	// completely. Zero means they never do.
+	Range float32
+
+	// InnerAngle and OuterAngle are the half-angles of a spot light's cone
+	// in radians. Light falls off smoothly between them.
+	InnerAngle float32
+	OuterAngle float32
+
+	// CastShadows renders a shadow map for directional and spot lights.
+	// Point lights do not cast shadows yet.
+	CastShadows bool
+}
+
+func NewDirectionalLight(direction, color mgl32.Vec3) *Light {
+	return &Light{Kind: LightDirectional, Color: color, Intensity: 1, Direction: direction}
+}
+
+func NewPointLight(position, color mgl32.Vec3, lightRange float32) *Light {
+	return &Light{Kind: LightPoint, Color: color, Intensity: 1, Position: position, Range: lightRange}
+}
+
+func NewSpotLight(position, direction, color mgl32.Vec3, inner, outer float32) *Light {
+	return &Light{
+		Kind:       LightSpot,
+		Color:      color,
+		Intensity:  1,
+		Position:   position,
+		Direction:  direction,
+		InnerAngle: inner,
+		OuterAngle: outer,
+	}
+}
+
+// Sizes in floats of the std140 LightBlock: a header of ambient color,
+// view position and light count, then four vec4s per light.
+const (
+	lightHeaderFloats = 12
+	lightFloats       = 16
+)
+
+// packLights lays out the LightBlock uniform buffer. slots, if given,
+// holds the shadow layers of each light. Lights past maxLights are dropped.
+func packLights(lights []*Light, slots []shadowSlot, ambient, viewPosition mgl32.Vec3, maxLights int) []float32 {
+	count := min(len(lights), maxLights)
+	data := make([]float32, lightHeaderFloats+maxLights*lightFloats)
+	copy(data[0:3], ambient[:])
+	copy(data[4:7], viewPosition[:])
+	data[8] = math.Float32frombits(uint32(count))
+
+	for i, l := range lights[:count] {
+		d := data[lightHeaderFloats+i*lightFloats:]
+		direction := l.Direction
+		if direction.Len() > 0 {
+			direction = direction.Normalize()
+		}
+		outer := max(l.OuterAngle, l.InnerAngle)
+		copy(d[0:3], l.Position[:])
+		d[3] = float32(l.Kind)
+		copy(d[4:7], direction[:])
+		d[7] = l.Range
+		color := l.Color.Mul(l.Intensity)
+		copy(d[8:11], color[:])
+		d[12] = float32(math.Cos(float64(l.InnerAngle)))
+		d[13] = float32(math.Cos(float64(outer)))
+		slot := noShadow
+		if i < len(slots) {
+			slot = slots[i]
+		}
+		d[14], d[15] = float32(slot.first), float32(slot.count)
+	}
+	return data
+}
+
+// lightBuffer is the uniform buffer holding LightBlock.
+type lightBuffer struct {
+	ubo       uint32
+	maxLights int
+}
+
+func newLightBuffer(maxLights int) *lightBuffer {
+	b := &lightBuffer{maxLights: maxLights}
+	gl.GenBuffers(1, &b.ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, b.ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, (lightHeaderFloats+maxLights*lightFloats)*4, nil, gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	return b
+}
+
+func (b *lightBuffer) upload(lights []*Light, slots []shadowSlot, ambient, viewPosition mgl32.Vec3) {
+	data := packLights(lights, slots, ambient, viewPosition, b.maxLights)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, b.ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(data)*4, gl.Ptr(data))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, lightBlockBinding, b.ubo)
+}
+
+func (b *lightBuffer) Delete() {
+	gl.DeleteBuffers(1, &b.ubo)
+}
@@ -0,0 +1,61 @@
+package rendering
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+	"testing"
+)
+
+// at reads the float at a std140 byte offset of LightBlock in shader.frag.
+func at(data []float32, offset int) float32 {
+	return data[offset/4]
+}
+
+func vec3At(data []float32, offset int) mgl32.Vec3 {
+	return mgl32.Vec3{at(data, offset), at(data, offset+4), at(data, offset+8)}
+}
+
+func TestPackLights(t *testing.T) {
+	spot := NewSpotLight(mgl32.Vec3{1, 2, 3}, mgl32.Vec3{0, -2, 0}, mgl32.Vec3{1, 0.5, 0.25}, 0.25, 0.5)
+	spot.Intensity = 2
+	spot.Range = 10
+	sun := NewDirectionalLight(mgl32.Vec3{0, 0, -1}, mgl32.Vec3{1, 1, 1})
+	extra := NewPointLight(mgl32.Vec3{}, mgl32.Vec3{1, 1, 1}, 1)
+
+	data := packLights([]*Light{spot, sun, extra}, nil, mgl32.Vec3{0.1, 0.2, 0.3}, mgl32.Vec3{4, 5, 6}, 2)
+
+	// vec4 ambientLight; vec4 viewPosition; int lightCount; Light lights[2]
+	// with lights starting at the next 16-byte boundary, 64 bytes each.
+	if len(data)*4 != 48+2*64 {
+		t.Fatalf("got %d bytes", len(data)*4)
+	}
+	if got := vec3At(data, 0); got != (mgl32.Vec3{0.1, 0.2, 0.3}) {
+		t.Errorf("ambientLight = %v", got)
+	}
+	if got := vec3At(data, 16); got != (mgl32.Vec3{4, 5, 6}) {
+		t.Errorf("viewPosition = %v", got)
+	}
+	if got := math.Float32bits(at(data, 32)); got != 2 {
+		t.Errorf("lightCount = %d, want 2: lights past the maximum are dropped", got)
+	}
+
+	const light0 = 48
+	if got := vec3At(data, light0); got != (mgl32.Vec3{1, 2, 3}) || at(data, light0+12) != float32(LightSpot) {
+		t.Errorf("position = %v, kind %v", got, at(data, light0+12))
+	}
+	if got := vec3At(data, light0+16); got != (mgl32.Vec3{0, -1, 0}) || at(data, light0+28) != 10 {
+		t.Errorf("direction = %v, range %v", got, at(data, light0+28))
+	}
+	if got := vec3At(data, light0+32); got != (mgl32.Vec3{2, 1, 0.5}) {
+		t.Errorf("colour = %v, want color times intensity", got)
+	}
+	cosInner, cosOuter := at(data, light0+48), at(data, light0+52)
+	if math.Abs(float64(cosInner)-math.Cos(0.25)) > 1e-6 || math.Abs(float64(cosOuter)-math.Cos(0.5)) > 1e-6 {
+		t.Errorf("cone = %v, %v", cosInner, cosOuter)
+	}
+
+	const light1 = light0 + 64
+	if at(data, light1+12) != float32(LightDirectional) || vec3At(data, light1+16) != (mgl32.Vec3{0, 0, -1}) {
+		t.Errorf("second light = %v", data[light1/4:light1/4+16])
+	}
+}
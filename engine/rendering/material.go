@@ -0,0 +1,59 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Uniform locations declared in shader.frag.
+const (
+	uniformDiffuseColor  = 0
+	uniformHasDiffuseMap = 1
+	uniformEmission      = 2
+	uniformAmbientColor  = 3
+	uniformSpecular      = 4
+	uniformShininess     = 5
+
+	// Set per object rather than per material.
+	uniformReceiveShadows = 6
+
+	diffuseTextureUnit = 0
+)
+
+// defaultDiffuse is used for faces without a usemtl and for materials that
+// were referenced but never defined.
+var defaultDiffuse = mgl32.Vec4{0.8, 0.8, 0.8, 1}
+
+func bindMaterial(mat *obj.Material, texture, sampler uint32) {
+	diffuse := defaultDiffuse
+	// Ka scales the renderer's ambient light on top of the diffuse color.
+	// Materials without one (most non-MTL sources) take it unscaled.
+	ambient := mgl32.Vec3{1, 1, 1}
+	var emission, specular mgl32.Vec3
+	var shininess float32
+	if mat != nil && mat.Defined() {
+		diffuse = mat.Diffuse.Vec4(mat.Opacity)
+		emission = mat.Emission
+		specular = mat.Specular
+		shininess = mat.Shininess
+		if mat.HasAmbient {
+			ambient = mat.Ambient
+		}
+	}
+
+	gl.Uniform4f(uniformDiffuseColor, diffuse[0], diffuse[1], diffuse[2], diffuse[3])
+	gl.Uniform3f(uniformEmission, emission[0], emission[1], emission[2])
+	gl.Uniform3f(uniformAmbientColor, ambient[0], ambient[1], ambient[2])
+	gl.Uniform3f(uniformSpecular, specular[0], specular[1], specular[2])
+	gl.Uniform1f(uniformShininess, shininess)
+
+	gl.ActiveTexture(gl.TEXTURE0 + diffuseTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.BindSampler(diffuseTextureUnit, sampler)
+	if texture != 0 {
+		gl.Uniform1i(uniformHasDiffuseMap, 1)
+	} else {
+		gl.Uniform1i(uniformHasDiffuseMap, 0)
+	}
+}
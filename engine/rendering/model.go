@@ -0,0 +1,133 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"3DPixelGameEngine/engine/scene"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// NewModelNode builds a scene node for a loaded model with one child per
+// OBJ object ("o") or group ("g"), each drawing that part's faces, so the
+// parts can be moved, hidden or reparented on their own. Parts without
+// faces are left out. Delete the node's objects with DeleteSceneObjects.
+func NewModelNode(name string, decoded *obj.DecodedObject) (*scene.Node, error) {
+	root := scene.NewNode(name)
+	keys := objectKeys(decoded.Objects)
+	for i, object := range decoded.Objects {
+		if len(object.Faces) == 0 {
+			continue
+		}
+		mesh, err := decoded.BuildObjectMesh(i)
+		if err != nil {
+			DeleteSceneObjects(root)
+			return nil, fmt.Errorf("object %s: %w", object.Name, err)
+		}
+
+		part := scene.NewNode(object.Name)
+		o := NewMeshObject(mesh)
+		o.Source.Object = keys[i]
+		part.Object = o
+		if err := root.AddChild(part); err != nil {
+			DeleteSceneObjects(part)
+			DeleteSceneObjects(root)
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// objectKeys names the objects of a model that have faces the way their
+// part nodes are told apart (see scene.ChildKeys): by name, with "#n"
+// appended to the nth repeat of a name. Objects without faces get "".
+func objectKeys(objects []obj.Object) []string {
+	keys := make([]string, len(objects))
+	seen := make(map[string]int)
+	for i, o := range objects {
+		if len(o.Faces) == 0 {
+			continue
+		}
+		keys[i] = o.Name
+		if n := seen[o.Name]; n > 0 {
+			keys[i] = fmt.Sprintf("%s#%d", o.Name, n)
+		}
+		seen[o.Name]++
+	}
+	return keys
+}
+
+// buildObjectMesh builds the mesh of the object with the given
+// objectKeys key, or of the whole model if key is empty.
+func buildObjectMesh(decoded *obj.DecodedObject, key string) (*obj.Mesh, error) {
+	if key == "" {
+		return decoded.BuildMesh()
+	}
+	i := slices.Index(objectKeys(decoded.Objects), key)
+	if i < 0 {
+		return nil, fmt.Errorf("no object %q", key)
+	}
+	return decoded.BuildObjectMesh(i)
+}
+
+// LoadModelNode builds the node NewModelNode would for the model file at
+// path, with the parts' meshes loaded through a so nodes of the same file
+// share them. Each part records the file as its Source so the node can be
+// saved in a scene. Delete the node's objects with DeleteSceneObjects.
+func LoadModelNode(a *AssetManager, name, path string) (*scene.Node, error) {
+	model, err := a.Model(path)
+	if err != nil {
+		return nil, err
+	}
+	defer model.Release()
+
+	root := scene.NewNode(name)
+	objects := model.Get().Objects
+	keys := objectKeys(objects)
+	for i, object := range objects {
+		if len(object.Faces) == 0 {
+			continue
+		}
+		h, err := a.Mesh(path, keys[i])
+		if err != nil {
+			DeleteSceneObjects(root)
+			return nil, fmt.Errorf("object %s: %w", object.Name, err)
+		}
+
+		part := scene.NewNode(object.Name)
+		o := NewAssetObject(h)
+		o.Source = MeshSource{Path: path, Object: keys[i]}
+		part.Object = o
+		if err := root.AddChild(part); err != nil {
+			DeleteSceneObjects(part)
+			DeleteSceneObjects(root)
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// LoadSceneTextures calls LoadTextures on every object at or below root.
+func LoadSceneTextures(root *scene.Node, a *AssetManager, opts TextureOptions) error {
+	var errs []error
+	root.Walk(func(n *scene.Node) bool {
+		if o, ok := n.Object.(*RenderableObject); ok {
+			if err := o.LoadTextures(a, opts); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", n.Name, err))
+			}
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// DeleteSceneObjects calls Delete on every object at or below root,
+// including hidden ones. Call it once the nodes are no longer drawn.
+func DeleteSceneObjects(root *scene.Node) {
+	if o, ok := root.Object.(*RenderableObject); ok {
+		o.Delete()
+	}
+	for _, child := range root.Children() {
+		DeleteSceneObjects(child)
+	}
+}
@@ -0,0 +1,36 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestObjectKeysKeepRepeatedGroups(t *testing.T) {
+	// Two "g default" blocks, as many exporters write, around a named
+	// group and an empty one.
+	src := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\n" +
+		"g default\nf 1 2 3\ng lid\nf 1 3 4\ng default\nf 1 2 4\nf 2 3 4\ng empty\n"
+	decoded, err := obj.DecodeObject(strings.NewReader(src), strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := objectKeys(decoded.Objects)
+	if want := []string{"default", "lid", "default#1", ""}; !slices.Equal(keys, want) {
+		t.Fatalf("got keys %q, want %q", keys, want)
+	}
+	for i, want := range []int{3, 3, 6} {
+		mesh, err := buildObjectMesh(decoded, keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mesh.Indices) != want {
+			t.Errorf("%s: got %d indices, want %d", keys[i], len(mesh.Indices), want)
+		}
+	}
+	if _, err := buildObjectMesh(decoded, "default#2"); err == nil {
+		t.Error("missing repeat accepted")
+	}
+}
@@ -0,0 +1,111 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/palette"
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+)
+
+// Uniform locations and texture units declared in palette.frag.
+const (
+	uniformPaletteSize = 0
+	uniformStrength    = 1
+
+	paletteTextureUnit   = postEffectUnits
+	thresholdTextureUnit = postEffectUnits + 1
+)
+
+// PalettePass is a PostEffect that maps the frame onto a fixed palette,
+// optionally with ordered dithering. palette.Quantize does the same on the
+// CPU.
+type PalettePass struct {
+	program   uint32
+	colors    uint32
+	threshold uint32
+
+	palette palette.Palette
+	opts    palette.Options
+}
+
+func NewPalettePass(p palette.Palette, opts palette.Options) (*PalettePass, error) {
+	shader, err := NewShader(fullscreenVertexShader, "engine/res/shaders/palette.frag")
+	if err != nil {
+		return nil, err
+	}
+
+	pass := &PalettePass{program: shader.Program}
+	gl.GenTextures(1, &pass.colors)
+	gl.GenTextures(1, &pass.threshold)
+
+	if err := pass.SetPalette(p); err != nil {
+		pass.Delete()
+		return nil, err
+	}
+	pass.SetOptions(opts)
+	return pass, nil
+}
+
+// SetPalette uploads a new palette.
+func (pp *PalettePass) SetPalette(p palette.Palette) error {
+	if len(p) == 0 {
+		return fmt.Errorf("palette pass: empty palette")
+	}
+	pix := make([]uint8, 0, 4*len(p))
+	for _, c := range p {
+		pix = append(pix, c.R, c.G, c.B, 255)
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, pp.colors)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(len(p)), 1, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pix))
+	setNearest(gl.TEXTURE_2D)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	pp.palette = p
+	return nil
+}
+
+// SetOptions changes the dither pattern and strength.
+func (pp *PalettePass) SetOptions(opts palette.Options) {
+	size, values := opts.Dither.ThresholdMap()
+	gl.BindTexture(gl.TEXTURE_2D, pp.threshold)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R32F, int32(size), int32(size), 0, gl.RED, gl.FLOAT, gl.Ptr(values))
+	setNearest(gl.TEXTURE_2D)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	pp.opts = opts
+}
+
+func (pp *PalettePass) Palette() palette.Palette {
+	return pp.palette
+}
+
+func (pp *PalettePass) Options() palette.Options {
+	return pp.opts
+}
+
+func (pp *PalettePass) Use() {
+	gl.UseProgram(pp.program)
+	gl.Uniform1i(uniformPaletteSize, int32(len(pp.palette)))
+	strength := float32(0)
+	if pp.opts.Dither != palette.DitherNone {
+		strength = pp.opts.Strength * pp.palette.Spread()
+	}
+	gl.Uniform1f(uniformStrength, strength)
+
+	bindUnsampled(paletteTextureUnit, pp.colors)
+	bindUnsampled(thresholdTextureUnit, pp.threshold)
+}
+
+func (pp *PalettePass) Delete() {
+	gl.DeleteProgram(pp.program)
+	gl.DeleteTextures(1, &pp.colors)
+	gl.DeleteTextures(1, &pp.threshold)
+}
+
+func setNearest(target uint32) {
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+}
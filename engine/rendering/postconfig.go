@@ -0,0 +1,153 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/palette"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// PostConfig declares a post-processing chain. It is usually loaded from
+// JSON:
+//
+//	{"effects": [
+//		{"name": "palette", "type": "palette", "palette": "engine/res/palettes/pico-8.hex",
+//		 "dither": "bayer4", "strength": 0.5},
+//		{"name": "vignette", "shader": "engine/res/shaders/post/vignette.frag",
+//		 "enabled": false, "uniforms": {"radius": 0.8, "tint": [0, 0, 0]}}
+//	]}
+type PostConfig struct {
+	Effects []PostEffectConfig `json:"effects"`
+}
+
+// PostEffectConfig is one effect of a PostConfig. Type is "shader" (the
+// default), which builds a ShaderPass from Shader and Uniforms, or
+// "palette", which builds a PalettePass from Palette, Dither and Strength.
+type PostEffectConfig struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+
+	Shader   string                  `json:"shader,omitempty"`
+	Uniforms map[string]UniformValue `json:"uniforms,omitempty"`
+
+	Palette  string  `json:"palette,omitempty"`
+	Dither   string  `json:"dither,omitempty"`
+	Strength float32 `json:"strength,omitempty"`
+}
+
+// UniformValue holds the components of a uniform. In JSON it is a number,
+// a bool or an array of numbers.
+type UniformValue []float32
+
+// UnmarshalJSON leaves v unchanged for null, as encoding/json does.
+func (v *UniformValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*v = UniformValue{0}
+		if b {
+			(*v)[0] = 1
+		}
+		return nil
+	}
+	var f float32
+	if err := json.Unmarshal(data, &f); err == nil {
+		*v = UniformValue{f}
+		return nil
+	}
+	var list []float32
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("uniform value must be a number, bool or array of numbers")
+	}
+	*v = list
+	return nil
+}
+
+func LoadPostConfig(path string) (*PostConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg PostConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Configure replaces the chain's effects with the ones cfg declares. If
+// any effect fails to build the chain is left as it was.
+func (c *PostChain) Configure(cfg *PostConfig) error {
+	// Names are checked before anything is built.
+	seen := make(map[string]bool)
+	for i, ec := range cfg.Effects {
+		if ec.Name == "" {
+			return fmt.Errorf("post effect %d has no name", i)
+		}
+		if seen[ec.Name] {
+			return fmt.Errorf("post effect %q declared twice", ec.Name)
+		}
+		seen[ec.Name] = true
+	}
+
+	stages := make([]*postStage, 0, len(cfg.Effects))
+	for _, ec := range cfg.Effects {
+		effect, err := ec.build()
+		if err != nil {
+			for _, stage := range stages {
+				stage.effect.Delete()
+			}
+			return fmt.Errorf("post effect %q: %w", ec.Name, err)
+		}
+		enabled := ec.Enabled == nil || *ec.Enabled
+		stages = append(stages, &postStage{name: ec.Name, effect: effect, enabled: enabled})
+	}
+
+	c.Clear()
+	c.stages = stages
+	return nil
+}
+
+// LoadConfig reads a JSON PostConfig and applies it with Configure.
+func (c *PostChain) LoadConfig(path string) error {
+	cfg, err := LoadPostConfig(path)
+	if err != nil {
+		return err
+	}
+	return c.Configure(cfg)
+}
+
+func (ec *PostEffectConfig) build() (PostEffect, error) {
+	switch ec.Type {
+	case "", "shader":
+		if ec.Shader == "" {
+			return nil, fmt.Errorf("missing shader path")
+		}
+		pass, err := NewShaderPass(ec.Shader)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range ec.Uniforms {
+			if err := pass.SetUniform(name, value...); err != nil {
+				pass.Delete()
+				return nil, err
+			}
+		}
+		return pass, nil
+
+	case "palette":
+		p, err := palette.Load(ec.Palette)
+		if err != nil {
+			return nil, err
+		}
+		dither, err := palette.ParseDither(ec.Dither)
+		if err != nil {
+			return nil, err
+		}
+		return NewPalettePass(p, palette.Options{Dither: dither, Strength: ec.Strength})
+	}
+	return nil, fmt.Errorf("unknown effect type %q", ec.Type)
+}
@@ -0,0 +1,72 @@
+package rendering
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUniformValue(t *testing.T) {
+	var cfg PostConfig
+	err := json.Unmarshal([]byte(`{"effects": [{"name": "a", "uniforms":
+		{"on": true, "off": false, "radius": 0.8, "tint": [0.25, 0.5, 1], "none": []}}]}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]UniformValue{
+		"on":     {1},
+		"off":    {0},
+		"radius": {0.8},
+		"tint":   {0.25, 0.5, 1},
+		"none":   {},
+	}
+	for name, value := range want {
+		if got := cfg.Effects[0].Uniforms[name]; !slices.Equal(got, value) {
+			t.Errorf("%s: got %v, want %v", name, got, value)
+		}
+	}
+
+	for _, bad := range []string{`"0.5"`, `{"x": 1}`, `[true]`, `["a"]`} {
+		var v UniformValue
+		if err := json.Unmarshal([]byte(bad), &v); err == nil {
+			t.Errorf("%s: accepted as %v", bad, v)
+		}
+	}
+
+	v := UniformValue{1}
+	if err := json.Unmarshal([]byte(`null`), &v); err != nil || !slices.Equal(v, UniformValue{1}) {
+		t.Errorf("null: got %v, %v", v, err)
+	}
+}
+
+// stubEffect stands in for a pass so the chain can be checked without GL.
+type stubEffect struct{ deleted bool }
+
+func (e *stubEffect) Use()    {}
+func (e *stubEffect) Delete() { e.deleted = true }
+
+func TestConfigureRejectsBadEffects(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		effects []PostEffectConfig
+		want    string
+	}{
+		{"unnamed", []PostEffectConfig{{Type: "palette"}}, "post effect 0 has no name"},
+		{"duplicate", []PostEffectConfig{{Name: "a", Shader: "a.frag"}, {Name: "a", Shader: "b.frag"}}, `post effect "a" declared twice`},
+		{"unknown type", []PostEffectConfig{{Name: "a", Type: "bloom"}}, `post effect "a": unknown effect type "bloom"`},
+		{"no shader", []PostEffectConfig{{Name: "a"}}, `post effect "a": missing shader path`},
+	} {
+		c := &PostChain{}
+		existing := &stubEffect{}
+		c.Add("existing", existing)
+
+		err := c.Configure(&PostConfig{Effects: tc.effects})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+		if !slices.Equal(c.Names(), []string{"existing"}) || existing.deleted {
+			t.Errorf("%s: chain changed to %v", tc.name, c.Names())
+		}
+	}
+}
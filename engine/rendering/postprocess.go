@@ -0,0 +1,203 @@
+package rendering
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"slices"
+)
+
+// Texture units every post-process shader can read. Effects bind any
+// textures of their own from postEffectUnits up.
+const (
+	postInputUnit = 0
+	postDepthUnit = 1
+
+	postEffectUnits = 2
+)
+
+const fullscreenVertexShader = "engine/res/shaders/fullscreen.vert"
+
+// PostEffect is one full-screen pass of a PostChain. Use makes its program
+// current and sets its uniforms; the chain binds the previous pass's color
+// to unit 0 (sampler "scene"), the scene depth to unit 1 (sampler "depth")
+// and draws a triangle covering the target.
+type PostEffect interface {
+	Use()
+	Delete()
+}
+
+type postStage struct {
+	name    string
+	effect  PostEffect
+	enabled bool
+}
+
+// PostChain runs an ordered list of effects over the rendered scene. Each
+// pass reads the previous pass's output; intermediate results alternate
+// between two targets, and the last pass draws straight to the window.
+type PostChain struct {
+	stages []*postStage
+	ping   *RenderTarget
+	pong   *RenderTarget
+	vao    uint32
+}
+
+func NewPostChain() *PostChain {
+	c := &PostChain{}
+	// Core profile needs a bound VAO even though the vertex shader makes
+	// its own positions.
+	gl.GenVertexArrays(1, &c.vao)
+	return c
+}
+
+// Add appends an enabled effect. Names must be unique within the chain.
+func (c *PostChain) Add(name string, effect PostEffect) error {
+	if c.index(name) >= 0 {
+		return fmt.Errorf("post effect %q already exists", name)
+	}
+	c.stages = append(c.stages, &postStage{name: name, effect: effect, enabled: true})
+	return nil
+}
+
+// Remove deletes the named effect and frees it.
+func (c *PostChain) Remove(name string) {
+	if i := c.index(name); i >= 0 {
+		c.stages[i].effect.Delete()
+		c.stages = slices.Delete(c.stages, i, i+1)
+	}
+}
+
+// Effect returns the named effect, or nil.
+func (c *PostChain) Effect(name string) PostEffect {
+	if i := c.index(name); i >= 0 {
+		return c.stages[i].effect
+	}
+	return nil
+}
+
+// Names lists the effects in the order they run.
+func (c *PostChain) Names() []string {
+	names := make([]string, len(c.stages))
+	for i, stage := range c.stages {
+		names[i] = stage.name
+	}
+	return names
+}
+
+// SetEnabled switches an effect on or off without removing it. It reports
+// whether the effect exists.
+func (c *PostChain) SetEnabled(name string, enabled bool) bool {
+	i := c.index(name)
+	if i < 0 {
+		return false
+	}
+	c.stages[i].enabled = enabled
+	return true
+}
+
+func (c *PostChain) Enabled(name string) bool {
+	i := c.index(name)
+	return i >= 0 && c.stages[i].enabled
+}
+
+// Active reports whether any effect is enabled.
+func (c *PostChain) Active() bool {
+	return slices.ContainsFunc(c.stages, func(s *postStage) bool { return s.enabled })
+}
+
+func (c *PostChain) index(name string) int {
+	return slices.IndexFunc(c.stages, func(s *postStage) bool { return s.name == name })
+}
+
+// Run applies the enabled effects to scene and presents the result,
+// scaled and letterboxed like RenderTarget.BlitToScreen.
+func (c *PostChain) Run(scene *RenderTarget, screenWidth, screenHeight int) {
+	var enabled []*postStage
+	for _, stage := range c.stages {
+		if stage.enabled {
+			enabled = append(enabled, stage)
+		}
+	}
+	if len(enabled) == 0 {
+		scene.BlitToScreen(screenWidth, screenHeight)
+		return
+	}
+
+	if err := c.resize(scene.Width, scene.Height); err != nil {
+		fmt.Println("Failed to create post-process targets: ", err)
+		scene.BlitToScreen(screenWidth, screenHeight)
+		return
+	}
+
+	gl.BindVertexArray(c.vao)
+	input, output := scene, c.ping
+	for i, stage := range enabled {
+		if i == len(enabled)-1 {
+			x, y, w, h := IntegerViewport(scene.Width, scene.Height, screenWidth, screenHeight)
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			gl.Viewport(0, 0, int32(screenWidth), int32(screenHeight))
+			gl.ClearColor(0, 0, 0, 1)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			gl.Viewport(int32(x), int32(y), int32(w), int32(h))
+		} else {
+			output.Bind()
+		}
+
+		stage.effect.Use()
+		bindUnsampled(postInputUnit, input.Texture())
+		bindUnsampled(postDepthUnit, scene.DepthTexture())
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+		input = output
+		if output == c.ping {
+			output = c.pong
+		} else {
+			output = c.ping
+		}
+	}
+	gl.BindVertexArray(0)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func (c *PostChain) resize(width, height int) error {
+	for _, target := range []**RenderTarget{&c.ping, &c.pong} {
+		var err error
+		if *target == nil {
+			*target, err = NewRenderTarget(width, height)
+		} else if (*target).Width != width || (*target).Height != height {
+			err = (*target).Resize(width, height)
+		}
+		if err != nil {
+			*target = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes and frees every effect.
+func (c *PostChain) Clear() {
+	for _, stage := range c.stages {
+		stage.effect.Delete()
+	}
+	c.stages = nil
+}
+
+func (c *PostChain) Delete() {
+	c.Clear()
+	for _, target := range []*RenderTarget{c.ping, c.pong} {
+		if target != nil {
+			target.Delete()
+		}
+	}
+	c.ping, c.pong = nil, nil
+	gl.DeleteVertexArrays(1, &c.vao)
+}
+
+// bindUnsampled binds tex without a sampler object, so material samplers
+// left on the unit do not apply.
+func bindUnsampled(unit, tex uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.BindSampler(unit, 0)
+}
@@ -0,0 +1,85 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/scene"
+	"errors"
+	"path/filepath"
+)
+
+// SavePrefab writes the subtree at node as a prefab file (see
+// scene.ReadPrefab), with the same limits as Renderer.SaveScene. The
+// node's own transform is left out, as every instance places the prefab
+// with its own.
+func SavePrefab(node *scene.Node, path string) error {
+	s := &sceneSaver{file: path}
+	root := s.node(node, "$.nodes[0]")
+	if len(s.errs) > 0 {
+		return errors.Join(s.errs...)
+	}
+	root = scene.CollapsePrefabs([]scene.NodeDesc{root})[0]
+	root.Transform = nil
+	root.MapPaths(relativeTo(filepath.Dir(path)))
+	return scene.WriteFile(path, &scene.File{Nodes: []scene.NodeDesc{root}})
+}
+
+// InstantiatePrefab builds a new instance of the prefab file at path, with
+// its models and textures loaded through the renderer. The node is not
+// attached anywhere.
+func (r *Renderer) InstantiatePrefab(path string) (*scene.Node, error) {
+	expanded, err := scene.ExpandPrefabs("", []scene.NodeDesc{{Prefab: path}}, loadPrefab)
+	if err != nil {
+		return nil, err
+	}
+	l := newSceneLoader(path, r.Assets)
+	node := l.node(expanded[0], "$.nodes[0]")
+	if err := l.err(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// ReloadPrefabs reads every prefab used below Root from disk again and
+// rebuilds its instances in place, keeping each instance's overrides, so
+// edits to prefab files show up without reloading the scene. Instances
+// inside other instances are rebuilt with the outermost one. If anything
+// fails to load, no instance is replaced. The replaced instances' objects
+// are deleted.
+func (r *Renderer) ReloadPrefabs() error {
+	instances := prefabInstances(r.Root, nil)
+	s := &sceneSaver{}
+	descs := make([]scene.NodeDesc, len(instances))
+	for i, n := range instances {
+		descs[i] = s.node(n, scene.IndexPath("$.instances", i))
+	}
+	if len(s.errs) > 0 {
+		return errors.Join(s.errs...)
+	}
+
+	expanded, err := scene.ExpandPrefabs("", scene.CollapsePrefabs(descs), loadPrefab)
+	if err != nil {
+		return err
+	}
+	l := newSceneLoader("", r.Assets)
+	rebuilt := l.nodes(expanded)
+	if err := l.err(); err != nil {
+		return err
+	}
+	for i, n := range instances {
+		n.Replace(rebuilt[i])
+		DeleteSceneObjects(n)
+	}
+	return nil
+}
+
+// prefabInstances appends the outermost instances below n, including
+// hidden ones, which Walk would skip.
+func prefabInstances(n *scene.Node, instances []*scene.Node) []*scene.Node {
+	for _, child := range n.Children() {
+		if child.Prefab != nil {
+			instances = append(instances, child)
+		} else {
+			instances = prefabInstances(child, instances)
+		}
+	}
+	return instances
+}
@@ -1,105 +1,169 @@
 package rendering
 
 import (
+	"3DPixelGameEngine/engine/assets"
 	"3DPixelGameEngine/engine/obj"
-	"fmt"
-	"github.com/go-gl/gl/v4.2-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"log"
 )
 
 type RenderableObject struct {
 	DecodedObject obj.DecodedObject
+	Mesh          obj.Mesh
 	ModelMatrix   mgl32.Mat4
 
 	Position mgl32.Vec3
 	Scale    mgl32.Vec3
 	Rotation mgl32.Quat
+
+	// CastShadows and ReceiveShadows control whether the object is drawn
+	// into shadow maps and whether shadows darken it. Both default to true.
+	CastShadows    bool
+	ReceiveShadows bool
+
+	// Source records where the mesh was loaded from so that scenes holding
+	// the object can be saved. It is empty for meshes built in code.
+	Source MeshSource
+
+	gpu        *GPUMesh
+	mesh       *assets.Handle[*MeshAsset]
+	material   Material
+	nodeMatrix mgl32.Mat4
 }
 
-func NewObject(decodedObject *obj.DecodedObject) *RenderableObject {
-	object := &RenderableObject{
-		DecodedObject: *decodedObject,
-		ModelMatrix:   mgl32.Ident4(),
+// MeshSource is a model file, or one object or group of it when Object is
+// set. Textures maps material names to image files replacing the
+// material's own diffuse map.
+type MeshSource struct {
+	Path     string
+	Object   string
+	Textures map[string]string
+}
 
-		Position: mgl32.Vec3{0, 0, 0},
-		Scale:    mgl32.Vec3{1, 1, 1},
-		Rotation: mgl32.QuatIdent(),
+func NewObject(decodedObject *obj.DecodedObject) *RenderableObject {
+	object := newObject()
+	object.DecodedObject = *decodedObject
+
+	mesh, err := decodedObject.BuildMesh()
+	if err != nil {
+		log.Printf("Failed to build mesh: %v", err)
+	} else {
+		object.Mesh = *mesh
 	}
+
 	object.setup()
 	return object
 }
 
-func (o *RenderableObject) setup() {
-	var vao, vbo, ebo uint32
-
-	gl.GenVertexArrays(1, &vao)
-	gl.BindVertexArray(vao)
-
-	gl.GenBuffers(1, &vbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+// NewMeshObject creates an object from an already built mesh, such as one
+// returned by obj.LoadMesh.
+func NewMeshObject(mesh *obj.Mesh) *RenderableObject {
+	object := newObject()
+	object.Mesh = *mesh
+	object.setup()
+	return object
+}
 
-	gl.BufferData(gl.ARRAY_BUFFER, len(o.DecodedObject.Vertices)*4, gl.Ptr(o.DecodedObject.Vertices), gl.STATIC_DRAW)
+// NewAssetObject creates an object drawing the mesh h holds, taking
+// ownership of h. Objects sharing a mesh asset share its GPU buffers.
+func NewAssetObject(h *assets.Handle[*MeshAsset]) *RenderableObject {
+	object := newObject()
+	object.Mesh = *h.Get().Mesh
+	object.mesh = h
+	object.gpu = h.Get().GPU
+	object.material = NewMaterial(object.Mesh.Materials)
+	object.bindDecoded()
+	return object
+}
 
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 0, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(0)
+func newObject() *RenderableObject {
+	return &RenderableObject{
+		ModelMatrix: mgl32.Ident4(),
 
-	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 0, gl.PtrOffset(3*4))
-	gl.EnableVertexAttribArray(1)
+		Position: mgl32.Vec3{0, 0, 0},
+		Scale:    mgl32.Vec3{1, 1, 1},
+		Rotation: mgl32.QuatIdent(),
 
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 0, gl.PtrOffset(5*4))
-	gl.EnableVertexAttribArray(2)
+		CastShadows:    true,
+		ReceiveShadows: true,
 
-	gl.GenBuffers(1, &ebo)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(o.DecodedObject.Indices)*4, gl.Ptr(o.DecodedObject.Indices), gl.STATIC_DRAW)
+		nodeMatrix: mgl32.Ident4(),
+	}
+}
 
-	o.DecodedObject.VAO = vao
-	o.DecodedObject.VBO = vbo
-	o.DecodedObject.EBO = ebo
+// SetMaterialTexture sets the diffuse texture bound while drawing the
+// submesh that uses the named material. The texture is not owned by the
+// object; see Material.SetTexture for textures that should be.
+func (o *RenderableObject) SetMaterialTexture(material string, texture uint32) {
+	o.material.releaseTexture(material)
+	o.material.Textures[material] = texture
+}
 
-	if err := gl.GetError(); err != gl.NO_ERROR {
-		log.Printf("OpenGL error during VAO/VBO setup: %v", err)
-	}
+// SetMaterialSampling overrides the renderer's sampling preset for the
+// submesh that uses the named material. SamplingInherit removes the
+// override.
+func (o *RenderableObject) SetMaterialSampling(material string, preset SamplingPreset) {
+	o.material.Sampling[material] = preset
+}
 
-	gl.BindVertexArray(0)
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+// LoadTextures loads the diffuse map of every material the mesh uses
+// through a. Materials whose image fails to load keep drawing with their
+// diffuse color; the failures are returned together.
+func (o *RenderableObject) LoadTextures(a *AssetManager, opts TextureOptions) error {
+	return o.material.LoadTextures(a, opts)
 }
 
-func (o *RenderableObject) Draw(program uint32, project, camera mgl32.Mat4) {
-	gl.BindVertexArray(o.DecodedObject.VAO)
-	if err := gl.GetError(); err != gl.NO_ERROR {
-		log.Printf("Error after glBindVertexArray: %v", err)
+// Delete releases the object's textures and mesh. Meshes uploaded by the
+// object itself are deleted; mesh assets only once no object uses them.
+func (o *RenderableObject) Delete() {
+	o.material.Release()
+	if o.mesh != nil {
+		o.mesh.Release()
+		o.mesh = nil
+	} else if o.gpu != nil {
+		o.gpu.Delete()
 	}
+	o.gpu = nil
+}
 
-	o.UpdateModelMatrix()
+// GPUMesh returns the object's uploaded mesh, which entities can share.
+func (o *RenderableObject) GPUMesh() *GPUMesh {
+	return o.gpu
+}
 
-	projData := project[:]
-	camData := camera[:]
-	modelData := o.ModelMatrix[:]
+// Material returns the object's materials and textures.
+func (o *RenderableObject) Material() *Material {
+	return &o.material
+}
 
-	projBuf := make([]float32, 16)
-	camBuf := make([]float32, 16)
-	modelBuf := make([]float32, 16)
+func (o *RenderableObject) setup() {
+	o.gpu = UploadMesh(&o.Mesh)
+	o.material = NewMaterial(o.Mesh.Materials)
+	o.bindDecoded()
+}
 
-	copy(projBuf, projData)
-	copy(camBuf, camData)
-	copy(modelBuf, modelData)
+func (o *RenderableObject) bindDecoded() {
+	o.DecodedObject.VAO = o.gpu.VAO
+	o.DecodedObject.VBO = o.gpu.VBO
+	o.DecodedObject.EBO = o.gpu.EBO
+}
 
-	gl.BindBuffer(gl.UNIFORM_BUFFER, o.DecodedObject.UBO)
-	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, 16*4, gl.Ptr(projBuf))
-	gl.BufferSubData(gl.UNIFORM_BUFFER, 16*4, 16*4, gl.Ptr(camBuf))
-	gl.BufferSubData(gl.UNIFORM_BUFFER, 32*4, 16*4, gl.Ptr(modelBuf))
+// Draw draws the object through the PerspectiveBlock buffer
+// DecodedObject.UBO with the textures' own sampling.
+func (o *RenderableObject) Draw(program uint32, project, camera mgl32.Mat4) {
+	d := o.drawable()
+	d.draw(drawContext{ubo: o.DecodedObject.UBO, program: program}, project, camera)
+}
 
-	if err := gl.GetError(); err != gl.NO_ERROR {
-		fmt.Println(program) // WHY IS PROGRAM 3?
-		fmt.Println(o.DecodedObject.VAO)
-		log.Printf("OpenGL error after uniform loc: %v", err)
+func (o *RenderableObject) drawable() drawable {
+	o.UpdateModelMatrix()
+	return drawable{
+		model:          o.ModelMatrix,
+		mesh:           o.gpu,
+		material:       &o.material,
+		castShadows:    o.CastShadows,
+		receiveShadows: o.ReceiveShadows,
 	}
-
-	gl.UseProgram(program)
-
-	gl.DrawElements(gl.TRIANGLES, int32(len(o.DecodedObject.Indices)), gl.UNSIGNED_INT, gl.PtrOffset(0))
 }
 
 func (o *RenderableObject) SetPosition(x, y, z float32) {
@@ -114,8 +178,12 @@ func (o *RenderableObject) SetRotation(angle float32, axis mgl32.Vec3) {
 	o.Rotation = mgl32.QuatRotate(angle, axis)
 }
 
+// UpdateModelMatrix composes Position, Rotation and Scale, which are
+// relative to the scene node carrying the object, with the node's world
+// transform.
 func (o *RenderableObject) UpdateModelMatrix() {
-	o.ModelMatrix = mgl32.Translate3D(o.Position.X(), o.Position.Y(), o.Position.Z()).
+	local := mgl32.Translate3D(o.Position.X(), o.Position.Y(), o.Position.Z()).
 		Mul4(o.Rotation.Mat4()).
 		Mul4(mgl32.Scale3D(o.Scale.X(), o.Scale.Y(), o.Scale.Z()))
+	o.ModelMatrix = o.nodeMatrix.Mul4(local)
 }
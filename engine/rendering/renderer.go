@@ -2,20 +2,51 @@ package rendering
 
 import (
 	"3DPixelGameEngine/engine"
+	"3DPixelGameEngine/engine/assets"
+	"3DPixelGameEngine/engine/ecs"
+	"3DPixelGameEngine/engine/palette"
+	"3DPixelGameEngine/engine/scene"
 	"fmt"
 	"github.com/go-gl/gl/v4.2-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/go-gl/mathgl/mgl64"
+	"slices"
 	"time"
 )
 
 type Renderer struct {
 	window   *Window
 	program  uint32
+	shader   *assets.Handle[*Shader]
 	ubo      uint32
-	Objects  map[string]*RenderableObject
+	Root     *scene.Node
+	World    *ecs.World
+	Assets   *AssetManager
+	samplers *samplerCache
 	camera   *Camera
 	lastTime time.Time
+	target   *RenderTarget
+	frame    *RenderTarget
+	lights   []*Light
+	lightBuf *lightBuffer
+	shadows  *shadowMaps
+
+	shadowDebug int
+
+	// Ambient is the light color every surface receives regardless of
+	// lights, scaled by each material's Ka.
+	Ambient mgl32.Vec3
+
+	// ShadowDistance is how far from the camera directional lights cast
+	// shadows. The cascades are spread over this distance.
+	ShadowDistance float32
+
+	// Post runs over every frame before it reaches the window.
+	Post *PostChain
+
+	// Sampling is the texture filtering used by every material that does
+	// not set its own in Material.Sampling.
+	Sampling SamplingPreset
 }
 
 func NewRenderer(window *Window) *Renderer {
@@ -26,24 +57,21 @@ func NewRenderer(window *Window) *Renderer {
 		fmt.Println("Init OpenGl using version: ", gl.GoStr(gl.GetString(gl.VERSION)))
 	}
 
-	shader, err := NewShader("engine/res/shaders/shader.vert", "engine/res/shaders/shader.frag")
-	if err != nil {
-		fmt.Println("Failed to create shader: ", err)
-	}
-
 	var ubo uint32
 	gl.GenBuffers(1, &ubo)
 	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo)
 	gl.BufferData(gl.UNIFORM_BUFFER, 3*16*4, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, 1, ubo)
 
-	blockIndex := gl.GetUniformBlockIndex(shader.Program, gl.Str("PerspectiveBlock\x00"))
-	gl.UniformBlockBinding(shader.Program, blockIndex, 1)
-
-	return &Renderer{
-		window:  window,
-		program: shader.Program,
-		ubo:     ubo,
-		Objects: make(map[string]*RenderableObject),
+	r := &Renderer{
+		window:   window,
+		ubo:      ubo,
+		Ambient:  mgl32.Vec3{0.2, 0.2, 0.2},
+		Root:     scene.NewNode("root"),
+		Assets:   NewAssetManager(),
+		samplers: newSamplerCache(),
+		Post:     NewPostChain(),
+		Sampling: SamplingPixelArt,
 		camera: &Camera{
 			Position:    mgl64.Vec3{0, 0, 3},
 			Front:       mgl64.Vec3{0, 0, -1},
@@ -56,24 +84,276 @@ func NewRenderer(window *Window) *Renderer {
 			Fov:         60,
 		},
 		lastTime: time.Now(),
+
+		ShadowDistance: 40,
+		shadowDebug:    -1,
+	}
+	if err := r.SetMaxLights(DefaultMaxLights); err != nil {
+		fmt.Println("Failed to create shader: ", err)
+	}
+	if err := r.SetShadowMapSize(DefaultShadowMapSize); err != nil {
+		fmt.Println("Failed to create shadow maps: ", err)
+	}
+	return r
+}
+
+func (r *Renderer) Camera() *Camera {
+	return r.camera
+}
+
+// SetShadowMapSize recreates the shadow maps with size x size texels per
+// layer.
+func (r *Renderer) SetShadowMapSize(size int) error {
+	shadows, err := newShadowMaps(size)
+	if err != nil {
+		return err
+	}
+	if r.shadows != nil {
+		r.shadows.Delete()
+	}
+	r.shadows = shadows
+	return nil
+}
+
+// SetShadowDebug overlays shadow map layer in the corner of the window,
+// for checking cascade coverage and bias. Directional lights take
+// ShadowCascades consecutive layers, spot lights one, in the order the
+// lights were added. A negative layer turns the overlay off.
+func (r *Renderer) SetShadowDebug(layer int) {
+	r.shadowDebug = min(layer, MaxShadowLayers-1)
+}
+
+// SetMaxLights rebuilds the scene shader and light buffer for up to n
+// lights. Lights added beyond the limit are ignored while drawing.
+func (r *Renderer) SetMaxLights(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid light count %d", n)
+	}
+	h, err := r.Assets.Shader("engine/res/shaders/shader.vert", "engine/res/shaders/shader.frag",
+		fmt.Sprintf("MAX_LIGHTS %d", n), fmt.Sprintf("MAX_SHADOW_LAYERS %d", MaxShadowLayers))
+	if err != nil {
+		return err
+	}
+	shader := h.Get()
+
+	blockIndex := gl.GetUniformBlockIndex(shader.Program, gl.Str("PerspectiveBlock\x00"))
+	gl.UniformBlockBinding(shader.Program, blockIndex, 1)
+	blockIndex = gl.GetUniformBlockIndex(shader.Program, gl.Str("LightBlock\x00"))
+	gl.UniformBlockBinding(shader.Program, blockIndex, lightBlockBinding)
+	blockIndex = gl.GetUniformBlockIndex(shader.Program, gl.Str("ShadowBlock\x00"))
+	gl.UniformBlockBinding(shader.Program, blockIndex, shadowBlockBinding)
+
+	r.shader.Release()
+	if r.lightBuf != nil {
+		r.lightBuf.Delete()
+	}
+	r.shader = h
+	r.program = shader.Program
+	r.lightBuf = newLightBuffer(n)
+	return nil
+}
+
+// MaxLights returns how many lights are drawn at most.
+func (r *Renderer) MaxLights() int {
+	if r.lightBuf == nil {
+		return 0
+	}
+	return r.lightBuf.maxLights
+}
+
+// AddLight registers a light. It keeps lighting the scene, picking up any
+// changes to its fields, until removed.
+func (r *Renderer) AddLight(light *Light) {
+	r.lights = append(r.lights, light)
+}
+
+func (r *Renderer) RemoveLight(light *Light) {
+	r.lights = slices.DeleteFunc(r.lights, func(l *Light) bool { return l == light })
+}
+
+func (r *Renderer) Lights() []*Light {
+	return r.lights
+}
+
+// SetVirtualResolution makes the renderer draw the scene into an offscreen
+// target of width x height and upscale it to the window by whole-number
+// factors. Passing 0, 0 goes back to rendering at window resolution.
+func (r *Renderer) SetVirtualResolution(width, height int) error {
+	if width == 0 && height == 0 {
+		if r.target != nil {
+			r.target.Delete()
+			r.target = nil
+		}
+		return nil
+	}
+
+	if r.target == nil {
+		target, err := NewRenderTarget(width, height)
+		if err != nil {
+			return err
+		}
+		r.target = target
+		return nil
+	}
+	return r.target.Resize(width, height)
+}
+
+// VirtualResolution returns the offscreen resolution, or 0, 0 when the
+// scene is drawn at window resolution.
+func (r *Renderer) VirtualResolution() (int, int) {
+	if r.target == nil {
+		return 0, 0
+	}
+	return r.target.Width, r.target.Height
+}
+
+// SetPalette maps every frame onto p with the given dithering, through
+// the post effect named "palette". A nil palette removes the effect.
+func (r *Renderer) SetPalette(p palette.Palette, opts palette.Options) error {
+	if p == nil {
+		r.Post.Remove("palette")
+		return nil
+	}
+
+	if pass, ok := r.Post.Effect("palette").(*PalettePass); ok {
+		if err := pass.SetPalette(p); err != nil {
+			return err
+		}
+		pass.SetOptions(opts)
+		return nil
+	}
+	pass, err := NewPalettePass(p, opts)
+	if err != nil {
+		return err
+	}
+	r.Post.Remove("palette")
+	return r.Post.Add("palette", pass)
+}
+
+// frameTarget returns the target the scene is drawn into, or nil to draw
+// straight to the window. Without a virtual resolution, post-processing
+// still needs the frame in a texture, so a window-sized target is kept
+// while any effect is enabled.
+func (r *Renderer) frameTarget(screenWidth, screenHeight int) *RenderTarget {
+	if r.target != nil || !r.Post.Active() {
+		if r.frame != nil {
+			r.frame.Delete()
+			r.frame = nil
+		}
+		return r.target
+	}
+
+	var err error
+	if r.frame == nil {
+		r.frame, err = NewRenderTarget(screenWidth, screenHeight)
+	} else if r.frame.Width != screenWidth || r.frame.Height != screenHeight {
+		err = r.frame.Resize(screenWidth, screenHeight)
+	}
+	if err != nil {
+		fmt.Println("Failed to create frame target: ", err)
+		r.frame = nil
 	}
+	return r.frame
 }
 
+// Draw draws the scene graph under Root and the entities of World.
 func (r *Renderer) Draw() {
+	r.draw(r.World)
+}
+
+// Update draws a frame with the entities of w, so the renderer can run as
+// the last system of a world.
+func (r *Renderer) Update(w *ecs.World, dt float64) {
+	r.draw(w)
+}
+
+func (r *Renderer) draw(world *ecs.World) {
+	screenWidth, screenHeight := r.window.window.GetFramebufferSize()
+	aspect := float32(r.window.GetWidth()) / float32(r.window.GetHeight())
+	target := r.frameTarget(screenWidth, screenHeight)
+	if target != nil {
+		aspect = float32(target.Width) / float32(target.Height)
+	}
+
+	near, far := float32(0.1), float32(100.0)
+	view := r.camera.GetTransform()
+	projection := mgl32.Perspective(r.camera.GetFov(), aspect, near, far)
+
+	drawables := r.drawables(world)
+	lights := r.lights[:min(len(r.lights), r.MaxLights())]
+	var slots []shadowSlot
+	if r.shadows != nil {
+		front := r.camera.Front
+		slots = r.shadows.render(lights, drawables, r.ubo, shadowView{
+			invViewProjection: projection.Mul4(view).Inv(),
+			forward:           mgl32.Vec3{float32(front[0]), float32(front[1]), float32(front[2])},
+			near:              near,
+			far:               far,
+			distance:          r.ShadowDistance,
+		})
+		r.shadows.bind()
+	}
+
+	if target != nil {
+		target.Bind()
+	} else {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.Viewport(0, 0, int32(screenWidth), int32(screenHeight))
+	}
+
 	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 
-	view := r.camera.GetTransform()
-	projection := mgl32.Perspective(r.camera.GetFov(),
-		float32(r.window.GetWidth())/float32(r.window.GetHeight()), 0.1, 100.0)
+	if r.lightBuf != nil {
+		p := r.camera.Position
+		r.lightBuf.upload(lights, slots, r.Ambient, mgl32.Vec3{float32(p[0]), float32(p[1]), float32(p[2])})
+	}
 
-	for _, obj := range r.Objects {
-		obj.Draw(r.program, view, projection)
+	ctx := drawContext{ubo: r.ubo, program: r.program, samplers: r.samplers, sampling: r.Sampling}
+	for i := range drawables {
+		drawables[i].draw(ctx, projection, view)
+	}
+
+	if target != nil {
+		r.Post.Run(target, screenWidth, screenHeight)
+	}
+	if r.shadows != nil && r.shadowDebug >= 0 {
+		r.shadows.drawDebug(r.shadowDebug, screenHeight)
 	}
 
 	r.window.SwapBuffers()
 }
 
+// drawables collects what to draw this frame: objects in the scene graph,
+// placed by their node's world transform, and every entity of world with a
+// scene.Transform, Mesh and Material.
+func (r *Renderer) drawables(world *ecs.World) []drawable {
+	var drawables []drawable
+	r.Root.Walk(func(n *scene.Node) bool {
+		if o, ok := n.Object.(*RenderableObject); ok {
+			o.nodeMatrix = n.World()
+			drawables = append(drawables, o.drawable())
+		}
+		return true
+	})
+
+	if world != nil {
+		ecs.Each3(world, func(_ ecs.Entity, t *scene.Transform, mesh *Mesh, mat *Material) {
+			if mesh.GPU == nil {
+				return
+			}
+			drawables = append(drawables, drawable{
+				model:          t.Matrix(),
+				mesh:           mesh.GPU,
+				material:       mat,
+				castShadows:    true,
+				receiveShadows: true,
+			})
+		})
+	}
+	return drawables
+}
+
 func (r *Renderer) CalculateDeltaTime() float64 {
 	currentTime := time.Now()
 	deltaTime := currentTime.Sub(r.lastTime).Seconds()
@@ -0,0 +1,127 @@
+package rendering
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+)
+
+// RenderTarget is an offscreen framebuffer with color and depth textures.
+type RenderTarget struct {
+	Width  int
+	Height int
+
+	fbo   uint32
+	color uint32
+	depth uint32
+}
+
+func NewRenderTarget(width, height int) (*RenderTarget, error) {
+	t := &RenderTarget{}
+	if err := t.Resize(width, height); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Resize recreates the attachments at the new size.
+func (t *RenderTarget) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid render target size %dx%d", width, height)
+	}
+	t.Delete()
+	t.Width, t.Height = width, height
+
+	gl.GenFramebuffers(1, &t.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+
+	gl.GenTextures(1, &t.color)
+	gl.BindTexture(gl.TEXTURE_2D, t.color)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(width), int32(height), 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t.color, 0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	gl.GenTextures(1, &t.depth)
+	gl.BindTexture(gl.TEXTURE_2D, t.depth)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH24_STENCIL8, int32(width), int32(height), 0, gl.DEPTH_STENCIL, gl.UNSIGNED_INT_24_8, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.TEXTURE_2D, t.depth, 0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		t.Delete()
+		return fmt.Errorf("render target framebuffer incomplete: 0x%X", status)
+	}
+	return nil
+}
+
+// Bind directs drawing into the target and sets the viewport to cover it.
+func (t *RenderTarget) Bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fbo)
+	gl.Viewport(0, 0, int32(t.Width), int32(t.Height))
+}
+
+// Texture returns the color attachment, for passes that sample the
+// rendered image.
+func (t *RenderTarget) Texture() uint32 {
+	return t.color
+}
+
+// DepthTexture returns the depth attachment.
+func (t *RenderTarget) DepthTexture() uint32 {
+	return t.depth
+}
+
+// BlitToScreen copies the target to the default framebuffer of the given
+// size, scaled up by the largest whole number that fits and centred with
+// black bars. Nearest filtering keeps every virtual pixel a sharp block.
+func (t *RenderTarget) BlitToScreen(screenWidth, screenHeight int) {
+	x, y, w, h := IntegerViewport(t.Width, t.Height, screenWidth, screenHeight)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, int32(screenWidth), int32(screenHeight))
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, t.fbo)
+	gl.BlitFramebuffer(0, 0, int32(t.Width), int32(t.Height),
+		int32(x), int32(y), int32(x+w), int32(y+h),
+		gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+}
+
+func (t *RenderTarget) Delete() {
+	if t.fbo != 0 {
+		gl.DeleteFramebuffers(1, &t.fbo)
+		gl.DeleteTextures(1, &t.color)
+		gl.DeleteTextures(1, &t.depth)
+		t.fbo, t.color, t.depth = 0, 0, 0
+	}
+}
+
+// IntegerViewport returns where a virtual screen lands on a real one when
+// scaled by a whole number and centred. A real screen smaller than the
+// virtual one falls back to the largest aspect-preserving fit.
+func IntegerViewport(virtualWidth, virtualHeight, screenWidth, screenHeight int) (x, y, w, h int) {
+	if virtualWidth <= 0 || virtualHeight <= 0 {
+		return 0, 0, screenWidth, screenHeight
+	}
+
+	scale := min(screenWidth/virtualWidth, screenHeight/virtualHeight)
+	if scale >= 1 {
+		w, h = virtualWidth*scale, virtualHeight*scale
+	} else if screenWidth*virtualHeight < screenHeight*virtualWidth {
+		w, h = screenWidth, virtualHeight*screenWidth/virtualWidth
+	} else {
+		w, h = virtualWidth*screenHeight/virtualHeight, screenHeight
+	}
+	return (screenWidth - w) / 2, (screenHeight - h) / 2, w, h
+}
@@ -0,0 +1,25 @@
+package rendering
+
+import "testing"
+
+func TestIntegerViewport(t *testing.T) {
+	for _, tc := range []struct {
+		name                       string
+		virtual, screen            [2]int
+		wantX, wantY, wantW, wantH int
+	}{
+		{"exact multiple", [2]int{320, 180}, [2]int{1280, 720}, 0, 0, 1280, 720},
+		{"larger exact multiple", [2]int{320, 180}, [2]int{1920, 1080}, 0, 0, 1920, 1080},
+		{"odd leftover", [2]int{320, 180}, [2]int{1366, 768}, 43, 24, 1280, 720},
+		{"odd leftover rounds down", [2]int{320, 180}, [2]int{1001, 541}, 20, 0, 960, 540},
+		{"tall screen", [2]int{320, 180}, [2]int{800, 1000}, 80, 320, 640, 360},
+		{"smaller and narrower", [2]int{320, 180}, [2]int{160, 160}, 0, 35, 160, 90},
+		{"smaller and wider", [2]int{320, 180}, [2]int{300, 100}, 61, 0, 177, 100},
+		{"no virtual size", [2]int{0, 180}, [2]int{640, 480}, 0, 0, 640, 480},
+	} {
+		x, y, w, h := IntegerViewport(tc.virtual[0], tc.virtual[1], tc.screen[0], tc.screen[1])
+		if x != tc.wantX || y != tc.wantY || w != tc.wantW || h != tc.wantH {
+			t.Errorf("%s: got %d,%d %dx%d, want %d,%d %dx%d", tc.name, x, y, w, h, tc.wantX, tc.wantY, tc.wantW, tc.wantH)
+		}
+	}
+}
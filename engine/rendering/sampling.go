@@ -0,0 +1,136 @@
+package rendering
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"strings"
+)
+
+// SamplingPreset picks how textures are filtered when drawn. Presets are
+// applied through GL sampler objects at draw time, so they can be changed
+// per material or for the whole renderer without reloading textures.
+type SamplingPreset int
+
+const (
+	// SamplingInherit defers to the renderer's global preset.
+	SamplingInherit SamplingPreset = iota
+
+	// SamplingPixelArt keeps every texel a crisp square: nearest filtering
+	// and no mipmaps. Distant surfaces shimmer.
+	SamplingPixelArt
+
+	// SamplingPixelArtMipmapped is nearest filtering that switches to
+	// smaller mip levels with distance, trading a little crispness on far
+	// surfaces for much less shimmering.
+	SamplingPixelArtMipmapped
+
+	// SamplingTrilinear is the usual smooth filtering for regular assets.
+	SamplingTrilinear
+
+	// SamplingAnisotropic is trilinear filtering that stays sharp at
+	// grazing angles, clamped to what the driver supports.
+	SamplingAnisotropic
+)
+
+var samplingNames = [...]string{"inherit", "pixelart", "pixelart-mipmapped", "trilinear", "anisotropic"}
+
+func (p SamplingPreset) String() string {
+	if p >= 0 && int(p) < len(samplingNames) {
+		return samplingNames[p]
+	}
+	return fmt.Sprintf("SamplingPreset(%d)", int(p))
+}
+
+// ParseSamplingPreset returns the preset with the given String name. An
+// empty name is SamplingInherit.
+func ParseSamplingPreset(name string) (SamplingPreset, error) {
+	if name == "" {
+		return SamplingInherit, nil
+	}
+	for p, n := range samplingNames {
+		if strings.EqualFold(name, n) {
+			return SamplingPreset(p), nil
+		}
+	}
+	return SamplingInherit, fmt.Errorf("unknown sampling preset %q", name)
+}
+
+const maxAnisotropy = 16
+
+// TextureOptions returns the sampler parameters of the preset with the
+// given wrap mode. SamplingInherit is treated as SamplingPixelArt.
+func (p SamplingPreset) TextureOptions(wrap int32) TextureOptions {
+	opts := TextureOptions{WrapS: wrap, WrapT: wrap}
+	switch p {
+	case SamplingPixelArtMipmapped:
+		opts.MinFilter, opts.MagFilter = gl.NEAREST_MIPMAP_NEAREST, gl.NEAREST
+	case SamplingTrilinear:
+		opts.MinFilter, opts.MagFilter = gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR
+	case SamplingAnisotropic:
+		opts.MinFilter, opts.MagFilter = gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR
+		opts.Anisotropy = maxAnisotropy
+	default:
+		opts.MinFilter, opts.MagFilter = gl.NEAREST, gl.NEAREST
+	}
+	return opts
+}
+
+type samplerKey struct {
+	preset SamplingPreset
+	clamp  bool
+}
+
+// samplerCache creates sampler objects on first use, one per preset and
+// wrap mode.
+type samplerCache struct {
+	samplers map[samplerKey]uint32
+}
+
+func newSamplerCache() *samplerCache {
+	return &samplerCache{samplers: make(map[samplerKey]uint32)}
+}
+
+func (c *samplerCache) get(preset SamplingPreset, clamp bool) uint32 {
+	if preset == SamplingInherit {
+		preset = SamplingPixelArt
+	}
+	key := samplerKey{preset, clamp}
+	if sampler, ok := c.samplers[key]; ok {
+		return sampler
+	}
+
+	wrap := int32(gl.REPEAT)
+	if clamp {
+		wrap = gl.CLAMP_TO_EDGE
+	}
+	opts := preset.TextureOptions(wrap)
+
+	var sampler uint32
+	gl.GenSamplers(1, &sampler)
+	gl.SamplerParameteri(sampler, gl.TEXTURE_WRAP_S, opts.WrapS)
+	gl.SamplerParameteri(sampler, gl.TEXTURE_WRAP_T, opts.WrapT)
+	gl.SamplerParameteri(sampler, gl.TEXTURE_MIN_FILTER, opts.MinFilter)
+	gl.SamplerParameteri(sampler, gl.TEXTURE_MAG_FILTER, opts.MagFilter)
+	if opts.Anisotropy > 1 {
+		gl.SamplerParameterf(sampler, gl.TEXTURE_MAX_ANISOTROPY, supportedAnisotropy(opts.Anisotropy))
+	}
+
+	c.samplers[key] = sampler
+	return sampler
+}
+
+func (c *samplerCache) Delete() {
+	for key, sampler := range c.samplers {
+		gl.DeleteSamplers(1, &sampler)
+		delete(c.samplers, key)
+	}
+}
+
+func supportedAnisotropy(want float32) float32 {
+	var limit float32
+	gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY, &limit)
+	if limit < 1 {
+		return 1
+	}
+	return min(want, limit)
+}
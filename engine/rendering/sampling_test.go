@@ -0,0 +1,50 @@
+package rendering
+
+import (
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"testing"
+)
+
+func TestParseSamplingPreset(t *testing.T) {
+	for p := SamplingInherit; p <= SamplingAnisotropic; p++ {
+		got, err := ParseSamplingPreset(p.String())
+		if err != nil || got != p {
+			t.Errorf("%v: got %v, %v", p, got, err)
+		}
+	}
+
+	if got, err := ParseSamplingPreset("PixelArt"); err != nil || got != SamplingPixelArt {
+		t.Errorf("mixed case: got %v, %v", got, err)
+	}
+	if got, err := ParseSamplingPreset(""); err != nil || got != SamplingInherit {
+		t.Errorf("empty name: got %v, %v", got, err)
+	}
+	if _, err := ParseSamplingPreset("bilinear"); err == nil {
+		t.Error("unknown name accepted")
+	}
+	if got := SamplingPreset(99).String(); got != "SamplingPreset(99)" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSamplingTextureOptions(t *testing.T) {
+	for _, tc := range []struct {
+		preset     SamplingPreset
+		min, mag   int32
+		anisotropy float32
+	}{
+		{SamplingInherit, gl.NEAREST, gl.NEAREST, 0},
+		{SamplingPixelArt, gl.NEAREST, gl.NEAREST, 0},
+		{SamplingPixelArtMipmapped, gl.NEAREST_MIPMAP_NEAREST, gl.NEAREST, 0},
+		{SamplingTrilinear, gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR, 0},
+		{SamplingAnisotropic, gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR, maxAnisotropy},
+	} {
+		opts := tc.preset.TextureOptions(gl.CLAMP_TO_EDGE)
+		if opts.MinFilter != tc.min || opts.MagFilter != tc.mag || opts.Anisotropy != tc.anisotropy {
+			t.Errorf("%v: got %+v", tc.preset, opts)
+		}
+		if opts.WrapS != gl.CLAMP_TO_EDGE || opts.WrapT != gl.CLAMP_TO_EDGE {
+			t.Errorf("%v: wrap not kept: %+v", tc.preset, opts)
+		}
+	}
+}
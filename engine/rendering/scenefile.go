@@ -0,0 +1,361 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/scene"
+	"errors"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"log"
+	"path/filepath"
+	"slices"
+)
+
+// LoadScene reads a scene file (see scene.File). Its nodes, with prefab
+// instances expanded, replace the children of Root, its lights replace
+// the renderer's lights and its camera, if it has one, is applied. Models,
+// textures and prefabs the file names are loaded relative to the file; on
+// any error nothing is changed. The objects of the nodes replaced are
+// deleted, releasing assets only they used. Problems with a model's own material
+// libraries or textures are only logged, as the model still draws without
+// them.
+func (r *Renderer) LoadScene(path string) error {
+	f, err := scene.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for i := range f.Nodes {
+		f.Nodes[i].MapPaths(resolveIn(filepath.Dir(path)))
+	}
+	l := newSceneLoader(path, r.Assets)
+	expanded, err := scene.ExpandPrefabs(path, f.Nodes, loadPrefab)
+	if err != nil {
+		return err
+	}
+	nodes := l.nodes(expanded)
+	lights := make([]*Light, len(f.Lights))
+	for i, desc := range f.Lights {
+		lights[i] = lightFromDesc(desc)
+	}
+	if err := l.err(); err != nil {
+		return err
+	}
+
+	for _, child := range slices.Clone(r.Root.Children()) {
+		child.Detach()
+		DeleteSceneObjects(child)
+	}
+	for _, n := range nodes {
+		r.Root.AddChild(n)
+	}
+	r.lights = lights
+	if c := f.Camera; c != nil {
+		r.camera.Position = c.Position
+		r.camera.Yaw = c.Yaw
+		r.camera.Pitch = c.Pitch
+		if c.Fov > 0 {
+			r.camera.Fov = c.Fov
+		}
+		r.camera.UpdateVec()
+	}
+	return nil
+}
+
+// SaveScene writes the nodes below Root, the lights and the camera to a
+// scene file at path, with asset paths relative to it. Prefab instances
+// are stored as their differences from the prefab. Nodes carrying a
+// RenderableObject are saved with its Source, so objects built in code
+// cannot be saved; the object's own Position, Rotation and Scale are not
+// saved either, only its node's transform.
+func (r *Renderer) SaveScene(path string) error {
+	s := &sceneSaver{file: path}
+	f := &scene.File{
+		Version: scene.FileVersion,
+		Camera: &scene.CameraDesc{
+			Position: r.camera.Position,
+			Yaw:      r.camera.Yaw,
+			Pitch:    r.camera.Pitch,
+			Fov:      r.camera.Fov,
+		},
+	}
+	for _, l := range r.lights {
+		f.Lights = append(f.Lights, describeLight(l))
+	}
+	for i, child := range r.Root.Children() {
+		f.Nodes = append(f.Nodes, s.node(child, scene.IndexPath("$.nodes", i)))
+	}
+	if len(s.errs) > 0 {
+		return errors.Join(s.errs...)
+	}
+	f.Nodes = scene.CollapsePrefabs(f.Nodes)
+	for i := range f.Nodes {
+		f.Nodes[i].MapPaths(relativeTo(filepath.Dir(path)))
+	}
+	return scene.WriteFile(path, f)
+}
+
+var lightTypes = map[string]LightKind{
+	scene.LightDirectional: LightDirectional,
+	scene.LightPoint:       LightPoint,
+	scene.LightSpot:        LightSpot,
+}
+
+func lightFromDesc(d scene.LightDesc) *Light {
+	return &Light{
+		Kind:        lightTypes[d.Type],
+		Color:       d.Color,
+		Intensity:   d.Intensity,
+		Position:    d.Position,
+		Direction:   d.Direction,
+		Range:       d.Range,
+		InnerAngle:  mgl32.DegToRad(d.InnerAngle),
+		OuterAngle:  mgl32.DegToRad(d.OuterAngle),
+		CastShadows: d.CastShadows,
+	}
+}
+
+func describeLight(l *Light) scene.LightDesc {
+	d := scene.LightDesc{
+		Color:       l.Color,
+		Intensity:   l.Intensity,
+		Position:    l.Position,
+		Direction:   l.Direction,
+		Range:       l.Range,
+		InnerAngle:  mgl32.RadToDeg(l.InnerAngle),
+		OuterAngle:  mgl32.RadToDeg(l.OuterAngle),
+		CastShadows: l.CastShadows,
+	}
+	for name, kind := range lightTypes {
+		if kind == l.Kind {
+			d.Type = name
+		}
+	}
+	return d
+}
+
+// sceneLoader builds scene nodes from expanded node descriptions whose
+// paths are relative to the working directory.
+type sceneLoader struct {
+	file    string
+	assets  *AssetManager
+	objects []*RenderableObject
+	errs    []error
+}
+
+func newSceneLoader(file string, assets *AssetManager) *sceneLoader {
+	return &sceneLoader{file: file, assets: assets}
+}
+
+func (l *sceneLoader) errorf(path, format string, args ...any) {
+	l.errorIn(l.file, path, format, args...)
+}
+
+func (l *sceneLoader) errorIn(file, path, format string, args ...any) {
+	l.errs = append(l.errs, &scene.SchemaError{File: file, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// err returns the errors met so far, deleting the objects already built
+// if there were any.
+func (l *sceneLoader) err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	for _, o := range l.objects {
+		o.Delete()
+	}
+	return errors.Join(l.errs...)
+}
+
+// resolveIn maps paths relative to dir to paths relative to the working
+// directory.
+func resolveIn(dir string) func(string) string {
+	return func(path string) string {
+		path = filepath.FromSlash(path)
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+}
+
+// relativeTo is the inverse of resolveIn.
+func relativeTo(dir string) func(string) string {
+	return func(path string) string {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return filepath.ToSlash(path)
+	}
+}
+
+// loadPrefab is the scene.PrefabLoader for files on disk.
+func loadPrefab(path string) (scene.NodeDesc, error) {
+	root, err := scene.ReadPrefab(path)
+	if err != nil {
+		return root, err
+	}
+	root.MapPaths(resolveIn(filepath.Dir(path)))
+	return root, nil
+}
+
+func (l *sceneLoader) nodes(descs []scene.NodeDesc) []*scene.Node {
+	nodes := make([]*scene.Node, len(descs))
+	for i, desc := range descs {
+		nodes[i] = l.node(desc, scene.IndexPath("$.nodes", i))
+	}
+	return nodes
+}
+
+func (l *sceneLoader) node(desc scene.NodeDesc, path string) *scene.Node {
+	n := scene.NewNode(desc.Name)
+	n.Hidden = desc.Hidden
+	n.SetLocal(desc.Transform.Transform())
+	n.Components = desc.Components
+	n.Prefab = desc.Link
+	if desc.Mesh != nil {
+		// Meshes from prefabs are reported against the prefab file.
+		file, meshPath := l.file, scene.FieldPath(path, "mesh")
+		if at := desc.Mesh.Origin; at != nil {
+			file, meshPath = at.File, at.Path
+		}
+		if o := l.mesh(desc.Mesh, file, meshPath); o != nil {
+			n.Object = o
+		}
+	}
+	children := scene.FieldPath(path, "children")
+	for i, child := range desc.Children {
+		n.AddChild(l.node(child, scene.IndexPath(children, i)))
+	}
+	return n
+}
+
+func (l *sceneLoader) mesh(desc *scene.MeshDesc, file, path string) *RenderableObject {
+	// Whole files are left to Mesh, which can load them from the mesh
+	// cache without decoding the model.
+	errPath := scene.FieldPath(path, "path")
+	if desc.Object != "" {
+		model, err := l.assets.Model(desc.Path)
+		if err != nil {
+			l.errorIn(file, errPath, "%v", err)
+			return nil
+		}
+		found := slices.Contains(objectKeys(model.Get().Objects), desc.Object)
+		model.Release()
+		if !found {
+			l.errorIn(file, scene.FieldPath(path, "object"), "%s has no object %q", desc.Path, desc.Object)
+			return nil
+		}
+		errPath = path
+	}
+	h, err := l.assets.Mesh(desc.Path, desc.Object)
+	if err != nil {
+		l.errorIn(file, errPath, "%v", err)
+		return nil
+	}
+
+	o := NewAssetObject(h)
+	l.objects = append(l.objects, o)
+	o.Source = MeshSource{Path: desc.Path, Object: desc.Object}
+	if desc.CastShadows != nil {
+		o.CastShadows = *desc.CastShadows
+	}
+	if desc.ReceiveShadows != nil {
+		o.ReceiveShadows = *desc.ReceiveShadows
+	}
+	if err := o.LoadTextures(l.assets, DefaultTextureOptions); err != nil {
+		log.Printf("%s: warning: %s: %v", file, desc.Path, err)
+	}
+
+	materials := scene.FieldPath(path, "materials")
+	for _, name := range sortedNames(desc.Materials) {
+		mat := desc.Materials[name]
+		matPath := scene.FieldPath(materials, name)
+		if mat.Sampling != "" {
+			preset, err := ParseSamplingPreset(mat.Sampling)
+			if err != nil {
+				l.errorIn(file, scene.FieldPath(matPath, "sampling"), "%v", err)
+			} else {
+				o.SetMaterialSampling(name, preset)
+			}
+		}
+		if mat.Texture != "" {
+			tex, err := l.assets.Texture(mat.Texture, DefaultTextureOptions)
+			if err != nil {
+				l.errorIn(file, scene.FieldPath(matPath, "texture"), "%v", err)
+				continue
+			}
+			o.material.SetTexture(name, tex)
+			if o.Source.Textures == nil {
+				o.Source.Textures = make(map[string]string)
+			}
+			o.Source.Textures[name] = mat.Texture
+		}
+	}
+	return o
+}
+
+// sceneSaver describes scene nodes with paths relative to the working
+// directory.
+type sceneSaver struct {
+	file string
+	errs []error
+}
+
+func (s *sceneSaver) node(n *scene.Node, path string) scene.NodeDesc {
+	desc := scene.NodeDesc{
+		Name:       n.Name,
+		Hidden:     n.Hidden,
+		Transform:  scene.DescribeTransform(n.Local()),
+		Components: n.Components,
+		Link:       n.Prefab,
+	}
+	if o, ok := n.Object.(*RenderableObject); ok {
+		if o.Source.Path == "" {
+			s.errs = append(s.errs, &scene.SchemaError{File: s.file, Path: path, Msg: "object has no source file to save"})
+		} else {
+			desc.Mesh = s.mesh(o)
+		}
+	}
+	children := scene.FieldPath(path, "children")
+	for i, child := range n.Children() {
+		desc.Children = append(desc.Children, s.node(child, scene.IndexPath(children, i)))
+	}
+	return desc
+}
+
+func (s *sceneSaver) mesh(o *RenderableObject) *scene.MeshDesc {
+	desc := &scene.MeshDesc{Path: o.Source.Path, Object: o.Source.Object}
+	if !o.CastShadows {
+		desc.CastShadows = &o.CastShadows
+	}
+	if !o.ReceiveShadows {
+		desc.ReceiveShadows = &o.ReceiveShadows
+	}
+
+	materials := make(map[string]scene.MaterialDesc)
+	for name, file := range o.Source.Textures {
+		mat := materials[name]
+		mat.Texture = file
+		materials[name] = mat
+	}
+	for name, preset := range o.material.Sampling {
+		if preset != SamplingInherit {
+			mat := materials[name]
+			mat.Sampling = preset.String()
+			materials[name] = mat
+		}
+	}
+	if len(materials) > 0 {
+		desc.Materials = materials
+	}
+	return desc
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/scene"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSceneErrorsPointIntoPrefabs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"scenes/s.json":  `{"version": 1, "nodes": [{"prefab": "../prefabs/p.json"}]}`,
+		"prefabs/p.json": `{"version": 1, "nodes": [{"name": "p", "children": [{"mesh": {"path": "missing.obj"}}]}]}`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The steps of LoadScene that do not need a GL context.
+	path := filepath.Join(dir, "scenes", "s.json")
+	f, err := scene.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Nodes[0].MapPaths(resolveIn(filepath.Dir(path)))
+	expanded, err := scene.ExpandPrefabs(path, f.Nodes, loadPrefab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newSceneLoader(path, NewAssetManager())
+	l.nodes(expanded)
+
+	want := filepath.Join(dir, "prefabs", "p.json") + ": $.nodes[0].children[0].mesh.path: "
+	if err := l.err(); err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got %v, want it to start with %q", err, want)
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"github.com/go-gl/gl/v4.2-core/gl"
 
 	"os"
+	"strings"
 )
 
 type Shader struct {
 	Program uint32
 }
 
-func NewShader(vPath, fPath string) (*Shader, error) {
+// NewShader compiles and links a program from two source files. Each
+// define, such as "MAX_LIGHTS 16", is added to both sources as a #define
+// after the #version line.
+func NewShader(vPath, fPath string, defines ...string) (*Shader, error) {
 	vSource, err := loadSource(vPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load vertex source code: %v", err)
@@ -22,6 +26,9 @@ func NewShader(vPath, fPath string) (*Shader, error) {
 		return nil, fmt.Errorf("failed to load fragment source code: %v", err)
 	}
 
+	vSource = addDefines(vSource, defines)
+	fSource = addDefines(fSource, defines)
+
 	program, err := createProgram(vSource, fSource)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create program: %v", err)
@@ -38,6 +45,23 @@ func loadSource(path string) (string, error) {
 	return string(data), nil
 }
 
+func addDefines(source string, defines []string) string {
+	if len(defines) == 0 {
+		return source
+	}
+	var b strings.Builder
+	rest := source
+	if version, after, found := strings.Cut(source, "\n"); found && strings.HasPrefix(strings.TrimSpace(version), "#version") {
+		b.WriteString(version + "\n")
+		rest = after
+	}
+	for _, define := range defines {
+		b.WriteString("#define " + define + "\n")
+	}
+	b.WriteString(rest)
+	return b.String()
+}
+
 func createProgram(vSource, fSource string) (uint32, error) {
 	vShader := gl.CreateShader(gl.VERTEX_SHADER)
 	vVertSource, free := gl.Strs(vSource + "\x00")
@@ -0,0 +1,118 @@
+package rendering
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"strings"
+)
+
+type uniformInfo struct {
+	location int32
+	kind     uint32
+}
+
+// ShaderPass is a PostEffect made from a single fragment shader. Its
+// uniforms are found by introspection and set by name, so passes can be
+// declared in config files without Go code.
+type ShaderPass struct {
+	program  uint32
+	uniforms map[string]uniformInfo
+	values   map[string][]float32
+}
+
+func NewShaderPass(fragmentPath string) (*ShaderPass, error) {
+	shader, err := NewShader(fullscreenVertexShader, fragmentPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ShaderPass{
+		program:  shader.Program,
+		uniforms: activeUniforms(shader.Program),
+		values:   make(map[string][]float32),
+	}, nil
+}
+
+// SetUniform stores a value for the named uniform, applied on every Use.
+// Bool and int uniforms take whole numbers; vectors take one value per
+// component.
+func (p *ShaderPass) SetUniform(name string, values ...float32) error {
+	info, ok := p.uniforms[name]
+	if !ok {
+		return fmt.Errorf("shader pass has no uniform %q", name)
+	}
+	if want := uniformComponents(info.kind); want == 0 {
+		return fmt.Errorf("uniform %q has an unsupported type", name)
+	} else if len(values) != want {
+		return fmt.Errorf("uniform %q takes %d values, got %d", name, want, len(values))
+	}
+	p.values[name] = values
+	return nil
+}
+
+// Uniform returns the stored value of a uniform.
+func (p *ShaderPass) Uniform(name string) []float32 {
+	return p.values[name]
+}
+
+func (p *ShaderPass) Use() {
+	gl.UseProgram(p.program)
+	for name, v := range p.values {
+		info := p.uniforms[name]
+		switch info.kind {
+		case gl.FLOAT:
+			gl.Uniform1f(info.location, v[0])
+		case gl.FLOAT_VEC2:
+			gl.Uniform2f(info.location, v[0], v[1])
+		case gl.FLOAT_VEC3:
+			gl.Uniform3f(info.location, v[0], v[1], v[2])
+		case gl.FLOAT_VEC4:
+			gl.Uniform4f(info.location, v[0], v[1], v[2], v[3])
+		case gl.INT, gl.BOOL:
+			gl.Uniform1i(info.location, int32(v[0]))
+		case gl.INT_VEC2, gl.BOOL_VEC2:
+			gl.Uniform2i(info.location, int32(v[0]), int32(v[1]))
+		case gl.INT_VEC3, gl.BOOL_VEC3:
+			gl.Uniform3i(info.location, int32(v[0]), int32(v[1]), int32(v[2]))
+		case gl.INT_VEC4, gl.BOOL_VEC4:
+			gl.Uniform4i(info.location, int32(v[0]), int32(v[1]), int32(v[2]), int32(v[3]))
+		}
+	}
+}
+
+func (p *ShaderPass) Delete() {
+	gl.DeleteProgram(p.program)
+}
+
+func activeUniforms(program uint32) map[string]uniformInfo {
+	var count, maxLength int32
+	gl.GetProgramiv(program, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(program, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+
+	uniforms := make(map[string]uniformInfo)
+	buf := make([]uint8, maxLength+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, size int32
+		var kind uint32
+		gl.GetActiveUniform(program, i, int32(len(buf)), &length, &size, &kind, &buf[0])
+		name := strings.TrimSuffix(string(buf[:length]), "[0]")
+		location := gl.GetUniformLocation(program, gl.Str(name+"\x00"))
+		if location >= 0 {
+			uniforms[name] = uniformInfo{location: location, kind: kind}
+		}
+	}
+	return uniforms
+}
+
+func uniformComponents(kind uint32) int {
+	switch kind {
+	case gl.FLOAT, gl.INT, gl.BOOL:
+		return 1
+	case gl.FLOAT_VEC2, gl.INT_VEC2, gl.BOOL_VEC2:
+		return 2
+	case gl.FLOAT_VEC3, gl.INT_VEC3, gl.BOOL_VEC3:
+		return 3
+	case gl.FLOAT_VEC4, gl.INT_VEC4, gl.BOOL_VEC4:
+		return 4
+	}
+	return 0
+}
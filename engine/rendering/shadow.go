@@ -0,0 +1,334 @@
+package rendering
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.2-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+const (
+	DefaultShadowMapSize = 1024
+
+	// ShadowCascades is how many shadow map layers a directional light
+	// uses, each covering a slice of the camera frustum.
+	ShadowCascades = 3
+
+	// MaxShadowLayers bounds the shadow map array. Shadow casters are
+	// given layers in the order they were added; once the array is full
+	// the rest are drawn unshadowed.
+	MaxShadowLayers = 8
+
+	shadowBlockBinding = 3
+	shadowTextureUnit  = 1
+
+	// cascadeSplitLambda blends logarithmic (1) and uniform (0) cascade
+	// splits.
+	cascadeSplitLambda = 0.75
+
+	// shadowCasterDepth extends each cascade towards the light so casters
+	// outside the visible slice still block it.
+	shadowCasterDepth = 50
+)
+
+// Size in floats of the std140 ShadowBlock: a matrix and a split vec4 per
+// layer, then the camera forward vector.
+const shadowBlockFloats = MaxShadowLayers*(16+4) + 4
+
+// shadowSlot is the range of shadow layers a light renders into. first is
+// -1 for lights without shadows.
+type shadowSlot struct {
+	first, count int
+}
+
+var noShadow = shadowSlot{first: -1}
+
+// shadowView is what the shadow pass needs to know about the camera.
+type shadowView struct {
+	invViewProjection mgl32.Mat4
+	forward           mgl32.Vec3
+	near, far         float32
+
+	// distance is how far from the camera directional shadows reach.
+	distance float32
+}
+
+// shadowMaps owns the depth texture array every shadow-casting light
+// renders into, and the pass that fills it.
+type shadowMaps struct {
+	size     int
+	texture  uint32
+	fbo      uint32
+	ubo      uint32
+	program  uint32
+	debug    uint32
+	sampler  uint32
+	vao      uint32
+	matrices []mgl32.Mat4
+}
+
+func newShadowMaps(size int) (*shadowMaps, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid shadow map size %d", size)
+	}
+	shader, err := NewShader("engine/res/shaders/shadow.vert", "engine/res/shaders/shadow.frag")
+	if err != nil {
+		return nil, err
+	}
+	debug, err := NewShader(fullscreenVertexShader, "engine/res/shaders/shadowdebug.frag")
+	if err != nil {
+		shader.DeleteProgram()
+		return nil, err
+	}
+	blockIndex := gl.GetUniformBlockIndex(shader.Program, gl.Str("PerspectiveBlock\x00"))
+	gl.UniformBlockBinding(shader.Program, blockIndex, 1)
+
+	s := &shadowMaps{size: size, program: shader.Program, debug: debug.Program}
+
+	gl.GenTextures(1, &s.texture)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, s.texture)
+	gl.TexImage3D(gl.TEXTURE_2D_ARRAY, 0, gl.DEPTH_COMPONENT24, int32(size), int32(size), MaxShadowLayers, 0,
+		gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, 0)
+
+	gl.GenFramebuffers(1, &s.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, s.fbo)
+	gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, s.texture, 0, 0)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		s.Delete()
+		return nil, fmt.Errorf("shadow framebuffer incomplete: 0x%X", status)
+	}
+
+	gl.GenBuffers(1, &s.ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, s.ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, shadowBlockFloats*4, nil, gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	// The debug view reads raw depth, so its sampler turns comparison off.
+	gl.GenSamplers(1, &s.sampler)
+	gl.SamplerParameteri(s.sampler, gl.TEXTURE_COMPARE_MODE, gl.NONE)
+	gl.SamplerParameteri(s.sampler, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.SamplerParameteri(s.sampler, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.GenVertexArrays(1, &s.vao)
+	return s, nil
+}
+
+// render fills the shadow layers for lights and returns each light's slot.
+// drawables that cast shadows are drawn with the depth-only program
+// through the shared PerspectiveBlock buffer ubo.
+func (s *shadowMaps) render(lights []*Light, drawables []drawable, ubo uint32, view shadowView) []shadowSlot {
+	slots := make([]shadowSlot, len(lights))
+	s.matrices = s.matrices[:0]
+	splits := make([]float32, 0, MaxShadowLayers)
+
+	for i, l := range lights {
+		slots[i] = noShadow
+		if !l.CastShadows {
+			continue
+		}
+		switch l.Kind {
+		case LightDirectional:
+			if len(s.matrices)+ShadowCascades > MaxShadowLayers {
+				continue
+			}
+			slots[i] = shadowSlot{len(s.matrices), ShadowCascades}
+			matrices, far := cascadeMatrices(l.Direction, view, ShadowCascades, s.size)
+			s.matrices = append(s.matrices, matrices...)
+			splits = append(splits, far...)
+		case LightSpot:
+			if len(s.matrices) >= MaxShadowLayers {
+				continue
+			}
+			slots[i] = shadowSlot{len(s.matrices), 1}
+			s.matrices = append(s.matrices, spotMatrix(l, view.distance))
+			splits = append(splits, 0)
+		}
+	}
+
+	s.upload(splits, view.forward)
+	if len(s.matrices) == 0 {
+		return slots
+	}
+
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(2, 4)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, s.fbo)
+	gl.Viewport(0, 0, int32(s.size), int32(s.size))
+	gl.UseProgram(s.program)
+
+	identity := mgl32.Ident4()
+	for layer, matrix := range s.matrices {
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, s.texture, 0, int32(layer))
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+		for i := range drawables {
+			if drawables[i].castShadows {
+				drawables[i].drawDepth(ubo, matrix, identity)
+			}
+		}
+	}
+
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	if !depthTest {
+		gl.Disable(gl.DEPTH_TEST)
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return slots
+}
+
+func (s *shadowMaps) upload(splits []float32, forward mgl32.Vec3) {
+	data := make([]float32, shadowBlockFloats)
+	for i, m := range s.matrices {
+		copy(data[i*16:], m[:])
+		data[MaxShadowLayers*16+i*4] = splits[i]
+	}
+	copy(data[MaxShadowLayers*20:], forward[:])
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, s.ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(data)*4, gl.Ptr(data))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+}
+
+// bind makes the shadow maps available to the scene shader.
+func (s *shadowMaps) bind() {
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, shadowBlockBinding, s.ubo)
+	gl.ActiveTexture(gl.TEXTURE0 + shadowTextureUnit)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, s.texture)
+	gl.BindSampler(shadowTextureUnit, 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// drawDebug shows a shadow layer as grayscale depth in the bottom-left
+// third of the screen.
+func (s *shadowMaps) drawDebug(layer, screenHeight int) {
+	size := int32(screenHeight / 3)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, size, size)
+	gl.UseProgram(s.debug)
+	gl.Uniform1i(0, int32(layer))
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, s.texture)
+	gl.BindSampler(0, s.sampler)
+	gl.BindVertexArray(s.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+	gl.BindSampler(0, 0)
+}
+
+func (s *shadowMaps) Delete() {
+	gl.DeleteTextures(1, &s.texture)
+	gl.DeleteFramebuffers(1, &s.fbo)
+	gl.DeleteBuffers(1, &s.ubo)
+	gl.DeleteProgram(s.program)
+	gl.DeleteProgram(s.debug)
+	gl.DeleteSamplers(1, &s.sampler)
+	gl.DeleteVertexArrays(1, &s.vao)
+}
+
+// cascadeSplits returns the far view depth of each cascade between near
+// and far.
+func cascadeSplits(near, far float32, cascades int) []float32 {
+	splits := make([]float32, cascades)
+	for i := range splits {
+		p := float64(i+1) / float64(cascades)
+		log := float64(near) * math.Pow(float64(far/near), p)
+		uniform := float64(near) + float64(far-near)*p
+		splits[i] = float32(cascadeSplitLambda*log + (1-cascadeSplitLambda)*uniform)
+	}
+	return splits
+}
+
+// cascadeMatrices fits an orthographic light projection around each slice
+// of the camera frustum. Each slice is bounded by a sphere so the
+// projection does not change size as the camera turns, and snapped to
+// whole shadow texels so shadow edges do not shimmer as it moves.
+func cascadeMatrices(direction mgl32.Vec3, view shadowView, cascades, size int) ([]mgl32.Mat4, []float32) {
+	if direction.Len() == 0 {
+		direction = mgl32.Vec3{0, -1, 0}
+	}
+	direction = direction.Normalize()
+	up := mgl32.Vec3{0, 1, 0}
+	if math.Abs(float64(direction.Y())) > 0.99 {
+		up = mgl32.Vec3{0, 0, 1}
+	}
+
+	// Frustum corners at the near and far planes, paired along each edge.
+	var nearCorners, farCorners [4]mgl32.Vec3
+	for i, c := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		nearCorners[i] = mgl32.TransformCoordinate(mgl32.Vec3{c[0], c[1], -1}, view.invViewProjection)
+		farCorners[i] = mgl32.TransformCoordinate(mgl32.Vec3{c[0], c[1], 1}, view.invViewProjection)
+	}
+
+	far := min(view.far, view.distance)
+	splits := cascadeSplits(view.near, far, cascades)
+	matrices := make([]mgl32.Mat4, cascades)
+	sliceNear := view.near
+	for c, sliceFar := range splits {
+		var corners [8]mgl32.Vec3
+		for i := range nearCorners {
+			edge := farCorners[i].Sub(nearCorners[i])
+			tNear := (sliceNear - view.near) / (view.far - view.near)
+			tFar := (sliceFar - view.near) / (view.far - view.near)
+			corners[i] = nearCorners[i].Add(edge.Mul(tNear))
+			corners[i+4] = nearCorners[i].Add(edge.Mul(tFar))
+		}
+
+		var center mgl32.Vec3
+		for _, corner := range corners {
+			center = center.Add(corner)
+		}
+		center = center.Mul(1.0 / 8)
+		var radius float32
+		for _, corner := range corners {
+			radius = max(radius, corner.Sub(center).Len())
+		}
+		radius = float32(math.Ceil(float64(radius)*16) / 16)
+
+		// The light looks out from the centre, so the sphere spans -radius to
+		// radius in depth as well.
+		lightView := mgl32.LookAtV(center, center.Add(direction), up)
+		projection := mgl32.Ortho(-radius, radius, -radius, radius, -radius-shadowCasterDepth, radius)
+
+		// Snap the world origin to a texel so the grid stays put.
+		origin := projection.Mul4(lightView).Mul4x1(mgl32.Vec4{0, 0, 0, 1}).Mul(float32(size) / 2)
+		offsetX := (float32(math.Round(float64(origin.X()))) - origin.X()) * 2 / float32(size)
+		offsetY := (float32(math.Round(float64(origin.Y()))) - origin.Y()) * 2 / float32(size)
+		projection[12] += offsetX
+		projection[13] += offsetY
+
+		matrices[c] = projection.Mul4(lightView)
+		sliceNear = sliceFar
+	}
+	return matrices, splits
+}
+
+func spotMatrix(l *Light, distance float32) mgl32.Mat4 {
+	direction := l.Direction
+	if direction.Len() == 0 {
+		direction = mgl32.Vec3{0, -1, 0}
+	}
+	direction = direction.Normalize()
+	up := mgl32.Vec3{0, 1, 0}
+	if math.Abs(float64(direction.Y())) > 0.99 {
+		up = mgl32.Vec3{0, 0, 1}
+	}
+
+	far := distance
+	if l.Range > 0 {
+		far = l.Range
+	}
+	fov := min(max(2*max(l.OuterAngle, l.InnerAngle), 0.01), mgl32.DegToRad(170))
+	projection := mgl32.Perspective(fov, 1, 0.05, far)
+	return projection.Mul4(mgl32.LookAtV(l.Position, l.Position.Add(direction), up))
+}
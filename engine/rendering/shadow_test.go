@@ -0,0 +1,75 @@
+package rendering
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+func TestCascadeSplits(t *testing.T) {
+	for _, cascades := range []int{1, 3, 4} {
+		splits := cascadeSplits(0.1, 100, cascades)
+		if len(splits) != cascades {
+			t.Fatalf("%d cascades: got %d splits", cascades, len(splits))
+		}
+		previous := float32(0.1)
+		for i, split := range splits {
+			if split <= previous {
+				t.Errorf("%d cascades: split %d is %v after %v", cascades, i, split, previous)
+			}
+			previous = split
+		}
+		if last := splits[cascades-1]; mgl32.Abs(last-100) > 1e-3 {
+			t.Errorf("%d cascades: last split is %v", cascades, last)
+		}
+	}
+}
+
+func TestCascadeMatricesContainSlices(t *testing.T) {
+	const near, far, distance = 0.1, 200, 60
+	camera := mgl32.LookAtV(mgl32.Vec3{3, 2, 5}, mgl32.Vec3{-4, 0, -10}, mgl32.Vec3{0, 1, 0})
+	forward := mgl32.Vec3{-7, -2, -15}.Normalize()
+
+	// A narrow camera makes thin slices, whose ends reach the edge of their
+	// bounding sphere when the light shines along the view.
+	for _, fov := range []float32{60, 1} {
+		projection := mgl32.Perspective(mgl32.DegToRad(fov), 16.0/9, near, far)
+		view := shadowView{
+			invViewProjection: projection.Mul4(camera).Inv(),
+			forward:           forward,
+			near:              near,
+			far:               far,
+			distance:          distance,
+		}
+
+		for _, direction := range []mgl32.Vec3{{0.3, -1, 0.2}, {0, -1, 0}, {1, 0, 0}, forward} {
+			matrices, splits := cascadeMatrices(direction, view, ShadowCascades, 1024)
+			if len(matrices) != ShadowCascades || len(splits) != ShadowCascades {
+				t.Fatalf("got %d matrices, %d splits", len(matrices), len(splits))
+			}
+			if mgl32.Abs(splits[len(splits)-1]-distance) > 1e-3 {
+				t.Errorf("last split is %v, want the shadow distance", splits[len(splits)-1])
+			}
+
+			sliceNear := float32(near)
+			for c, sliceFar := range splits {
+				// Slice corners worked out in view space, away from the
+				// inverse view-projection cascadeMatrices uses.
+				for _, depth := range []float32{sliceNear, sliceFar} {
+					for _, ndc := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+						viewPoint := mgl32.Vec3{ndc[0] * depth / projection[0], ndc[1] * depth / projection[5], -depth}
+						world := mgl32.TransformCoordinate(viewPoint, camera.Inv())
+
+						p := mgl32.TransformCoordinate(world, matrices[c])
+						for axis := 0; axis < 3; axis++ {
+							if p[axis] < -1.0001 || p[axis] > 1.0001 {
+								t.Errorf("fov %v direction %v cascade %d: corner %v at depth %v lands at %v", fov, direction, c, ndc, depth, p)
+								break
+							}
+						}
+					}
+				}
+				sliceNear = sliceFar
+			}
+		}
+	}
+}
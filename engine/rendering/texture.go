@@ -0,0 +1,57 @@
+package rendering
+
+import (
+	"3DPixelGameEngine/engine/texture"
+	"github.com/go-gl/gl/v4.2-core/gl"
+)
+
+// TextureOptions are the sampler parameters a texture is created with.
+// Anisotropy above 1 enables anisotropic filtering, clamped to the
+// driver's limit. Samplers bound by the renderer override them while
+// drawing; see SamplingPreset.
+type TextureOptions struct {
+	WrapS      int32
+	WrapT      int32
+	MinFilter  int32
+	MagFilter  int32
+	Anisotropy float32
+}
+
+// DefaultTextureOptions give crisp, repeating pixel-art sampling.
+var DefaultTextureOptions = SamplingPixelArt.TextureOptions(gl.REPEAT)
+
+type Texture struct {
+	ID     uint32
+	Width  int
+	Height int
+}
+
+// NewTexture uploads img to a new GL texture.
+func NewTexture(img *texture.Image, opts TextureOptions) *Texture {
+	var id uint32
+	gl.GenTextures(1, &id)
+	gl.BindTexture(gl.TEXTURE_2D, id)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(img.Width), int32(img.Height), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, opts.WrapS)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, opts.WrapT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, opts.MinFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, opts.MagFilter)
+	if opts.Anisotropy > 1 {
+		gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY, supportedAnisotropy(opts.Anisotropy))
+	}
+
+	// Mipmaps are always built so any preset can be switched to later.
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return &Texture{ID: id, Width: img.Width, Height: img.Height}
+}
+
+func (t *Texture) Delete() {
+	gl.DeleteTextures(1, &t.ID)
+	t.ID = 0
+}
@@ -0,0 +1,10 @@
+#version 420
+
+layout(location = 0) out vec2 TexCoord;
+
+// One triangle covering the viewport, generated from gl_VertexID.
+void main() {
+    vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+    TexCoord = pos;
+    gl_Position = vec4(pos * 2.0 - 1.0, 0.0, 1.0);
+}
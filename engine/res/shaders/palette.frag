@@ -0,0 +1,38 @@
+#version 420
+#extension GL_ARB_explicit_uniform_location : enable
+
+layout (location = 0) out vec4 frag_colour;
+
+layout(location = 0) in vec2 TexCoord;
+
+layout(binding = 0) uniform sampler2D scene;
+layout(binding = 2) uniform sampler2D palette;
+layout(binding = 3) uniform sampler2D thresholds;
+
+layout(location = 0) uniform int paletteSize;
+layout(location = 1) uniform float strength;
+
+// Must match palette.Palette.Map.
+void main() {
+    ivec2 size = textureSize(scene, 0);
+    ivec2 pixel = min(ivec2(TexCoord * vec2(size)), size - 1);
+    vec4 colour = texelFetch(scene, pixel, 0);
+
+    // Threshold maps are indexed top-down like images on the CPU.
+    ivec2 mapSize = textureSize(thresholds, 0);
+    ivec2 cell = ivec2(pixel.x, size.y - 1 - pixel.y) % mapSize;
+    vec3 rgb = colour.rgb + (texelFetch(thresholds, cell, 0).r - 0.5) * strength;
+
+    vec3 best = texelFetch(palette, ivec2(0, 0), 0).rgb;
+    float bestDist = dot(rgb - best, rgb - best);
+    for (int i = 1; i < paletteSize; i++) {
+        vec3 c = texelFetch(palette, ivec2(i, 0), 0).rgb;
+        vec3 d = rgb - c;
+        float dist = dot(d, d);
+        if (dist < bestDist) {
+            best = c;
+            bestDist = dist;
+        }
+    }
+    frag_colour = vec4(best, colour.a);
+}
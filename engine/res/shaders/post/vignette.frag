@@ -0,0 +1,18 @@
+#version 420
+
+layout (location = 0) out vec4 frag_colour;
+
+layout(location = 0) in vec2 TexCoord;
+
+layout(binding = 0) uniform sampler2D scene;
+
+uniform float radius;
+uniform float softness;
+uniform vec3 tint;
+
+void main() {
+    ivec2 size = textureSize(scene, 0);
+    vec4 colour = texelFetch(scene, min(ivec2(TexCoord * vec2(size)), size - 1), 0);
+    float shade = smoothstep(radius, radius - softness, length(TexCoord - 0.5) * 1.414);
+    frag_colour = vec4(mix(tint, colour.rgb, shade), colour.a);
+}
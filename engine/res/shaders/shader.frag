@@ -1,12 +1,145 @@
 #version 420
 #extension GL_ARB_explicit_uniform_location : enable
 
+#ifndef MAX_LIGHTS
+#define MAX_LIGHTS 16
+#endif
+
+#ifndef MAX_SHADOW_LAYERS
+#define MAX_SHADOW_LAYERS 8
+#endif
+
+#define LIGHT_DIRECTIONAL 0
+#define LIGHT_POINT 1
+#define LIGHT_SPOT 2
+
 layout (location = 0) out vec4 frag_colour;
 
 layout(location = 0) in vec2 TexCoord;
+layout(location = 1) in vec3 FragPos;
+layout(location = 2) in vec3 Normal;
+
+layout(binding = 0) uniform sampler2D texture1;
+layout(binding = 1) uniform sampler2DArrayShadow shadowMap;
+
+layout(location = 0) uniform vec4 diffuseColor;
+layout(location = 1) uniform bool hasDiffuseMap;
+layout(location = 2) uniform vec3 emission;
+layout(location = 3) uniform vec3 ambientColor;
+layout(location = 4) uniform vec3 specularColor;
+layout(location = 5) uniform float shininess;
+layout(location = 6) uniform bool receiveShadows;
+
+struct Light {
+    vec4 position;  // xyz, w = kind
+    vec4 direction; // xyz, w = range
+    vec4 colour;    // rgb premultiplied by intensity
+    vec4 cone;      // x = cos inner, y = cos outer, z = first shadow layer, w = layer count
+};
+
+layout(std140, binding = 2) uniform LightBlock {
+    vec4 ambientLight;
+    vec4 viewPosition;
+    int lightCount;
+    Light lights[MAX_LIGHTS];
+};
+
+layout(std140, binding = 3) uniform ShadowBlock {
+    mat4 shadowMatrices[MAX_SHADOW_LAYERS];
+    vec4 shadowSplits[MAX_SHADOW_LAYERS]; // x = far view depth of a cascade
+    vec4 viewForward;
+};
+
+// 3x3 PCF over hardware-compared taps.
+float shadowFactor(int layer, vec3 N, vec3 L) {
+    vec4 pos = shadowMatrices[layer] * vec4(FragPos, 1.0);
+    vec3 coord = pos.xyz / pos.w * 0.5 + 0.5;
+    if (coord.z > 1.0 || any(lessThan(coord.xy, vec2(0.0))) || any(greaterThan(coord.xy, vec2(1.0)))) {
+        return 1.0;
+    }
+
+    float bias = max(0.005 * (1.0 - dot(N, L)), 0.001);
+    vec2 texel = 1.0 / vec2(textureSize(shadowMap, 0).xy);
+    float lit = 0.0;
+    for (int y = -1; y <= 1; y++) {
+        for (int x = -1; x <= 1; x++) {
+            lit += texture(shadowMap, vec4(coord.xy + vec2(x, y) * texel, float(layer), coord.z - bias));
+        }
+    }
+    return lit / 9.0;
+}
 
-uniform sampler2D texture1;
+// shadowLayer picks the layer covering this fragment, or -1.
+int shadowLayer(Light light, int kind) {
+    int first = int(light.cone.z);
+    int count = int(light.cone.w);
+    if (!receiveShadows || first < 0) {
+        return -1;
+    }
+    if (kind != LIGHT_DIRECTIONAL) {
+        return first;
+    }
+
+    float depth = dot(FragPos - viewPosition.xyz, viewForward.xyz);
+    for (int c = 0; c < count; c++) {
+        if (depth < shadowSplits[first + c].x) {
+            return first + c;
+        }
+    }
+    return -1;
+}
 
 void main() {
-    frag_colour = texture(texture1, TexCoord);
+    vec4 colour = diffuseColor;
+    if (hasDiffuseMap) {
+        colour = vec4(texture(texture1, TexCoord).rgb, texture(texture1, TexCoord).a * diffuseColor.a);
+    }
+
+    vec3 N = normalize(Normal);
+    vec3 V = normalize(viewPosition.xyz - FragPos);
+    vec3 lit = ambientLight.rgb * ambientColor * colour.rgb;
+
+    for (int i = 0; i < lightCount; i++) {
+        Light light = lights[i];
+        int kind = int(light.position.w);
+
+        vec3 L;
+        float attenuation = 1.0;
+        if (kind == LIGHT_DIRECTIONAL) {
+            L = -light.direction.xyz;
+        } else {
+            vec3 toLight = light.position.xyz - FragPos;
+            float dist = length(toLight);
+            L = toLight / max(dist, 1e-4);
+            attenuation = 1.0 / (1.0 + dist * dist);
+            float range = light.direction.w;
+            if (range > 0.0) {
+                float falloff = clamp(1.0 - pow(dist / range, 4.0), 0.0, 1.0);
+                attenuation *= falloff * falloff;
+            }
+            if (kind == LIGHT_SPOT) {
+                float cosAngle = dot(-L, light.direction.xyz);
+                attenuation *= smoothstep(light.cone.y, light.cone.x, cosAngle);
+            }
+        }
+
+        float NdotL = max(dot(N, L), 0.0);
+        if (NdotL <= 0.0 || attenuation <= 0.0) {
+            continue;
+        }
+
+        int layer = shadowLayer(light, kind);
+        if (layer >= 0) {
+            attenuation *= shadowFactor(layer, N, L);
+        }
+
+        // Blinn-Phong: specular from the half vector, shininess from Ns.
+        vec3 H = normalize(L + V);
+        float spec = pow(max(dot(N, H), 0.0), max(shininess, 1.0));
+
+        vec3 radiance = light.colour.rgb * attenuation;
+        lit += radiance * (colour.rgb * NdotL + specularColor * spec);
+    }
+
+    frag_colour = vec4(lit + emission, colour.a);
 }
@@ -5,8 +5,11 @@
 layout(location = 0) in vec3 vp;
 layout(location = 1) in vec2 aTexCoord;
 layout(location = 2) in vec3 aNormal;
+layout(location = 3) in vec4 aTangent;
 
 layout(location = 0) out vec2 TexCoord;
+layout(location = 1) out vec3 FragPos;
+layout(location = 2) out vec3 Normal;
 
 layout(binding = 1) uniform PerspectiveBlock {
     mat4 project;
@@ -15,6 +18,9 @@ layout(binding = 1) uniform PerspectiveBlock {
 };
 
 void main() {
-    gl_Position = project * camera * model * vec4(vp, 1);
+    vec4 world = model * vec4(vp, 1);
+    gl_Position = project * camera * world;
     TexCoord = aTexCoord;
-}
\ No newline at end of file
+    FragPos = world.xyz;
+    Normal = mat3(transpose(inverse(model))) * aNormal;
+}
@@ -0,0 +1,5 @@
+#version 420
+
+// Depth only; the shadow pass has no color attachment.
+void main() {
+}
@@ -0,0 +1,13 @@
+#version 420
+
+layout(location = 0) in vec3 vp;
+
+layout(binding = 1) uniform PerspectiveBlock {
+    mat4 project;
+    mat4 camera;
+    mat4 model;
+};
+
+void main() {
+    gl_Position = project * camera * model * vec4(vp, 1);
+}
@@ -0,0 +1,15 @@
+#version 420
+#extension GL_ARB_explicit_uniform_location : enable
+
+layout (location = 0) out vec4 frag_colour;
+
+layout(location = 0) in vec2 TexCoord;
+
+layout(binding = 0) uniform sampler2DArray shadowMap;
+
+layout(location = 0) uniform int layer;
+
+void main() {
+    float depth = texture(shadowMap, vec3(TexCoord, layer)).r;
+    frag_colour = vec4(vec3(depth), 1.0);
+}
@@ -0,0 +1,632 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FileVersion is the scene file format version written by Encode and the
+// only one Decode accepts.
+const FileVersion = 1
+
+// File is a scene as stored on disk in JSON:
+//
+//	{"version": 1,
+//	 "camera": {"position": [0, 0, 3], "yaw": -90, "fov": 60},
+//	 "lights": [{"type": "directional", "direction": [-0.4, -1, -0.6], "castShadows": true}],
+//	 "nodes": [
+//		{"name": "crate", "transform": {"position": [2, 0, 0]},
+//		 "mesh": {"path": "../models/crate.obj",
+//		          "materials": {"wood": {"texture": "wood.png", "sampling": "trilinear"}}},
+//		 "components": {"health": 100},
+//		 "children": []}
+//	 ]}
+//
+// Asset paths are relative to the directory of the file.
+type File struct {
+	Version int         `json:"version"`
+	Camera  *CameraDesc `json:"camera,omitempty"`
+	Lights  []LightDesc `json:"lights,omitempty"`
+	Nodes   []NodeDesc  `json:"nodes,omitempty"`
+}
+
+type CameraDesc struct {
+	Position [3]float64 `json:"position"`
+	Yaw      float64    `json:"yaw"`
+	Pitch    float64    `json:"pitch"`
+	Fov      float32    `json:"fov,omitempty"`
+}
+
+// Light types of LightDesc.Type.
+const (
+	LightDirectional = "directional"
+	LightPoint       = "point"
+	LightSpot        = "spot"
+)
+
+// LightDesc describes a light. Angles are half-angles in degrees. Color
+// and Intensity default to white and 1 when left out.
+type LightDesc struct {
+	Type        string     `json:"type"`
+	Color       [3]float32 `json:"color"`
+	Intensity   float32    `json:"intensity"`
+	Position    [3]float32 `json:"position"`
+	Direction   [3]float32 `json:"direction"`
+	Range       float32    `json:"range,omitempty"`
+	InnerAngle  float32    `json:"innerAngle,omitempty"`
+	OuterAngle  float32    `json:"outerAngle,omitempty"`
+	CastShadows bool       `json:"castShadows,omitempty"`
+}
+
+type NodeDesc struct {
+	Name      string         `json:"name,omitempty"`
+	Hidden    bool           `json:"hidden,omitempty"`
+	Transform *TransformDesc `json:"transform,omitempty"`
+	Mesh      *MeshDesc      `json:"mesh,omitempty"`
+
+	// Components holds game data by component name, left as raw JSON for
+	// the game to decode.
+	Components map[string]json.RawMessage `json:"components,omitempty"`
+
+	Children []NodeDesc `json:"children,omitempty"`
+
+	// Prefab makes the node an instance of the prefab file at that path,
+	// changed by Overrides. Instances have no mesh, components or
+	// children of their own; see NodeOverride.
+	Prefab    string                  `json:"prefab,omitempty"`
+	Overrides map[string]NodeOverride `json:"overrides,omitempty"`
+
+	// Link is set on instance roots by ExpandPrefabs.
+	Link *PrefabLink `json:"-"`
+
+	// Origin is set by ExpandPrefabs to where the node was declared, which
+	// for nodes copied from a prefab is the prefab file.
+	Origin *Origin `json:"-"`
+}
+
+// Origin locates a node or mesh description in the file it was read from,
+// so that errors found after prefabs are expanded can point at it.
+type Origin struct {
+	File string
+	Path string
+}
+
+// TransformDesc is a Transform with the rotation stored as a quaternion
+// x, y, z, w. Fields left out default to the identity.
+type TransformDesc struct {
+	Position [3]float32 `json:"position"`
+	Rotation [4]float32 `json:"rotation"`
+	Scale    [3]float32 `json:"scale"`
+}
+
+// DescribeTransform returns the stored form of t, or nil for the
+// identity.
+func DescribeTransform(t Transform) *TransformDesc {
+	if t == Identity() {
+		return nil
+	}
+	return &TransformDesc{
+		Position: t.Position,
+		Rotation: [4]float32{t.Rotation.V[0], t.Rotation.V[1], t.Rotation.V[2], t.Rotation.W},
+		Scale:    t.Scale,
+	}
+}
+
+// Transform returns the described transform. A nil description is the
+// identity.
+func (d *TransformDesc) Transform() Transform {
+	if d == nil {
+		return Identity()
+	}
+	return Transform{
+		Position: d.Position,
+		Rotation: mgl32.Quat{W: d.Rotation[3], V: mgl32.Vec3{d.Rotation[0], d.Rotation[1], d.Rotation[2]}},
+		Scale:    d.Scale,
+	}
+}
+
+// MeshDesc refers to a model file, or with Object to one object or group
+// in it, and overrides its materials by name. Repeated object names are
+// told apart as child nodes are, "default#1" being the second "default".
+type MeshDesc struct {
+	Path           string                  `json:"path"`
+	Object         string                  `json:"object,omitempty"`
+	CastShadows    *bool                   `json:"castShadows,omitempty"`
+	ReceiveShadows *bool                   `json:"receiveShadows,omitempty"`
+	Materials      map[string]MaterialDesc `json:"materials,omitempty"`
+
+	// Origin is set by ExpandPrefabs, as for NodeDesc. A mesh replaced by
+	// an override points at the override.
+	Origin *Origin `json:"-"`
+}
+
+// MaterialDesc replaces a material's diffuse texture and sampling preset.
+// Empty fields keep the model's own.
+type MaterialDesc struct {
+	Texture  string `json:"texture,omitempty"`
+	Sampling string `json:"sampling,omitempty"`
+}
+
+// SchemaError reports a value that does not fit the scene format. Path
+// locates it in the document, as in $.nodes[0].children[2].mesh.path.
+type SchemaError struct {
+	File string
+	Path string
+	Msg  string
+}
+
+func (e *SchemaError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Path, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FieldPath appends an object key to a JSON path.
+func FieldPath(path, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+	b, _ := json.Marshal(key)
+	return path + "[" + string(b) + "]"
+}
+
+// IndexPath appends an array index to a JSON path.
+func IndexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// Decode reads a scene file. Every value that does not fit the format is
+// reported, each as a *SchemaError, joined into one error.
+func Decode(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFile(data, "")
+}
+
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFile(data, path)
+}
+
+func Encode(w io.Writer, f *File) error {
+	out := *f
+	if out.Version == 0 {
+		out.Version = FileVersion
+	}
+	data, err := json.MarshalIndent(&out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func WriteFile(path string, f *File) error {
+	var buf bytes.Buffer
+	if err := Encode(&buf, f); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func decodeFile(data []byte, file string) (*File, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			line, col := position(data, syntax.Offset)
+			err = fmt.Errorf("%d:%d: %w", line, col, err)
+		}
+		if file != "" {
+			err = fmt.Errorf("%s: %w", file, err)
+		}
+		return nil, err
+	}
+
+	d := &fileDecoder{file: file}
+	f := d.document(doc, "$")
+	if len(d.errs) > 0 {
+		return nil, errors.Join(d.errs...)
+	}
+	return f, nil
+}
+
+// position converts the offset of a json.SyntaxError, which counts the
+// offending byte, into its 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	before := data[:min(max(int(offset)-1, 0), len(data))]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// fileDecoder converts the generic JSON document into a File, recording a
+// SchemaError for each value that does not fit and carrying on with a
+// zero value.
+type fileDecoder struct {
+	file string
+	errs []error
+}
+
+func (d *fileDecoder) errorf(path, format string, args ...any) {
+	d.errs = append(d.errs, &SchemaError{File: d.file, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a bool"
+	}
+	return "null"
+}
+
+// object returns v as an object, reporting keys other than the given ones.
+func (d *fileDecoder) object(v any, path string, keys ...string) map[string]any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.errorf(path, "expected an object, got %s", jsonKind(v))
+		return nil
+	}
+	for _, k := range sortedKeys(m) {
+		if !slices.Contains(keys, k) {
+			d.errorf(FieldPath(path, k), "unknown field")
+		}
+	}
+	return m
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (d *fileDecoder) array(v any, path string) []any {
+	a, ok := v.([]any)
+	if !ok {
+		d.errorf(path, "expected an array, got %s", jsonKind(v))
+	}
+	return a
+}
+
+func (d *fileDecoder) str(v any, path string) string {
+	s, ok := v.(string)
+	if !ok {
+		d.errorf(path, "expected a string, got %s", jsonKind(v))
+	}
+	return s
+}
+
+func (d *fileDecoder) boolean(v any, path string) bool {
+	b, ok := v.(bool)
+	if !ok {
+		d.errorf(path, "expected a bool, got %s", jsonKind(v))
+	}
+	return b
+}
+
+func (d *fileDecoder) number(v any, path string) float64 {
+	n, ok := v.(json.Number)
+	if !ok {
+		d.errorf(path, "expected a number, got %s", jsonKind(v))
+		return 0
+	}
+	f, err := n.Float64()
+	if err != nil {
+		d.errorf(path, "%v", err)
+	}
+	return f
+}
+
+// numbers reads an array of exactly n numbers.
+func (d *fileDecoder) numbers(v any, path string, n int) []float64 {
+	a, ok := v.([]any)
+	if !ok || len(a) != n {
+		d.errorf(path, "expected an array of %d numbers", n)
+		return make([]float64, n)
+	}
+	values := make([]float64, n)
+	for i, item := range a {
+		values[i] = d.number(item, IndexPath(path, i))
+	}
+	return values
+}
+
+func (d *fileDecoder) floats(v any, path string, dst []float32) {
+	for i, f := range d.numbers(v, path, len(dst)) {
+		dst[i] = float32(f)
+	}
+}
+
+// dictionary returns v as an object with arbitrary keys, such as a map
+// of names.
+func (d *fileDecoder) dictionary(v any, path string) map[string]any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		d.errorf(path, "expected an object, got %s", jsonKind(v))
+	}
+	return m
+}
+
+func (d *fileDecoder) document(v any, path string) *File {
+	m := d.object(v, path, "version", "camera", "lights", "nodes")
+	f := &File{}
+	if m == nil {
+		return f
+	}
+
+	if version, ok := m["version"]; !ok {
+		d.errorf(path, "missing version")
+	} else if f.Version = int(d.number(version, FieldPath(path, "version"))); f.Version != FileVersion {
+		d.errorf(FieldPath(path, "version"), "unsupported version %d, want %d", f.Version, FileVersion)
+	}
+	if camera, ok := m["camera"]; ok {
+		f.Camera = d.camera(camera, FieldPath(path, "camera"))
+	}
+	if lights, ok := m["lights"]; ok {
+		p := FieldPath(path, "lights")
+		for i, light := range d.array(lights, p) {
+			f.Lights = append(f.Lights, d.light(light, IndexPath(p, i)))
+		}
+	}
+	if nodes, ok := m["nodes"]; ok {
+		f.Nodes = d.nodes(nodes, FieldPath(path, "nodes"))
+	}
+	return f
+}
+
+func (d *fileDecoder) camera(v any, path string) *CameraDesc {
+	m := d.object(v, path, "position", "yaw", "pitch", "fov")
+	c := &CameraDesc{}
+	if position, ok := m["position"]; ok {
+		copy(c.Position[:], d.numbers(position, FieldPath(path, "position"), 3))
+	}
+	if yaw, ok := m["yaw"]; ok {
+		c.Yaw = d.number(yaw, FieldPath(path, "yaw"))
+	}
+	if pitch, ok := m["pitch"]; ok {
+		c.Pitch = d.number(pitch, FieldPath(path, "pitch"))
+	}
+	if fov, ok := m["fov"]; ok {
+		c.Fov = float32(d.number(fov, FieldPath(path, "fov")))
+	}
+	return c
+}
+
+func (d *fileDecoder) light(v any, path string) LightDesc {
+	m := d.object(v, path, "type", "color", "intensity", "position", "direction",
+		"range", "innerAngle", "outerAngle", "castShadows")
+	l := LightDesc{Color: [3]float32{1, 1, 1}, Intensity: 1}
+	if m == nil {
+		return l
+	}
+
+	if t, ok := m["type"]; !ok {
+		d.errorf(path, "missing type")
+	} else {
+		l.Type = d.str(t, FieldPath(path, "type"))
+		switch l.Type {
+		case LightDirectional, LightPoint, LightSpot:
+		default:
+			d.errorf(FieldPath(path, "type"), "unknown light type %q", l.Type)
+		}
+	}
+	vectors := []struct {
+		key string
+		dst []float32
+	}{{"color", l.Color[:]}, {"position", l.Position[:]}, {"direction", l.Direction[:]}}
+	for _, v := range vectors {
+		if value, ok := m[v.key]; ok {
+			d.floats(value, FieldPath(path, v.key), v.dst)
+		}
+	}
+	scalars := []struct {
+		key string
+		dst *float32
+	}{{"intensity", &l.Intensity}, {"range", &l.Range}, {"innerAngle", &l.InnerAngle}, {"outerAngle", &l.OuterAngle}}
+	for _, v := range scalars {
+		if value, ok := m[v.key]; ok {
+			*v.dst = float32(d.number(value, FieldPath(path, v.key)))
+		}
+	}
+	if cast, ok := m["castShadows"]; ok {
+		l.CastShadows = d.boolean(cast, FieldPath(path, "castShadows"))
+	}
+
+	if (l.Type == LightDirectional || l.Type == LightSpot) && l.Direction == [3]float32{} {
+		d.errorf(path, "%s light needs a non-zero direction", l.Type)
+	}
+	return l
+}
+
+func (d *fileDecoder) nodes(v any, path string) []NodeDesc {
+	var nodes []NodeDesc
+	for i, node := range d.array(v, path) {
+		nodes = append(nodes, d.node(node, IndexPath(path, i)))
+	}
+	return nodes
+}
+
+func (d *fileDecoder) node(v any, path string) NodeDesc {
+	m := d.object(v, path, "name", "hidden", "transform", "mesh", "components", "children", "prefab", "overrides")
+	var n NodeDesc
+	if name, ok := m["name"]; ok {
+		n.Name = d.str(name, FieldPath(path, "name"))
+	}
+	if hidden, ok := m["hidden"]; ok {
+		n.Hidden = d.boolean(hidden, FieldPath(path, "hidden"))
+	}
+	if transform, ok := m["transform"]; ok {
+		n.Transform = d.transform(transform, FieldPath(path, "transform"))
+	}
+	if mesh, ok := m["mesh"]; ok {
+		n.Mesh = d.mesh(mesh, FieldPath(path, "mesh"))
+	}
+	if components, ok := m["components"]; ok {
+		n.Components = d.components(components, FieldPath(path, "components"))
+	}
+	if children, ok := m["children"]; ok {
+		n.Children = d.nodes(children, FieldPath(path, "children"))
+	}
+
+	if prefab, ok := m["prefab"]; ok {
+		n.Prefab = d.str(prefab, FieldPath(path, "prefab"))
+		if strings.TrimSpace(n.Prefab) == "" {
+			d.errorf(FieldPath(path, "prefab"), "missing prefab path")
+		}
+		for _, key := range []string{"mesh", "components", "children"} {
+			if _, ok := m[key]; ok {
+				d.errorf(FieldPath(path, key), "prefab instances take %s through overrides", key)
+			}
+		}
+	}
+	if overrides, ok := m["overrides"]; ok {
+		p := FieldPath(path, "overrides")
+		if n.Prefab == "" {
+			d.errorf(p, "overrides need a prefab")
+		}
+		om := d.dictionary(overrides, p)
+		for _, key := range sortedKeys(om) {
+			if n.Overrides == nil {
+				n.Overrides = make(map[string]NodeOverride)
+			}
+			n.Overrides[key] = d.override(om[key], FieldPath(p, key))
+		}
+	}
+	return n
+}
+
+func (d *fileDecoder) override(v any, path string) NodeOverride {
+	m := d.object(v, path, "hidden", "transform", "mesh", "components", "children", "removed")
+	var o NodeOverride
+	if hidden, ok := m["hidden"]; ok {
+		h := d.boolean(hidden, FieldPath(path, "hidden"))
+		o.Hidden = &h
+	}
+	if transform, ok := m["transform"]; ok {
+		o.Transform = d.transform(transform, FieldPath(path, "transform"))
+	}
+	if mesh, ok := m["mesh"]; ok {
+		o.Mesh = d.mesh(mesh, FieldPath(path, "mesh"))
+	}
+	if components, ok := m["components"]; ok {
+		o.Components = d.components(components, FieldPath(path, "components"))
+	}
+	if children, ok := m["children"]; ok {
+		o.Children = d.nodes(children, FieldPath(path, "children"))
+	}
+	if removed, ok := m["removed"]; ok {
+		o.Removed = d.boolean(removed, FieldPath(path, "removed"))
+	}
+	return o
+}
+
+func (d *fileDecoder) components(v any, path string) map[string]json.RawMessage {
+	var components map[string]json.RawMessage
+	cm := d.dictionary(v, path)
+	for _, name := range sortedKeys(cm) {
+		raw, err := json.Marshal(cm[name])
+		if err != nil {
+			d.errorf(FieldPath(path, name), "%v", err)
+			continue
+		}
+		if components == nil {
+			components = make(map[string]json.RawMessage)
+		}
+		components[name] = raw
+	}
+	return components
+}
+
+func (d *fileDecoder) transform(v any, path string) *TransformDesc {
+	m := d.object(v, path, "position", "rotation", "scale")
+	t := &TransformDesc{Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}}
+	if position, ok := m["position"]; ok {
+		d.floats(position, FieldPath(path, "position"), t.Position[:])
+	}
+	if rotation, ok := m["rotation"]; ok {
+		d.floats(rotation, FieldPath(path, "rotation"), t.Rotation[:])
+		if t.Rotation == [4]float32{} {
+			d.errorf(FieldPath(path, "rotation"), "quaternion must not be zero")
+		}
+	}
+	if scale, ok := m["scale"]; ok {
+		d.floats(scale, FieldPath(path, "scale"), t.Scale[:])
+	}
+	return t
+}
+
+func (d *fileDecoder) mesh(v any, path string) *MeshDesc {
+	m := d.object(v, path, "path", "object", "castShadows", "receiveShadows", "materials")
+	mesh := &MeshDesc{}
+	if m == nil {
+		return mesh
+	}
+
+	if p, ok := m["path"]; ok {
+		mesh.Path = d.str(p, FieldPath(path, "path"))
+	}
+	if strings.TrimSpace(mesh.Path) == "" {
+		d.errorf(FieldPath(path, "path"), "missing model path")
+	}
+	if object, ok := m["object"]; ok {
+		mesh.Object = d.str(object, FieldPath(path, "object"))
+	}
+	if cast, ok := m["castShadows"]; ok {
+		b := d.boolean(cast, FieldPath(path, "castShadows"))
+		mesh.CastShadows = &b
+	}
+	if receive, ok := m["receiveShadows"]; ok {
+		b := d.boolean(receive, FieldPath(path, "receiveShadows"))
+		mesh.ReceiveShadows = &b
+	}
+	if materials, ok := m["materials"]; ok {
+		p := FieldPath(path, "materials")
+		mm := d.dictionary(materials, p)
+		for _, name := range sortedKeys(mm) {
+			mp := FieldPath(p, name)
+			fields := d.object(mm[name], mp, "texture", "sampling")
+			var mat MaterialDesc
+			if texture, ok := fields["texture"]; ok {
+				mat.Texture = d.str(texture, FieldPath(mp, "texture"))
+			}
+			if sampling, ok := fields["sampling"]; ok {
+				mat.Sampling = d.str(sampling, FieldPath(mp, "sampling"))
+			}
+			if mesh.Materials == nil {
+				mesh.Materials = make(map[string]MaterialDesc)
+			}
+			mesh.Materials[name] = mat
+		}
+	}
+	return mesh
+}
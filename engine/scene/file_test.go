@@ -0,0 +1,143 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-gl/mathgl/mgl32"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	no := false
+	in := &File{
+		Camera: &CameraDesc{Position: [3]float64{0, 1.5, 3}, Yaw: -90, Fov: 60},
+		Lights: []LightDesc{
+			{Type: LightDirectional, Color: [3]float32{1, 0.9, 0.8}, Intensity: 1, Direction: [3]float32{0, -1, 0}, CastShadows: true},
+			{Type: LightSpot, Color: [3]float32{1, 1, 1}, Intensity: 2, Direction: [3]float32{0, 0, -1}, InnerAngle: 20, OuterAngle: 30},
+		},
+		Nodes: []NodeDesc{{
+			Name:       "crate",
+			Transform:  DescribeTransform(Transform{Position: mgl32.Vec3{1, 2, 3}, Rotation: mgl32.QuatRotate(0.5, mgl32.Vec3{0, 1, 0}), Scale: mgl32.Vec3{2, 2, 2}}),
+			Components: map[string]json.RawMessage{"health": json.RawMessage(`{"max":100}`)},
+			Children: []NodeDesc{{
+				Name:   "lid",
+				Hidden: true,
+				Mesh: &MeshDesc{
+					Path:        "models/crate.obj",
+					Object:      "Lid",
+					CastShadows: &no,
+					Materials:   map[string]MaterialDesc{"wood": {Texture: "wood.png", Sampling: "trilinear"}},
+				},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.Version = FileVersion
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	if DescribeTransform(Identity()) != nil {
+		t.Error("identity transform was not left out")
+	}
+	if got := out.Nodes[0].Transform.Transform(); !near(got.Position, mgl32.Vec3{1, 2, 3}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFileDefaults(t *testing.T) {
+	f, err := Decode(strings.NewReader(`{"version": 1,
+		"lights": [{"type": "point"}],
+		"nodes": [{"transform": {"position": [1, 0, 0]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := f.Lights[0]; l.Color != [3]float32{1, 1, 1} || l.Intensity != 1 {
+		t.Errorf("got light %+v", l)
+	}
+	if got := f.Nodes[0].Transform.Transform(); got.Scale != (mgl32.Vec3{1, 1, 1}) || got.Rotation != mgl32.QuatIdent() {
+		t.Errorf("got transform %+v", got)
+	}
+}
+
+func TestFileSchemaErrors(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"version": 1,
+		"lights": [{"type": "sun"}, {"type": "spot"}],
+		"nodes": [{"name": "a", "children": [
+			{"transform": {"scale": [1, 1]}},
+			{"mesh": {"object": "x"}, "colour": "red"},
+			{"components": {"my data": 1}, "hidden": "yes"}
+		]}]}`))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+
+	want := []string{
+		`$.lights[0].type: unknown light type "sun"`,
+		`$.lights[1]: spot light needs a non-zero direction`,
+		`$.nodes[0].children[0].transform.scale: expected an array of 3 numbers`,
+		`$.nodes[0].children[1].colour: unknown field`,
+		`$.nodes[0].children[1].mesh.path: missing model path`,
+		`$.nodes[0].children[2].hidden: expected a bool, got a string`,
+	}
+	if got := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	var schema *SchemaError
+	if !errors.As(err, &schema) {
+		t.Error("errors are not SchemaErrors")
+	}
+
+	if _, err := Decode(strings.NewReader(`{"nodes": []}`)); err == nil || !strings.Contains(err.Error(), "missing version") {
+		t.Errorf("got %v", err)
+	}
+	if _, err := Decode(strings.NewReader("{\"version\": 1,\n  \"nodes\": [}")); err == nil || !strings.HasPrefix(err.Error(), "2:13:") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	if got := FieldPath("$.components", "my data"); got != `$.components["my data"]` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestReadResourceScenes(t *testing.T) {
+	f, err := ReadFile("../res/scenes/default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Nodes) == 0 || len(f.Lights) == 0 || f.Camera == nil {
+		t.Errorf("got %+v", f)
+	}
+
+	resolve := func(dir string) func(string) string {
+		return func(p string) string { return filepath.Join(dir, p) }
+	}
+	for i := range f.Nodes {
+		f.Nodes[i].MapPaths(resolve("../res/scenes"))
+	}
+	nodes, err := ExpandPrefabs("default.json", f.Nodes, func(path string) (NodeDesc, error) {
+		root, err := ReadPrefab(path)
+		root.MapPaths(resolve(filepath.Dir(path)))
+		return root, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh := nodes[0].Children[0].Mesh; mesh == nil || mesh.Path != filepath.FromSlash("../res/models/cube.obj") {
+		t.Errorf("got %+v", nodes[0])
+	}
+}
@@ -0,0 +1,210 @@
+package scene
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-gl/mathgl/mgl32"
+	"slices"
+)
+
+var ErrCycle = errors.New("scene: node cannot be attached below itself")
+
+// Node is an element of the scene graph. Its transform is relative to its
+// parent; world matrices are cached and only recomputed after the node or
+// one of its ancestors changes.
+type Node struct {
+	Name string
+
+	// Hidden nodes are skipped, with their children, by Walk.
+	Hidden bool
+
+	// Object is what the node carries, such as a renderable mesh or a
+	// light. The scene graph does not look at it.
+	Object any
+
+	// Components holds game data by component name as raw JSON. Scene
+	// files load and save it unchanged.
+	Components map[string]json.RawMessage
+
+	// Prefab is set on the root of a prefab instance. Scene files store
+	// the instance as its differences from Prefab.Source.
+	Prefab *PrefabLink
+
+	parent   *Node
+	children []*Node
+	local    Transform
+	world    mgl32.Mat4
+	dirty    bool
+}
+
+func NewNode(name string) *Node {
+	return &Node{Name: name, local: Identity(), world: mgl32.Ident4()}
+}
+
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the node's children. The slice must not be modified.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+func (n *Node) Local() Transform {
+	return n.local
+}
+
+func (n *Node) SetLocal(t Transform) {
+	n.local = t
+	n.invalidate()
+}
+
+func (n *Node) SetPosition(position mgl32.Vec3) {
+	n.local.Position = position
+	n.invalidate()
+}
+
+func (n *Node) SetRotation(rotation mgl32.Quat) {
+	n.local.Rotation = rotation
+	n.invalidate()
+}
+
+func (n *Node) SetScale(scale mgl32.Vec3) {
+	n.local.Scale = scale
+	n.invalidate()
+}
+
+// World returns the node's transform relative to the root of its tree.
+func (n *Node) World() mgl32.Mat4 {
+	if n.dirty {
+		n.world = n.local.Matrix()
+		if n.parent != nil {
+			n.world = n.parent.World().Mul4(n.world)
+		}
+		n.dirty = false
+	}
+	return n.world
+}
+
+func (n *Node) WorldPosition() mgl32.Vec3 {
+	return n.World().Col(3).Vec3()
+}
+
+// SetWorld sets the local transform so the node ends up at world.
+func (n *Node) SetWorld(world mgl32.Mat4) {
+	if n.parent != nil {
+		world = n.parent.World().Inv().Mul4(world)
+	}
+	n.SetLocal(Decompose(world))
+}
+
+// invalidate marks the node and its descendants as needing new world
+// matrices. A node that is already dirty has dirty descendants too.
+func (n *Node) invalidate() {
+	if n.dirty {
+		return
+	}
+	n.dirty = true
+	for _, child := range n.children {
+		child.invalidate()
+	}
+}
+
+// AddChild attaches child under n, keeping its local transform, so it
+// moves with n. A child that already has a parent is moved.
+func (n *Node) AddChild(child *Node) error {
+	if child.IsAncestorOf(n) {
+		return ErrCycle
+	}
+	child.remove()
+	child.parent = n
+	n.children = append(n.children, child)
+	child.invalidate()
+	return nil
+}
+
+// Reparent moves n under parent without changing where it is in the
+// world. A nil parent makes n a root.
+func (n *Node) Reparent(parent *Node) error {
+	if parent == n.parent {
+		return nil
+	}
+	if parent != nil && n.IsAncestorOf(parent) {
+		return ErrCycle
+	}
+	world := n.World()
+	n.remove()
+	if parent != nil {
+		n.parent = parent
+		parent.children = append(parent.children, n)
+	}
+	n.SetWorld(world)
+	return nil
+}
+
+// Detach makes n a root, keeping its world transform.
+func (n *Node) Detach() {
+	n.Reparent(nil)
+}
+
+// Replace puts other in n's place among its parent's children, keeping
+// other's local transform, and leaves n without a parent.
+func (n *Node) Replace(other *Node) error {
+	parent := n.parent
+	if parent == nil || other == n {
+		return nil
+	}
+	if other.IsAncestorOf(parent) {
+		return ErrCycle
+	}
+	other.remove()
+	parent.children[slices.Index(parent.children, n)] = other
+	other.parent = parent
+	other.invalidate()
+	n.parent = nil
+	n.invalidate()
+	return nil
+}
+
+func (n *Node) remove() {
+	if n.parent == nil {
+		return
+	}
+	n.parent.children = slices.DeleteFunc(n.parent.children, func(c *Node) bool { return c == n })
+	n.parent = nil
+}
+
+// IsAncestorOf reports whether other is n or below n.
+func (n *Node) IsAncestorOf(other *Node) bool {
+	for ; other != nil; other = other.parent {
+		if other == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk visits n and its visible descendants depth first, parents before
+// children. Returning false from fn skips the node's children.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if n.Hidden || !fn(n) {
+		return
+	}
+	for _, child := range n.children {
+		child.Walk(fn)
+	}
+}
+
+// Find returns the first node named name at or below n, including hidden
+// ones, or nil.
+func (n *Node) Find(name string) *Node {
+	if n.Name == name {
+		return n
+	}
+	for _, child := range n.children {
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
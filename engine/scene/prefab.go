@@ -0,0 +1,453 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// A prefab is a scene file with a single root node, which scenes place
+// with instance nodes:
+//
+//	{"name": "crate2", "prefab": "../prefabs/crate.json",
+//	 "transform": {"position": [4, 0, 0]},
+//	 "overrides": {
+//		"": {"components": {"health": 50}},
+//		"Lid": {"hidden": true},
+//		"Lid/Hinge": {"removed": true}
+//	 }}
+//
+// The instance gives the copy its name, visibility and transform; all
+// other differences from the prefab are stored as overrides keyed by the
+// path of the node they change below the prefab root, "" being the root
+// itself. Each path element is a child's name, with "#n" appended for the
+// nth repeat of a name among its siblings ("Wheel", "Wheel#1", ...).
+// Everything not overridden comes from the prefab file each time the scene
+// is loaded, so edits to the prefab reach every instance.
+
+// NodeOverride changes one node of a prefab instance. Components are
+// merged into the prefab's, a null value removing the component, and
+// Children are added after the prefab's children.
+type NodeOverride struct {
+	Hidden     *bool                      `json:"hidden,omitempty"`
+	Transform  *TransformDesc             `json:"transform,omitempty"`
+	Mesh       *MeshDesc                  `json:"mesh,omitempty"`
+	Components map[string]json.RawMessage `json:"components,omitempty"`
+	Children   []NodeDesc                 `json:"children,omitempty"`
+	Removed    bool                       `json:"removed,omitempty"`
+}
+
+func (o *NodeOverride) empty() bool {
+	return o.Hidden == nil && o.Transform == nil && o.Mesh == nil &&
+		len(o.Components) == 0 && len(o.Children) == 0 && !o.Removed
+}
+
+// PrefabLink marks the root of an expanded prefab instance with the
+// prefab it came from. Source is the expanded prefab root the instance
+// was built from, which overrides are measured against when the instance
+// is collapsed again.
+type PrefabLink struct {
+	Path   string
+	Source NodeDesc
+}
+
+// PrefabLoader returns the root node of the prefab file at path, with the
+// paths in it already mapped the way the caller wants them.
+type PrefabLoader func(path string) (NodeDesc, error)
+
+// ReadPrefab reads a prefab file: a scene file with exactly one node and
+// no camera or lights.
+func ReadPrefab(path string) (NodeDesc, error) {
+	f, err := ReadFile(path)
+	if err != nil {
+		return NodeDesc{}, err
+	}
+	var errs []error
+	if f.Camera != nil {
+		errs = append(errs, &SchemaError{File: path, Path: "$.camera", Msg: "prefabs cannot have a camera"})
+	}
+	if len(f.Lights) > 0 {
+		errs = append(errs, &SchemaError{File: path, Path: "$.lights", Msg: "prefabs cannot have lights"})
+	}
+	if len(f.Nodes) != 1 {
+		errs = append(errs, &SchemaError{File: path, Path: "$.nodes", Msg: fmt.Sprintf("a prefab has one root node, got %d", len(f.Nodes))})
+	}
+	if len(errs) > 0 {
+		return NodeDesc{}, errors.Join(errs...)
+	}
+	return f.Nodes[0], nil
+}
+
+// ExpandPrefabs replaces every prefab instance in nodes, the top-level
+// nodes of file, with a copy of its prefab with the overrides applied.
+// Prefabs may contain instances of other prefabs but not of themselves.
+// Expanded instance roots carry a PrefabLink.
+func ExpandPrefabs(file string, nodes []NodeDesc, load PrefabLoader) ([]NodeDesc, error) {
+	e := &expander{load: load, prefabs: make(map[string]NodeDesc)}
+	expanded := e.nodes(file, nodes, "$.nodes")
+	return expanded, errors.Join(e.errs...)
+}
+
+type expander struct {
+	load    PrefabLoader
+	prefabs map[string]NodeDesc
+	loading []string
+	errs    []error
+}
+
+func (e *expander) errorf(file, path, format string, args ...any) {
+	e.errs = append(e.errs, &SchemaError{File: file, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (e *expander) nodes(file string, nodes []NodeDesc, path string) []NodeDesc {
+	if nodes == nil {
+		return nil
+	}
+	expanded := make([]NodeDesc, len(nodes))
+	for i, n := range nodes {
+		expanded[i] = e.node(file, n, IndexPath(path, i))
+	}
+	return expanded
+}
+
+func (e *expander) node(file string, n NodeDesc, path string) NodeDesc {
+	if n.Prefab == "" {
+		n.Origin = &Origin{File: file, Path: path}
+		n.Mesh = n.Mesh.withOrigin(file, FieldPath(path, "mesh"))
+		n.Children = e.nodes(file, n.Children, FieldPath(path, "children"))
+		return n
+	}
+
+	source, ok := e.prefab(file, n.Prefab, FieldPath(path, "prefab"))
+	if !ok {
+		return NodeDesc{Name: n.Name, Hidden: n.Hidden, Transform: n.Transform}
+	}
+	overrides := make(map[string]NodeOverride, len(n.Overrides))
+	for key, o := range n.Overrides {
+		p := FieldPath(FieldPath(path, "overrides"), key)
+		o.Mesh = o.Mesh.withOrigin(file, FieldPath(p, "mesh"))
+		o.Children = e.nodes(file, o.Children, FieldPath(p, "children"))
+		overrides[key] = o
+	}
+
+	instance := e.instantiate(file, source, n, overrides, path)
+	instance.Link = &PrefabLink{Path: n.Prefab, Source: source}
+	return instance
+}
+
+// prefab loads and expands a prefab once per ExpandPrefabs call.
+func (e *expander) prefab(file, prefab, path string) (NodeDesc, bool) {
+	if root, ok := e.prefabs[prefab]; ok {
+		return root, true
+	}
+	if slices.Contains(e.loading, prefab) {
+		e.errorf(file, path, "prefab %s contains itself", prefab)
+		return NodeDesc{}, false
+	}
+	root, err := e.load(prefab)
+	if err != nil {
+		e.errorf(file, path, "%v", err)
+		return NodeDesc{}, false
+	}
+
+	e.loading = append(e.loading, prefab)
+	root = e.node(prefab, root, "$.nodes[0]")
+	e.loading = e.loading[:len(e.loading)-1]
+	e.prefabs[prefab] = root
+	return root, true
+}
+
+func (e *expander) instantiate(file string, source, n NodeDesc, overrides map[string]NodeOverride, path string) NodeDesc {
+	instance := source.clone()
+	if n.Name != "" {
+		instance.Name = n.Name
+	}
+	instance.Hidden = n.Hidden
+	instance.Transform = n.Transform
+
+	var removed []string
+	for _, key := range sortedKeys(overrides) {
+		o := overrides[key]
+		p := FieldPath(FieldPath(path, "overrides"), key)
+		parent, index := findNode(&instance, key)
+		if index < -1 {
+			e.errorf(file, p, "prefab %s has no node %q", n.Prefab, key)
+			continue
+		}
+		if o.Removed {
+			if parent == nil {
+				e.errorf(file, p, "the prefab root cannot be removed")
+			} else {
+				removed = append(removed, key)
+			}
+			continue
+		}
+
+		target := &instance
+		if parent != nil {
+			target = &parent.Children[index]
+		}
+		if o.Hidden != nil {
+			target.Hidden = *o.Hidden
+		}
+		if o.Transform != nil {
+			target.Transform = o.Transform
+		}
+		if o.Mesh != nil {
+			target.Mesh = o.Mesh
+		}
+		for name, value := range o.Components {
+			if isNull(value) {
+				delete(target.Components, name)
+				continue
+			}
+			if target.Components == nil {
+				target.Components = make(map[string]json.RawMessage)
+			}
+			target.Components[name] = value
+		}
+		target.Children = append(target.Children, o.Children...)
+	}
+
+	// Removals are resolved before any is applied, since removing a node
+	// renames its repeated siblings, and applied deepest first so that no
+	// removal moves a node another one points at.
+	type removal struct {
+		parent *NodeDesc
+		index  int
+		depth  int
+	}
+	removals := make([]removal, len(removed))
+	for i, key := range removed {
+		parent, index := findNode(&instance, key)
+		removals[i] = removal{parent, index, strings.Count(key, "/")}
+	}
+	slices.SortFunc(removals, func(a, b removal) int {
+		if a.depth != b.depth {
+			return b.depth - a.depth
+		}
+		return b.index - a.index
+	})
+	for _, r := range removals {
+		r.parent.Children = slices.Delete(r.parent.Children, r.index, r.index+1)
+	}
+	return instance
+}
+
+// findNode resolves an override path. It returns the node's parent and
+// its index there, a nil parent and -1 for the root, or an index below -1
+// when there is no such node.
+func findNode(root *NodeDesc, key string) (*NodeDesc, int) {
+	if key == "" {
+		return nil, -1
+	}
+	node := root
+	var parent *NodeDesc
+	index := -2
+	for _, part := range strings.Split(key, "/") {
+		index = slices.Index(ChildKeys(node.Children), part)
+		if index < 0 {
+			return nil, -2
+		}
+		parent, node = node, &node.Children[index]
+	}
+	return parent, index
+}
+
+// ChildKeys returns the override path element of each child.
+func ChildKeys(children []NodeDesc) []string {
+	keys := make([]string, len(children))
+	seen := make(map[string]int)
+	for i, c := range children {
+		keys[i] = c.Name
+		if n := seen[c.Name]; n > 0 {
+			keys[i] = fmt.Sprintf("%s#%d", c.Name, n)
+		}
+		seen[c.Name]++
+	}
+	return keys
+}
+
+func childPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "/" + key
+}
+
+// clone copies the parts of n that instantiation modifies in place.
+func (n NodeDesc) clone() NodeDesc {
+	c := n
+	c.Components = maps.Clone(n.Components)
+	if n.Children != nil {
+		c.Children = make([]NodeDesc, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = child.clone()
+		}
+	}
+	return c
+}
+
+// CollapsePrefabs turns every expanded instance in nodes back into an
+// instance node, storing how it differs from its PrefabLink.Source as
+// overrides. Prefab nodes missing from the instance are stored as
+// removed and nodes that are not in the prefab as added children; a node
+// that lost its mesh keeps the prefab's.
+func CollapsePrefabs(nodes []NodeDesc) []NodeDesc {
+	if nodes == nil {
+		return nil
+	}
+	collapsed := make([]NodeDesc, len(nodes))
+	for i, n := range nodes {
+		collapsed[i] = collapse(n)
+	}
+	return collapsed
+}
+
+func collapse(n NodeDesc) NodeDesc {
+	if n.Link == nil {
+		n.Children = CollapsePrefabs(n.Children)
+		return n
+	}
+	overrides := make(map[string]NodeOverride)
+	diff(&n.Link.Source, &n, "", overrides)
+	if len(overrides) == 0 {
+		overrides = nil
+	}
+	return NodeDesc{
+		Name:      n.Name,
+		Hidden:    n.Hidden,
+		Transform: n.Transform,
+		Prefab:    n.Link.Path,
+		Overrides: overrides,
+	}
+}
+
+func diff(source, actual *NodeDesc, path string, overrides map[string]NodeOverride) {
+	var o NodeOverride
+	// The root's name, visibility and transform belong to the instance.
+	if path != "" {
+		if actual.Hidden != source.Hidden {
+			hidden := actual.Hidden
+			o.Hidden = &hidden
+		}
+		if actual.Transform.Transform() != source.Transform.Transform() {
+			o.Transform = actual.Transform
+			if o.Transform == nil {
+				o.Transform = &TransformDesc{Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}}
+			}
+		}
+	}
+	if actual.Mesh != nil && !sameMesh(actual.Mesh, source.Mesh) {
+		o.Mesh = actual.Mesh
+	}
+	for name, value := range actual.Components {
+		if prev, ok := source.Components[name]; !ok || !sameJSON(prev, value) {
+			if o.Components == nil {
+				o.Components = make(map[string]json.RawMessage)
+			}
+			o.Components[name] = value
+		}
+	}
+	for name := range source.Components {
+		if _, ok := actual.Components[name]; !ok {
+			if o.Components == nil {
+				o.Components = make(map[string]json.RawMessage)
+			}
+			o.Components[name] = json.RawMessage("null")
+		}
+	}
+
+	sourceKeys, actualKeys := ChildKeys(source.Children), ChildKeys(actual.Children)
+	for i, key := range sourceKeys {
+		if j := slices.Index(actualKeys, key); j >= 0 {
+			diff(&source.Children[i], &actual.Children[j], childPath(path, key), overrides)
+		} else {
+			overrides[childPath(path, key)] = NodeOverride{Removed: true}
+		}
+	}
+	for j, key := range actualKeys {
+		if !slices.Contains(sourceKeys, key) {
+			o.Children = append(o.Children, collapse(actual.Children[j]))
+		}
+	}
+
+	if !o.empty() {
+		overrides[path] = o
+	}
+}
+
+func sameMesh(a, b *MeshDesc) bool {
+	if b == nil {
+		return false
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func sameJSON(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+func isNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+// MapPaths replaces every asset path at and below n with fn(path): model
+// files, textures and prefabs, including those inside overrides.
+func (n *NodeDesc) MapPaths(fn func(string) string) {
+	if n.Prefab != "" {
+		n.Prefab = fn(n.Prefab)
+	}
+	n.Mesh = n.Mesh.mapPaths(fn)
+	for key, o := range n.Overrides {
+		o.Mesh = o.Mesh.mapPaths(fn)
+		for i := range o.Children {
+			o.Children[i].MapPaths(fn)
+		}
+		n.Overrides[key] = o
+	}
+	for i := range n.Children {
+		n.Children[i].MapPaths(fn)
+	}
+}
+
+// withOrigin returns a copy of m declared at path in file, as mesh
+// descriptions are shared between prefab instances.
+func (m *MeshDesc) withOrigin(file, path string) *MeshDesc {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Origin = &Origin{File: file, Path: path}
+	return &c
+}
+
+// mapPaths returns a copy of m with its paths mapped, as mesh
+// descriptions are shared between prefab instances.
+func (m *MeshDesc) mapPaths(fn func(string) string) *MeshDesc {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.Path = fn(c.Path)
+	if m.Materials != nil {
+		c.Materials = make(map[string]MaterialDesc, len(m.Materials))
+		for name, mat := range m.Materials {
+			if mat.Texture != "" {
+				mat.Texture = fn(mat.Texture)
+			}
+			c.Materials[name] = mat
+		}
+	}
+	return &c
+}
@@ -0,0 +1,234 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func prefabLoader(prefabs map[string]string) PrefabLoader {
+	return func(path string) (NodeDesc, error) {
+		src, ok := prefabs[path]
+		if !ok {
+			return NodeDesc{}, fmt.Errorf("no prefab %s", path)
+		}
+		f, err := Decode(strings.NewReader(src))
+		if err != nil {
+			return NodeDesc{}, err
+		}
+		return f.Nodes[0], nil
+	}
+}
+
+var cratePrefabs = map[string]string{
+	"crate.json": `{"version": 1, "nodes": [{"name": "crate",
+		"mesh": {"path": "crate.obj"},
+		"components": {"health": 100, "loot": "gold"},
+		"children": [
+			{"name": "lid", "mesh": {"path": "crate.obj", "object": "Lid"}},
+			{"name": "handle"},
+			{"name": "handle"},
+			{"name": "light", "prefab": "lamp.json"}
+		]}]}`,
+	"lamp.json": `{"version": 1, "nodes": [{"name": "lamp", "children": [{"name": "bulb"}]}]}`,
+}
+
+func expand(t *testing.T, src string) []NodeDesc {
+	t.Helper()
+	f, err := Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := ExpandPrefabs("scene.json", f.Nodes, prefabLoader(cratePrefabs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nodes
+}
+
+func childNames(n NodeDesc) []string {
+	var names []string
+	for _, c := range n.Children {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestExpandPrefabs(t *testing.T) {
+	nodes := expand(t, `{"version": 1, "nodes": [
+		{"name": "a", "prefab": "crate.json", "transform": {"position": [1, 0, 0]}},
+		{"name": "b", "prefab": "crate.json", "overrides": {
+			"": {"components": {"health": 50, "loot": null}, "children": [{"name": "sticker"}]},
+			"lid": {"hidden": true},
+			"handle#1": {"removed": true},
+			"light/bulb": {"components": {"color": "red"}}
+		}}
+	]}`)
+
+	a, b := nodes[0], nodes[1]
+	if a.Name != "a" || a.Link == nil || a.Link.Path != "crate.json" || a.Mesh == nil {
+		t.Fatalf("got %+v", a)
+	}
+	if a.Transform.Transform().Position[0] != 1 {
+		t.Error("instance transform not applied")
+	}
+	if a.Children[3].Link == nil || a.Children[3].Children[0].Name != "bulb" {
+		t.Error("nested prefab not expanded")
+	}
+
+	if string(b.Components["health"]) != "50" || b.Components["loot"] != nil {
+		t.Errorf("got components %s", b.Components)
+	}
+	if got := childNames(b); !reflect.DeepEqual(got, []string{"lid", "handle", "light", "sticker"}) {
+		t.Errorf("got children %v", got)
+	}
+	if !b.Children[0].Hidden || b.Children[2].Children[0].Components["color"] == nil {
+		t.Error("nested overrides not applied")
+	}
+	// Instances do not share state with each other or the prefab.
+	if a.Children[0].Hidden || string(a.Components["health"]) != "100" || len(a.Children[3].Children[0].Components) != 0 {
+		t.Error("override leaked into another instance")
+	}
+}
+
+func TestExpandPrefabsOrigins(t *testing.T) {
+	nodes := expand(t, `{"version": 1, "nodes": [
+		{"name": "floor", "mesh": {"path": "floor.obj"}},
+		{"name": "b", "prefab": "crate.json", "overrides": {
+			"lid": {"mesh": {"path": "lid.obj"}},
+			"": {"children": [{"name": "sticker", "mesh": {"path": "sticker.obj"}}]}
+		}},
+		{"name": "c", "prefab": "crate.json"}
+	]}`)
+	b := nodes[1]
+	for _, tc := range []struct {
+		name string
+		got  *Origin
+		want Origin
+	}{
+		{"scene node", nodes[0].Origin, Origin{"scene.json", "$.nodes[0]"}},
+		{"scene mesh", nodes[0].Mesh.Origin, Origin{"scene.json", "$.nodes[0].mesh"}},
+		{"prefab root", b.Origin, Origin{"crate.json", "$.nodes[0]"}},
+		{"prefab mesh", b.Mesh.Origin, Origin{"crate.json", "$.nodes[0].mesh"}},
+		{"prefab child", b.Children[1].Origin, Origin{"crate.json", "$.nodes[0].children[1]"}},
+		{"nested prefab", b.Children[3].Children[0].Origin, Origin{"lamp.json", "$.nodes[0].children[0]"}},
+		{"overridden mesh", b.Children[0].Mesh.Origin, Origin{"scene.json", `$.nodes[1].overrides.lid.mesh`}},
+		{"added child", b.Children[4].Mesh.Origin, Origin{"scene.json", `$.nodes[1].overrides[""].children[0].mesh`}},
+		// Overrides on one instance do not reach the next.
+		{"other instance", nodes[2].Children[0].Mesh.Origin, Origin{"crate.json", "$.nodes[0].children[0].mesh"}},
+	} {
+		if tc.got == nil || *tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestCollapsePrefabs(t *testing.T) {
+	src := `{"version": 1, "nodes": [{"name": "b", "prefab": "crate.json", "overrides": {
+		"": {"components": {"loot": null}},
+		"handle#1": {"removed": true},
+		"lid": {"hidden": true}
+	}}]}`
+	nodes := expand(t, src)
+
+	// Edit the expanded instance the way a game would, then store it.
+	b := &nodes[0]
+	b.Children[0].Transform = &TransformDesc{Position: [3]float32{0, 1, 0}, Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}}
+	b.Children = append(b.Children, NodeDesc{Name: "sticker"})
+	b.Components["health"] = json.RawMessage("7")
+
+	collapsed := CollapsePrefabs(nodes)
+	want := map[string]NodeOverride{
+		"": {
+			Components: map[string]json.RawMessage{"health": json.RawMessage("7"), "loot": json.RawMessage("null")},
+			Children:   []NodeDesc{{Name: "sticker"}},
+		},
+		"handle#1": {Removed: true},
+		"lid":      {Hidden: &[]bool{true}[0], Transform: b.Children[0].Transform},
+	}
+	if got := collapsed[0]; got.Prefab != "crate.json" || got.Name != "b" || !reflect.DeepEqual(got.Overrides, want) {
+		out, _ := json.MarshalIndent(got, "", "  ")
+		t.Fatalf("got %s", out)
+	}
+
+	// Expanding the stored form gives back the edited instance.
+	again, err := ExpandPrefabs("scene.json", collapsed, prefabLoader(cratePrefabs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CollapsePrefabs(again)[0].Overrides["lid"].Transform.Position[1] != 1 || !reflect.DeepEqual(childNames(again[0]), childNames(*b)) {
+		t.Errorf("got %+v", again[0])
+	}
+
+	// An untouched instance stores no overrides.
+	if got := CollapsePrefabs(expand(t, `{"version": 1, "nodes": [{"prefab": "crate.json"}]}`)); got[0].Overrides != nil {
+		t.Errorf("got overrides %+v", got[0].Overrides)
+	}
+}
+
+func TestPrefabEditsReachInstances(t *testing.T) {
+	nodes := expand(t, `{"version": 1, "nodes": [{"name": "b", "prefab": "crate.json", "overrides": {"lid": {"hidden": true}}}]}`)
+	collapsed := CollapsePrefabs(nodes)
+
+	edited := map[string]string{
+		"crate.json": strings.Replace(cratePrefabs["crate.json"], `"health": 100`, `"health": 120`, 1),
+		"lamp.json":  cratePrefabs["lamp.json"],
+	}
+	again, err := ExpandPrefabs("scene.json", collapsed, prefabLoader(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again[0].Components["health"]) != "120" || !again[0].Children[0].Hidden {
+		t.Errorf("got %+v", again[0])
+	}
+}
+
+func TestPrefabErrors(t *testing.T) {
+	f, err := Decode(strings.NewReader(`{"version": 1, "nodes": [
+		{"prefab": "crate.json", "overrides": {"nope": {"hidden": true}, "": {"removed": true}}},
+		{"prefab": "loop.json"},
+		{"prefab": "missing.json"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefabs := map[string]string{
+		"loop.json": `{"version": 1, "nodes": [{"name": "x", "children": [{"prefab": "loop.json"}]}]}`,
+	}
+	for k, v := range cratePrefabs {
+		prefabs[k] = v
+	}
+	_, err = ExpandPrefabs("scene.json", f.Nodes, prefabLoader(prefabs))
+	want := []string{
+		`scene.json: $.nodes[0].overrides[""]: the prefab root cannot be removed`,
+		`scene.json: $.nodes[0].overrides.nope: prefab crate.json has no node "nope"`,
+		`loop.json: $.nodes[0].children[0].prefab: prefab loop.json contains itself`,
+		`scene.json: $.nodes[2].prefab: no prefab missing.json`,
+	}
+	if err == nil || !reflect.DeepEqual(strings.Split(err.Error(), "\n"), want) {
+		t.Errorf("got %v", err)
+	}
+
+	_, err = Decode(strings.NewReader(`{"version": 1, "nodes": [{"prefab": "a.json", "mesh": {"path": "a.obj"}}, {"overrides": {}}]}`))
+	if err == nil || !strings.Contains(err.Error(), "$.nodes[0].mesh: prefab instances take mesh through overrides") ||
+		!strings.Contains(err.Error(), "$.nodes[1].overrides: overrides need a prefab") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestMapPaths(t *testing.T) {
+	f, err := Decode(strings.NewReader(`{"version": 1, "nodes": [{"prefab": "p.json", "overrides": {"": {
+		"mesh": {"path": "a.obj", "materials": {"m": {"texture": "t.png"}}},
+		"children": [{"mesh": {"path": "b.obj"}}]}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := f.Nodes[0]
+	n.MapPaths(func(p string) string { return "dir/" + p })
+	o := n.Overrides[""]
+	if n.Prefab != "dir/p.json" || o.Mesh.Path != "dir/a.obj" || o.Mesh.Materials["m"].Texture != "dir/t.png" || o.Children[0].Mesh.Path != "dir/b.obj" {
+		t.Errorf("got %+v", n)
+	}
+}
@@ -0,0 +1,129 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+func near(a, b mgl32.Vec3) bool {
+	return a.Sub(b).Len() < 1e-4
+}
+
+func TestWorldFollowsParent(t *testing.T) {
+	root := NewNode("root")
+	child := NewNode("child")
+	if err := root.AddChild(child); err != nil {
+		t.Fatal(err)
+	}
+	child.SetPosition(mgl32.Vec3{1, 0, 0})
+
+	root.SetPosition(mgl32.Vec3{0, 2, 0})
+	root.SetRotation(mgl32.QuatRotate(mgl32.DegToRad(90), mgl32.Vec3{0, 1, 0}))
+	if got := child.WorldPosition(); !near(got, mgl32.Vec3{0, 2, -1}) {
+		t.Errorf("got %v", got)
+	}
+
+	// A cached world matrix is refreshed when an ancestor moves again.
+	root.SetScale(mgl32.Vec3{2, 2, 2})
+	if got := child.WorldPosition(); !near(got, mgl32.Vec3{0, 2, -2}) {
+		t.Errorf("after scaling got %v", got)
+	}
+}
+
+func TestReparentKeepsWorld(t *testing.T) {
+	a, b := NewNode("a"), NewNode("b")
+	a.SetPosition(mgl32.Vec3{5, 0, 0})
+	b.SetPosition(mgl32.Vec3{0, 0, 3})
+	b.SetRotation(mgl32.QuatRotate(mgl32.DegToRad(45), mgl32.Vec3{0, 0, 1}))
+	b.SetScale(mgl32.Vec3{2, 2, 2})
+
+	child := NewNode("child")
+	child.SetPosition(mgl32.Vec3{1, 1, 1})
+	a.AddChild(child)
+	before := child.World()
+
+	if err := child.Reparent(b); err != nil {
+		t.Fatal(err)
+	}
+	if child.Parent() != b || len(a.Children()) != 0 || len(b.Children()) != 1 {
+		t.Fatal("child was not moved")
+	}
+	if !child.World().ApproxEqualThreshold(before, 1e-4) {
+		t.Errorf("world changed from %v to %v", before, child.World())
+	}
+
+	child.Detach()
+	if child.Parent() != nil || len(b.Children()) != 0 {
+		t.Fatal("child was not detached")
+	}
+	if !child.World().ApproxEqualThreshold(before, 1e-4) {
+		t.Errorf("world changed on detach to %v", child.World())
+	}
+}
+
+func TestCyclesRejected(t *testing.T) {
+	root, child := NewNode("root"), NewNode("child")
+	root.AddChild(child)
+	if err := child.AddChild(root); err != ErrCycle {
+		t.Errorf("AddChild: got %v", err)
+	}
+	if err := root.Reparent(child); err != ErrCycle {
+		t.Errorf("Reparent: got %v", err)
+	}
+	if err := root.AddChild(root); err != ErrCycle {
+		t.Errorf("self: got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	root := NewNode("root")
+	a, b, c := NewNode("a"), NewNode("b"), NewNode("c")
+	root.AddChild(a)
+	root.AddChild(b)
+	c.SetPosition(mgl32.Vec3{0, 1, 0})
+	if err := a.Replace(c); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Children(); len(got) != 2 || got[0] != c || got[1] != b || a.Parent() != nil {
+		t.Errorf("got children %v", got)
+	}
+	if got := c.WorldPosition(); !near(got, mgl32.Vec3{0, 1, 0}) {
+		t.Errorf("got %v", got)
+	}
+	if err := c.Replace(root); err != ErrCycle {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestWalkAndFind(t *testing.T) {
+	root := NewNode("root")
+	a, b, c := NewNode("a"), NewNode("b"), NewNode("c")
+	root.AddChild(a)
+	root.AddChild(b)
+	a.AddChild(c)
+	b.Hidden = true
+
+	var names []string
+	root.Walk(func(n *Node) bool {
+		names = append(names, n.Name)
+		return true
+	})
+	if got := len(names); got != 3 || names[0] != "root" || names[1] != "a" || names[2] != "c" {
+		t.Errorf("got %v", names)
+	}
+	if root.Find("b") != b || root.Find("missing") != nil {
+		t.Error("Find failed")
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	want := Transform{
+		Position: mgl32.Vec3{1, 2, 3},
+		Rotation: mgl32.QuatRotate(1, mgl32.Vec3{1, 1, 0}.Normalize()),
+		Scale:    mgl32.Vec3{2, 3, 4},
+	}
+	got := Decompose(want.Matrix())
+	if !near(got.Position, want.Position) || !near(got.Scale, want.Scale) || !got.Rotation.ApproxEqualThreshold(want.Rotation, 1e-4) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
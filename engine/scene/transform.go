@@ -0,0 +1,47 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Transform is a translation, rotation and scale, applied scale first.
+type Transform struct {
+	Position mgl32.Vec3
+	Rotation mgl32.Quat
+	Scale    mgl32.Vec3
+}
+
+func Identity() Transform {
+	return Transform{Rotation: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}}
+}
+
+func (t Transform) Matrix() mgl32.Mat4 {
+	return mgl32.Translate3D(t.Position.X(), t.Position.Y(), t.Position.Z()).
+		Mul4(t.Rotation.Normalize().Mat4()).
+		Mul4(mgl32.Scale3D(t.Scale.X(), t.Scale.Y(), t.Scale.Z()))
+}
+
+// Decompose splits an affine matrix into a Transform. Shear, which
+// non-uniformly scaled parents can introduce, cannot be represented and
+// is dropped.
+func Decompose(m mgl32.Mat4) Transform {
+	x, y, z := m.Col(0).Vec3(), m.Col(1).Vec3(), m.Col(2).Vec3()
+	scale := mgl32.Vec3{x.Len(), y.Len(), z.Len()}
+	if m.Mat3().Det() < 0 {
+		scale[0] = -scale[0]
+	}
+
+	var rotation mgl32.Mat3
+	for i, axis := range []mgl32.Vec3{x, y, z} {
+		if scale[i] != 0 {
+			axis = axis.Mul(1 / scale[i])
+		}
+		rotation.SetCol(i, axis)
+	}
+
+	return Transform{
+		Position: m.Col(3).Vec3(),
+		Rotation: mgl32.Mat4ToQuat(rotation.Mat4()).Normalize(),
+		Scale:    scale,
+	}
+}
@@ -0,0 +1,74 @@
+package texture
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+)
+
+// Image is decoded 8-bit RGBA pixel data ready for glTexImage2D. Rows are
+// stored bottom to top, matching GL's texture origin, so UVs exported with
+// the usual bottom-left convention sample the right texels.
+type Image struct {
+	Width  int
+	Height int
+	Pix    []byte
+}
+
+// Load decodes a PNG or JPEG file.
+func Load(path string) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := Decode(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Decode reads a PNG or JPEG image, detected from its contents.
+func Decode(r io.Reader) (*Image, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromImage(src), nil
+}
+
+// DecodeBytes is Decode for images already in memory, such as those
+// embedded in glTF files.
+func DecodeBytes(data []byte) (*Image, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// FromImage converts any image to non-premultiplied RGBA and flips it for GL.
+func FromImage(src image.Image) *Image {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.NRGBA)
+	if !ok || rgba.Stride != 4*bounds.Dx() {
+		rgba = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+
+	img := &Image{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Pix:    make([]byte, len(rgba.Pix)),
+	}
+	stride := 4 * img.Width
+	for y := 0; y < img.Height; y++ {
+		src := rgba.Pix[y*rgba.Stride : y*rgba.Stride+stride]
+		copy(img.Pix[(img.Height-1-y)*stride:], src)
+	}
+	return img
+}
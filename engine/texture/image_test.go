@@ -0,0 +1,55 @@
+package texture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestFromImageFlipsRows(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	src.Set(1, 1, color.NRGBA{0, 0, 255, 128})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	img, err := DecodeBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The top-left red pixel ends up in the last row; the half transparent
+	// blue one keeps its straight (not premultiplied) color in the first.
+	if got := img.Pix[8:12]; !bytes.Equal(got, []byte{255, 0, 0, 255}) {
+		t.Errorf("got top-left %v", got)
+	}
+	if got := img.Pix[4:8]; !bytes.Equal(got, []byte{0, 0, 255, 128}) {
+		t.Errorf("got bottom-right %v", got)
+	}
+}
+
+func TestLoadResources(t *testing.T) {
+	paths := []string{
+		"../res/textures/UVChecker.png",
+		"../res/models/2b-nier-automata/textures/Face.jpeg",
+		"../res/models/2b-nier-automata/textures/cloths.jpeg",
+		"../res/models/2b-nier-automata/textures/hair.jpeg",
+	}
+	for _, path := range paths {
+		img, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Width == 0 || img.Height == 0 || len(img.Pix) != img.Width*img.Height*4 {
+			t.Errorf("%s: got %dx%d with %d bytes", path, img.Width, img.Height, len(img.Pix))
+		}
+	}
+
+	if _, err := DecodeBytes([]byte("not an image")); err == nil {
+		t.Error("expected an error for garbage data")
+	}
+}
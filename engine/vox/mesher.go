@@ -0,0 +1,223 @@
+package vox
+
+import (
+	"3DPixelGameEngine/engine/obj"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// PaletteSize is the width of the 1-pixel-high texture that palette UVs
+// address; see GreedyMesh.
+const PaletteSize = 256
+
+// Object meshes model index of the file with its palette.
+func (f *File) Object(index int) (*obj.DecodedObject, error) {
+	if index < 0 || index >= len(f.Models) {
+		return nil, fmt.Errorf("vox: model %d out of range", index)
+	}
+
+	dec := GreedyMesh(f.Models[index], &f.Palette)
+	dec.Objects[0].Name = fmt.Sprintf("model%d", index)
+	return dec, nil
+}
+
+// GreedyMesh converts a voxel grid to quads, merging coplanar faces of the
+// same color into the largest rectangles it can. Only faces between a
+// filled and an empty cell are emitted.
+//
+// The result is an ordinary DecodedObject so it can be built, encoded or
+// rendered like a loaded OBJ: one material per palette color ("color<N>",
+// with the color as Kd) and UVs that point at the color's texel in a
+// PaletteSize x 1 palette texture. Positions are one unit per voxel,
+// centred on the model the way MagicaVoxel pivots it, and converted to the
+// engine's Y-up space.
+func GreedyMesh(model *Model, palette *Palette) *obj.DecodedObject {
+	m := &mesher{
+		size:     model.Size,
+		grid:     make([]uint8, model.Size[0]*model.Size[1]*model.Size[2]),
+		corners:  make(map[[3]int]int),
+		colorUVs: make(map[uint8]int),
+		palette:  palette,
+		decoded:  &obj.DecodedObject{Materials: make(map[string]*obj.Material)},
+		faces:    make([]obj.Face, 0),
+	}
+	for _, v := range model.Voxels {
+		m.grid[m.cell([3]int{int(v.X), int(v.Y), int(v.Z)})] = v.Color
+	}
+
+	for axis := 0; axis < 3; axis++ {
+		m.appendNormal(axis, 1)
+		m.appendNormal(axis, -1)
+	}
+	for axis := 0; axis < 3; axis++ {
+		m.sweep(axis)
+	}
+
+	m.decoded.Objects = []obj.Object{{Name: "voxels", Faces: m.faces}}
+	return m.decoded
+}
+
+type mesher struct {
+	size     [3]int
+	grid     []uint8
+	corners  map[[3]int]int
+	colorUVs map[uint8]int
+	palette  *Palette
+	decoded  *obj.DecodedObject
+	faces    []obj.Face
+}
+
+func (m *mesher) cell(p [3]int) int {
+	return p[0] + m.size[0]*(p[1]+m.size[1]*p[2])
+}
+
+func (m *mesher) at(p [3]int) uint8 {
+	for axis := range p {
+		if p[axis] < 0 || p[axis] >= m.size[axis] {
+			return 0
+		}
+	}
+	return m.grid[m.cell(p)]
+}
+
+// sweep walks the planes perpendicular to axis. For each plane it builds a
+// mask of visible faces, signed by which side they face, and greedily
+// covers it with rectangles.
+func (m *mesher) sweep(axis int) {
+	u, v := (axis+1)%3, (axis+2)%3
+	width, height := m.size[u], m.size[v]
+	mask := make([]int, width*height)
+
+	for plane := 0; plane <= m.size[axis]; plane++ {
+		for j := 0; j < height; j++ {
+			for i := 0; i < width; i++ {
+				var behind, front [3]int
+				behind[axis], behind[u], behind[v] = plane-1, i, j
+				front[axis], front[u], front[v] = plane, i, j
+
+				a, b := m.at(behind), m.at(front)
+				switch {
+				case a != 0 && b == 0:
+					mask[i+j*width] = int(a)
+				case a == 0 && b != 0:
+					mask[i+j*width] = -int(b)
+				default:
+					mask[i+j*width] = 0
+				}
+			}
+		}
+
+		for j := 0; j < height; j++ {
+			for i := 0; i < width; {
+				c := mask[i+j*width]
+				if c == 0 {
+					i++
+					continue
+				}
+
+				w := 1
+				for i+w < width && mask[i+w+j*width] == c {
+					w++
+				}
+				h := 1
+			grow:
+				for j+h < height {
+					for k := 0; k < w; k++ {
+						if mask[i+k+(j+h)*width] != c {
+							break grow
+						}
+					}
+					h++
+				}
+
+				var origin, du, dv [3]int
+				origin[axis], origin[u], origin[v] = plane, i, j
+				du[u], dv[v] = w, h
+				m.appendQuad(axis, origin, du, dv, c)
+
+				for y := j; y < j+h; y++ {
+					for x := i; x < i+w; x++ {
+						mask[x+y*width] = 0
+					}
+				}
+				i += w
+			}
+		}
+	}
+}
+
+// appendQuad adds a face counter-clockwise as seen from the side it faces.
+// du x dv points along +axis, so back faces swap the edge order.
+func (m *mesher) appendQuad(axis int, origin, du, dv [3]int, signedColor int) {
+	normal := axis * 2
+	color := uint8(signedColor)
+	corners := [4][3]int{origin, add(origin, du), add(add(origin, du), dv), add(origin, dv)}
+	if signedColor < 0 {
+		normal++
+		color = uint8(-signedColor)
+		corners[1], corners[3] = corners[3], corners[1]
+	}
+
+	uv, material := m.color(color)
+	face := obj.Face{
+		Vertices: make([]int, 4),
+		Normals:  []int{normal, normal, normal, normal},
+		UVs:      []int{uv, uv, uv, uv},
+		Material: material,
+	}
+	for c, corner := range corners {
+		face.Vertices[c] = m.corner(corner)
+	}
+	v := face.Vertices
+	m.decoded.Indices = append(m.decoded.Indices,
+		uint32(v[0]), uint32(v[1]), uint32(v[2]), uint32(v[0]), uint32(v[2]), uint32(v[3]))
+	m.faces = append(m.faces, face)
+}
+
+func (m *mesher) corner(p [3]int) int {
+	if index, ok := m.corners[p]; ok {
+		return index
+	}
+
+	index := len(m.decoded.Vertices) / 3
+	x := float32(p[0] - m.size[0]/2)
+	y := float32(p[1] - m.size[1]/2)
+	z := float32(p[2] - m.size[2]/2)
+	m.decoded.Vertices = append(m.decoded.Vertices, x, z, -y)
+	m.corners[p] = index
+	return index
+}
+
+func (m *mesher) appendNormal(axis, sign int) {
+	var n [3]float32
+	n[axis] = float32(sign)
+	m.decoded.Normals = append(m.decoded.Normals, n[0], n[2], -n[1])
+}
+
+// color returns the UV index and material name for a palette index,
+// creating both the first time the color is used.
+func (m *mesher) color(index uint8) (int, string) {
+	name := fmt.Sprintf("color%d", index)
+	if uv, ok := m.colorUVs[index]; ok {
+		return uv, name
+	}
+
+	uv := len(m.decoded.UVs) / 2
+	m.decoded.UVs = append(m.decoded.UVs, (float32(index)+0.5)/PaletteSize, 0.5)
+	m.colorUVs[index] = uv
+
+	c := m.palette[index]
+	m.decoded.Materials[name] = &obj.Material{
+		Name:           name,
+		Opacity:        float32(c.A) / 255,
+		OpticalDensity: 1,
+		Illumination:   1,
+		Diffuse:        mgl32.Vec3{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255},
+		Transmission:   mgl32.Vec3{1, 1, 1},
+	}
+	return uv, name
+}
+
+func add(a, b [3]int) [3]int {
+	return [3]int{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
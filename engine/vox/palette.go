@@ -0,0 +1,37 @@
+package vox
+
+import (
+	"image/color"
+)
+
+// Palette maps voxel color indices to colors. Index 0 means empty.
+type Palette [256]color.RGBA
+
+// DefaultPalette returns the palette MagicaVoxel uses for files without an
+// RGBA chunk: a 6x6x6 color cube without black, followed by ten-step ramps
+// of red, green, blue and gray.
+func DefaultPalette() Palette {
+	var p Palette
+	steps := []uint8{0xff, 0xcc, 0x99, 0x66, 0x33, 0x00}
+	ramp := []uint8{0xee, 0xdd, 0xbb, 0xaa, 0x88, 0x77, 0x55, 0x44, 0x22, 0x11}
+
+	i := 1
+	for _, r := range steps {
+		for _, g := range steps {
+			for _, b := range steps {
+				if i < 216 {
+					p[i] = color.RGBA{r, g, b, 0xff}
+					i++
+				}
+			}
+		}
+	}
+	for _, v := range ramp {
+		p[i] = color.RGBA{v, 0, 0, 0xff}
+		p[i+10] = color.RGBA{0, v, 0, 0xff}
+		p[i+20] = color.RGBA{0, 0, v, 0xff}
+		p[i+30] = color.RGBA{v, v, v, 0xff}
+		i++
+	}
+	return p
+}
@@ -0,0 +1,207 @@
+package vox
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"strconv"
+	"strings"
+)
+
+type NodeKind int
+
+const (
+	NodeTransform NodeKind = iota
+	NodeGroup
+	NodeShape
+)
+
+// Node is an nTRN, nGRP or nSHP chunk. Transform nodes have exactly one
+// child; group nodes any number; shape nodes none, but reference Models.
+type Node struct {
+	ID       int
+	Kind     NodeKind
+	Name     string
+	Hidden   bool
+	Children []int
+	Models   []int
+
+	// Transform nodes only, from the first animation frame.
+	Translation [3]int
+	Rotation    [3][3]int
+	Layer       int
+}
+
+// Instance is one placement of a model in the scene, with its transform
+// already converted to the engine's Y-up space.
+type Instance struct {
+	Model     int
+	Name      string
+	Transform mgl32.Mat4
+}
+
+var identity = [3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+func (c *chunkReader) node() *Node {
+	node := &Node{ID: c.int(), Rotation: identity}
+	attrs := c.dict()
+	node.Name = attrs["_name"]
+	node.Hidden = attrs["_hidden"] == "1"
+
+	switch c.id {
+	case "nTRN":
+		node.Kind = NodeTransform
+		node.Children = []int{c.int()}
+		c.int() // reserved
+		node.Layer = c.int()
+		frames := c.int()
+		for i := 0; i < frames && c.err == nil; i++ {
+			frame := c.dict()
+			if i > 0 {
+				continue
+			}
+			if t, ok := frame["_t"]; ok {
+				node.Translation = parseTranslation(t)
+			}
+			if r, ok := frame["_r"]; ok {
+				if packed, err := strconv.Atoi(r); err == nil {
+					node.Rotation = decodeRotation(packed)
+				}
+			}
+		}
+	case "nGRP":
+		node.Kind = NodeGroup
+		count := c.int()
+		if count < 0 || count > len(c.data)/4 {
+			if c.err == nil {
+				c.err = fmt.Errorf("vox: nGRP node %d has invalid child count %d", node.ID, count)
+			}
+			return node
+		}
+		for i := 0; i < count; i++ {
+			node.Children = append(node.Children, c.int())
+		}
+	case "nSHP":
+		node.Kind = NodeShape
+		count := c.int()
+		for i := 0; i < count && c.err == nil; i++ {
+			node.Models = append(node.Models, c.int())
+			c.dict()
+		}
+	}
+	return node
+}
+
+func parseTranslation(s string) [3]int {
+	var t [3]int
+	for i, field := range strings.Fields(s) {
+		if i < 3 {
+			t[i], _ = strconv.Atoi(field)
+		}
+	}
+	return t
+}
+
+// decodeRotation unpacks the _r byte: bits 0-1 and 2-3 give the column of
+// the non-zero entry in rows one and two, bits 4-6 the sign of each row.
+func decodeRotation(packed int) [3][3]int {
+	first := packed & 3
+	second := (packed >> 2) & 3
+	if first == 3 || second == 3 || first == second {
+		return identity
+	}
+
+	var r [3][3]int
+	columns := [3]int{first, second, 3 - first - second}
+	for row, col := range columns {
+		r[row][col] = 1
+		if packed&(1<<(4+row)) != 0 {
+			r[row][col] = -1
+		}
+	}
+	return r
+}
+
+func (f *File) validateNodes() error {
+	for id, node := range f.Nodes {
+		for _, child := range node.Children {
+			if _, ok := f.Nodes[child]; !ok {
+				return fmt.Errorf("vox: node %d references missing node %d", id, child)
+			}
+		}
+		for _, model := range node.Models {
+			if model < 0 || model >= len(f.Models) {
+				return fmt.Errorf("vox: node %d references missing model %d", id, model)
+			}
+		}
+	}
+	return nil
+}
+
+// Instances walks the scene graph from the root node and returns every
+// visible model placement. Files without a scene graph yield one instance
+// per model at the origin.
+func (f *File) Instances() []Instance {
+	if _, ok := f.Nodes[0]; !ok {
+		instances := make([]Instance, len(f.Models))
+		for i := range f.Models {
+			instances[i] = Instance{Model: i, Transform: mgl32.Ident4()}
+		}
+		return instances
+	}
+
+	instances := make([]Instance, 0)
+	visited := make(map[int]bool)
+	var walk func(id int, parent mgl32.Mat4, name string)
+	walk = func(id int, parent mgl32.Mat4, name string) {
+		node := f.Nodes[id]
+		if visited[id] || node.Hidden {
+			return
+		}
+		visited[id] = true
+		defer delete(visited, id)
+
+		switch node.Kind {
+		case NodeTransform:
+			if node.Name != "" {
+				name = node.Name
+			}
+			parent = parent.Mul4(node.matrix())
+		case NodeShape:
+			for _, model := range node.Models {
+				instances = append(instances, Instance{
+					Model:     model,
+					Name:      name,
+					Transform: zUpToYUp.Mul4(parent).Mul4(yUpToZUp),
+				})
+			}
+		}
+		for _, child := range node.Children {
+			walk(child, parent, name)
+		}
+	}
+	walk(0, mgl32.Ident4(), "")
+	return instances
+}
+
+func (n *Node) matrix() mgl32.Mat4 {
+	m := mgl32.Ident4()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			m.Set(row, col, float32(n.Rotation[row][col]))
+		}
+		m.Set(row, 3, float32(n.Translation[row]))
+	}
+	return m
+}
+
+// MagicaVoxel is Z-up; the engine is Y-up. Converting is a -90 degree
+// rotation about X: (x, y, z) becomes (x, z, -y).
+var (
+	zUpToYUp = mgl32.Mat4{
+		1, 0, 0, 0,
+		0, 0, -1, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1,
+	}
+	yUpToZUp = zUpToYUp.Transpose()
+)
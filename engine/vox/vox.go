@@ -0,0 +1,233 @@
+package vox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+)
+
+// File is a decoded MagicaVoxel .vox file. Nodes is the scene graph keyed
+// by node id; it is empty for files written before scene support, in which
+// case every model is shown once at the origin.
+type File struct {
+	Version int
+	Models  []*Model
+	Palette Palette
+	Nodes   map[int]*Node
+}
+
+// Model is one SIZE/XYZI pair. Coordinates are in MagicaVoxel's Z-up space.
+type Model struct {
+	Size   [3]int
+	Voxels []Voxel
+}
+
+// Voxel is a filled cell. Color indexes the palette and is never 0.
+type Voxel struct {
+	X, Y, Z uint8
+	Color   uint8
+}
+
+const maxChunkSize = 1 << 28
+
+// Load reads a .vox file from disk.
+func Load(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Decode(bufio.NewReader(file))
+}
+
+// Decode parses a .vox stream. Chunks the engine has no use for (layers,
+// materials, render settings) are skipped.
+func Decode(r io.Reader) (*File, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("vox: reading header: %w", err)
+	}
+	if string(header[:4]) != "VOX " {
+		return nil, fmt.Errorf("vox: not a .vox file")
+	}
+
+	f := &File{
+		Version: int(binary.LittleEndian.Uint32(header[4:])),
+		Palette: DefaultPalette(),
+		Nodes:   make(map[int]*Node),
+	}
+
+	id, content, children, err := readChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != "MAIN" {
+		return nil, fmt.Errorf("vox: expected MAIN chunk, got %q", id)
+	}
+	if len(content) != 0 {
+		return nil, fmt.Errorf("vox: MAIN chunk has content")
+	}
+
+	var size *[3]int
+	for len(children) > 0 {
+		id, content, rest, err := splitChunk(children)
+		if err != nil {
+			return nil, err
+		}
+		children = rest
+
+		c := &chunkReader{id: id, data: content}
+		switch id {
+		case "SIZE":
+			s := [3]int{c.int(), c.int(), c.int()}
+			size = &s
+		case "XYZI":
+			if size == nil {
+				return nil, fmt.Errorf("vox: XYZI chunk without SIZE")
+			}
+			model := &Model{Size: *size}
+			model.Voxels = c.voxels(model.Size)
+			f.Models = append(f.Models, model)
+			size = nil
+		case "RGBA":
+			// Palette entry i+1 is stored at position i; index 0 is empty.
+			for i := 0; i < 255; i++ {
+				rgba := c.bytes(4)
+				if rgba != nil {
+					f.Palette[i+1] = color.RGBA{rgba[0], rgba[1], rgba[2], rgba[3]}
+				}
+			}
+		case "nTRN", "nGRP", "nSHP":
+			node := c.node()
+			if c.err == nil {
+				if _, ok := f.Nodes[node.ID]; ok {
+					return nil, fmt.Errorf("vox: duplicate node id %d", node.ID)
+				}
+				f.Nodes[node.ID] = node
+			}
+		}
+
+		if c.err != nil {
+			return nil, c.err
+		}
+	}
+
+	return f, f.validateNodes()
+}
+
+func readChunk(r io.Reader) (string, []byte, []byte, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", nil, nil, fmt.Errorf("vox: reading chunk: %w", err)
+	}
+
+	contentSize := binary.LittleEndian.Uint32(header[4:])
+	childrenSize := binary.LittleEndian.Uint32(header[8:])
+	if contentSize > maxChunkSize || childrenSize > maxChunkSize {
+		return "", nil, nil, fmt.Errorf("vox: chunk %q too large", header[:4])
+	}
+
+	data := make([]byte, contentSize+childrenSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", nil, nil, fmt.Errorf("vox: reading chunk %q: %w", header[:4], err)
+	}
+	return string(header[:4]), data[:contentSize], data[contentSize:], nil
+}
+
+// splitChunk returns the first chunk in data and what follows it. Nested
+// children are skipped; no chunk below MAIN uses them.
+func splitChunk(data []byte) (string, []byte, []byte, error) {
+	if len(data) < 12 {
+		return "", nil, nil, fmt.Errorf("vox: truncated chunk header")
+	}
+
+	contentSize := uint64(binary.LittleEndian.Uint32(data[4:]))
+	childrenSize := uint64(binary.LittleEndian.Uint32(data[8:]))
+	end := 12 + contentSize + childrenSize
+	if end > uint64(len(data)) {
+		return "", nil, nil, fmt.Errorf("vox: chunk %q overruns its parent", data[:4])
+	}
+	return string(data[:4]), data[12 : 12+contentSize], data[end:], nil
+}
+
+// chunkReader reads little-endian fields from a chunk's content. The first
+// error sticks and later reads return zero values.
+type chunkReader struct {
+	id   string
+	data []byte
+	err  error
+}
+
+func (c *chunkReader) bytes(n int) []byte {
+	if c.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(c.data) {
+		c.err = fmt.Errorf("vox: %s chunk truncated", c.id)
+		return nil
+	}
+	b := c.data[:n]
+	c.data = c.data[n:]
+	return b
+}
+
+func (c *chunkReader) int() int {
+	b := c.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int(int32(binary.LittleEndian.Uint32(b)))
+}
+
+func (c *chunkReader) string() string {
+	return string(c.bytes(c.int()))
+}
+
+func (c *chunkReader) dict() map[string]string {
+	count := c.int()
+	if count < 0 || count > len(c.data)/8 {
+		if c.err == nil {
+			c.err = fmt.Errorf("vox: %s chunk has invalid dictionary size %d", c.id, count)
+		}
+		return nil
+	}
+
+	dict := make(map[string]string, count)
+	for i := 0; i < count && c.err == nil; i++ {
+		key := c.string()
+		dict[key] = c.string()
+	}
+	return dict
+}
+
+func (c *chunkReader) voxels(size [3]int) []Voxel {
+	for axis, n := range size {
+		if n <= 0 || n > 256 {
+			c.err = fmt.Errorf("vox: model size %v out of range on axis %d", size, axis)
+			return nil
+		}
+	}
+
+	count := c.int()
+	if count < 0 || count > len(c.data)/4 {
+		if c.err == nil {
+			c.err = fmt.Errorf("vox: XYZI chunk has invalid voxel count %d", count)
+		}
+		return nil
+	}
+
+	voxels := make([]Voxel, 0, count)
+	for i := 0; i < count; i++ {
+		b := c.bytes(4)
+		v := Voxel{X: b[0], Y: b[1], Z: b[2], Color: b[3]}
+		if int(v.X) >= size[0] || int(v.Y) >= size[1] || int(v.Z) >= size[2] || v.Color == 0 {
+			continue
+		}
+		voxels = append(voxels, v)
+	}
+	return voxels
+}
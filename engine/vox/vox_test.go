@@ -0,0 +1,242 @@
+package vox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/go-gl/mathgl/mgl32"
+	"image/color"
+	"testing"
+)
+
+type voxWriter struct {
+	children bytes.Buffer
+}
+
+func (w *voxWriter) chunk(id string, fields ...any) {
+	var content bytes.Buffer
+	for _, field := range fields {
+		switch f := field.(type) {
+		case int:
+			binary.Write(&content, binary.LittleEndian, int32(f))
+		case string:
+			binary.Write(&content, binary.LittleEndian, int32(len(f)))
+			content.WriteString(f)
+		case []byte:
+			content.Write(f)
+		case map[string]string:
+			binary.Write(&content, binary.LittleEndian, int32(len(f)))
+			for k, v := range f {
+				binary.Write(&content, binary.LittleEndian, int32(len(k)))
+				content.WriteString(k)
+				binary.Write(&content, binary.LittleEndian, int32(len(v)))
+				content.WriteString(v)
+			}
+		}
+	}
+	w.children.WriteString(id)
+	binary.Write(&w.children, binary.LittleEndian, int32(content.Len()))
+	binary.Write(&w.children, binary.LittleEndian, int32(0))
+	w.children.Write(content.Bytes())
+}
+
+func (w *voxWriter) model(size [3]int, voxels ...Voxel) {
+	w.chunk("SIZE", size[0], size[1], size[2])
+	data := make([]byte, 0, len(voxels)*4)
+	for _, v := range voxels {
+		data = append(data, v.X, v.Y, v.Z, v.Color)
+	}
+	w.chunk("XYZI", len(voxels), data)
+}
+
+func (w *voxWriter) bytes() []byte {
+	var out bytes.Buffer
+	out.WriteString("VOX ")
+	binary.Write(&out, binary.LittleEndian, int32(150))
+	out.WriteString("MAIN")
+	binary.Write(&out, binary.LittleEndian, int32(0))
+	binary.Write(&out, binary.LittleEndian, int32(w.children.Len()))
+	out.Write(w.children.Bytes())
+	return out.Bytes()
+}
+
+func decode(t *testing.T, w *voxWriter) *File {
+	f, err := Decode(bytes.NewReader(w.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestDefaultPalette(t *testing.T) {
+	p := DefaultPalette()
+	cases := map[int]color.RGBA{
+		0:   {},
+		1:   {0xff, 0xff, 0xff, 0xff},
+		2:   {0xff, 0xff, 0xcc, 0xff},
+		215: {0x00, 0x00, 0x33, 0xff},
+		216: {0xee, 0x00, 0x00, 0xff},
+		236: {0x00, 0x00, 0xee, 0xff},
+		255: {0x11, 0x11, 0x11, 0xff},
+	}
+	for index, want := range cases {
+		if p[index] != want {
+			t.Errorf("palette[%d] = %v, want %v", index, p[index], want)
+		}
+	}
+}
+
+func TestGreedyMesh(t *testing.T) {
+	cases := []struct {
+		name      string
+		size      [3]int
+		voxels    []Voxel
+		faces     int
+		vertices  int
+		materials int
+	}{
+		{"single", [3]int{1, 1, 1}, []Voxel{{0, 0, 0, 1}}, 6, 8, 1},
+		{"solid", [3]int{2, 2, 2}, []Voxel{
+			{0, 0, 0, 5}, {1, 0, 0, 5}, {0, 1, 0, 5}, {1, 1, 0, 5},
+			{0, 0, 1, 5}, {1, 0, 1, 5}, {0, 1, 1, 5}, {1, 1, 1, 5},
+		}, 6, 8, 1},
+		// Two colors side by side along X: the shared face disappears and
+		// the four long sides split by color.
+		{"two colors", [3]int{2, 1, 1}, []Voxel{{0, 0, 0, 1}, {1, 0, 0, 2}}, 10, 12, 2},
+		// An L shape merges its bottom and inner side into single rectangles;
+		// each L-shaped side needs two.
+		{"ell", [3]int{2, 1, 2}, []Voxel{{0, 0, 0, 3}, {1, 0, 0, 3}, {0, 0, 1, 3}}, 10, 14, 1},
+	}
+
+	for _, tc := range cases {
+		w := &voxWriter{}
+		w.model(tc.size, tc.voxels...)
+		dec, err := decode(t, w).Object(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		faces := dec.Objects[0].Faces
+		if len(faces) != tc.faces || len(dec.Vertices)/3 != tc.vertices || len(dec.Materials) != tc.materials {
+			t.Errorf("%s: got %d faces, %d vertices, %d materials", tc.name, len(faces), len(dec.Vertices)/3, len(dec.Materials))
+			continue
+		}
+
+		// Indices fan each quad the way the OBJ reader does.
+		if len(dec.Indices) != tc.faces*6 || dec.Indices[3] != uint32(faces[0].Vertices[0]) || dec.Indices[5] != uint32(faces[0].Vertices[3]) {
+			t.Errorf("%s: got indices %v", tc.name, dec.Indices)
+		}
+
+		// Every face must wind counter-clockwise around its normal.
+		for _, face := range faces {
+			p := func(c int) mgl32.Vec3 {
+				i := face.Vertices[c] * 3
+				return mgl32.Vec3{dec.Vertices[i], dec.Vertices[i+1], dec.Vertices[i+2]}
+			}
+			n := face.Normals[0] * 3
+			normal := mgl32.Vec3{dec.Normals[n], dec.Normals[n+1], dec.Normals[n+2]}
+			if p(1).Sub(p(0)).Cross(p(2).Sub(p(0))).Dot(normal) <= 0 {
+				t.Errorf("%s: face %v winds against its normal %v", tc.name, face.Vertices, normal)
+			}
+		}
+
+		mesh, err := dec.BuildMesh()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mesh.Indices) != tc.faces*6 {
+			t.Errorf("%s: got %d indices", tc.name, len(mesh.Indices))
+		}
+	}
+}
+
+func TestPaletteMaterials(t *testing.T) {
+	w := &voxWriter{}
+	w.model([3]int{1, 1, 1}, Voxel{0, 0, 0, 7})
+	rgba := make([]byte, 256*4)
+	copy(rgba[6*4:], []byte{255, 0, 0, 128})
+	w.chunk("RGBA", rgba)
+
+	dec, err := decode(t, w).Object(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mat := dec.Materials["color7"]
+	if mat == nil || mat.Diffuse != (mgl32.Vec3{1, 0, 0}) || mat.Opacity != float32(128)/255 {
+		t.Fatalf("got material %+v", mat)
+	}
+	if dec.UVs[0] != 7.5/PaletteSize || dec.UVs[1] != 0.5 {
+		t.Errorf("got uv %v", dec.UVs)
+	}
+
+	// The 1x1x1 model is centred on its pivot at 0 and converted to Y-up.
+	mesh, err := dec.BuildMesh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh.Min != (mgl32.Vec3{0, 0, -1}) || mesh.Max != (mgl32.Vec3{1, 1, 0}) {
+		t.Errorf("got bounds %v %v", mesh.Min, mesh.Max)
+	}
+}
+
+func TestSceneGraph(t *testing.T) {
+	w := &voxWriter{}
+	w.model([3]int{1, 1, 1}, Voxel{0, 0, 0, 1})
+	w.model([3]int{2, 2, 2}, Voxel{1, 1, 1, 2})
+	w.chunk("nTRN", 0, map[string]string{}, 1, -1, -1, 1, map[string]string{})
+	w.chunk("nGRP", 1, map[string]string{}, 3, 2, 4, 6)
+	// Rotation 0b0100001: row 0 -> column 1, row 1 -> column 0, row 2 is
+	// negated.
+	w.chunk("nTRN", 2, map[string]string{"_name": "tree"}, 3, -1, 0, 1, map[string]string{"_t": "10 0 5", "_r": "65"})
+	w.chunk("nSHP", 3, map[string]string{}, 1, 0, map[string]string{})
+	w.chunk("nTRN", 4, map[string]string{"_hidden": "1"}, 5, -1, 0, 1, map[string]string{})
+	w.chunk("nSHP", 5, map[string]string{}, 1, 1, map[string]string{})
+	w.chunk("nTRN", 6, map[string]string{}, 7, -1, 0, 1, map[string]string{})
+	w.chunk("nSHP", 7, map[string]string{}, 1, 1, map[string]string{})
+
+	f := decode(t, w)
+	if len(f.Models) != 2 || len(f.Nodes) != 8 {
+		t.Fatalf("got %d models, %d nodes", len(f.Models), len(f.Nodes))
+	}
+
+	tree := f.Nodes[2]
+	if tree.Rotation != [3][3]int{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}} {
+		t.Errorf("got rotation %v", tree.Rotation)
+	}
+
+	instances := f.Instances()
+	if len(instances) != 2 || instances[0].Model != 0 || instances[0].Name != "tree" || instances[1].Model != 1 {
+		t.Fatalf("got instances %+v", instances)
+	}
+
+	// Vox (10, 0, 5) is engine (10, 5, 0).
+	origin := instances[0].Transform.Mul4x1(mgl32.Vec4{0, 0, 0, 1})
+	if origin != (mgl32.Vec4{10, 5, 0, 1}) {
+		t.Errorf("got origin %v", origin)
+	}
+	// Engine +Y is vox +Z, which the rotation negates.
+	up := instances[0].Transform.Mul4x1(mgl32.Vec4{0, 1, 0, 0})
+	if up != (mgl32.Vec4{0, -1, 0, 0}) {
+		t.Errorf("got up %v", up)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	cases := map[string]func(w *voxWriter){
+		"xyzi without size": func(w *voxWriter) { w.chunk("XYZI", 0) },
+		"size out of range": func(w *voxWriter) { w.model([3]int{0, 1, 1}) },
+		"voxel count":       func(w *voxWriter) { w.chunk("SIZE", 1, 1, 1); w.chunk("XYZI", 5) },
+		"missing child":     func(w *voxWriter) { w.chunk("nGRP", 0, map[string]string{}, 1, 9) },
+		"missing model":     func(w *voxWriter) { w.chunk("nSHP", 0, map[string]string{}, 1, 3, map[string]string{}) },
+	}
+	for name, build := range cases {
+		w := &voxWriter{}
+		build(w)
+		if _, err := Decode(bytes.NewReader(w.bytes())); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := Decode(bytes.NewReader([]byte("VOX \x96\x00\x00\x00MAIN"))); err == nil {
+		t.Error("truncated: expected an error")
+	}
+}
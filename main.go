@@ -1,8 +1,8 @@
 package main
 
 import (
+	"3DPixelGameEngine/engine/ecs"
 	"3DPixelGameEngine/engine/io"
-	"3DPixelGameEngine/engine/obj"
 	"3DPixelGameEngine/engine/rendering"
 	"fmt"
 	"github.com/go-gl/glfw/v3.2/glfw"
@@ -17,20 +17,27 @@ func main() {
 	defer glfw.Terminate()
 
 	renderer := rendering.NewRenderer(window)
-
-	model, err := obj.LoadModel("engine/res/models/cube.obj", "engine/res/models/cube.mtl")
-	if err != nil {
-		fmt.Println("fail to load model", err)
+	if err := renderer.SetVirtualResolution(320, 180); err != nil {
+		fmt.Println("warning: rendering at window resolution:", err)
+	}
+	if err := renderer.Post.LoadConfig("engine/res/postprocess.json"); err != nil {
+		fmt.Println("warning: post-processing disabled:", err)
 	}
-	object := rendering.NewObject(model)
-	renderer.Objects["cube"] = object
 
-	for !window.ShouldClose() {
-		deltaTime := renderer.CalculateDeltaTime()
+	if err := renderer.LoadScene("engine/res/scenes/default.json"); err != nil {
+		fmt.Println("fail to load scene:", err)
+	}
 
-		io.InputRunner(window, deltaTime)
+	world := ecs.NewWorld()
+	world.AddSystem("input", ecs.SystemFunc(func(_ *ecs.World, dt float64) {
+		io.InputRunner(window, dt)
+	}))
+	world.AddSystem("render", renderer, ecs.After("input"))
 
-		renderer.Draw()
+	for !window.ShouldClose() {
+		if err := world.Update(renderer.CalculateDeltaTime()); err != nil {
+			log.Fatal(err)
+		}
 
 		window.PollEvents()
 		window.SwapBuffers()